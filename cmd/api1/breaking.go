@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/all"
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/pkg/errors"
+)
+
+// checkBreaking compares api files found in paths with the baseline, prints
+// the changes and fails if any of them is breaking
+func checkBreaking(rf *renderFlags, paths []string, baseline string) int {
+	config, files, err := rf.findFiles(paths)
+	if err != nil {
+		return report(err)
+	}
+	schema, err := parseSchema(ioutil.ReadFile, files)
+	if err != nil {
+		return report(err)
+	}
+	old, err := loadBaseline(config, baseline)
+	if err != nil {
+		return report(errors.Wrapf(err, "load baseline [%s] failed", baseline))
+	}
+
+	changes := api1.CompareSchemas(old, schema)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if breaking := changes.Breaking(); len(breaking) > 0 {
+		info("%d breaking change(s) found since %s", len(breaking), baseline)
+		return exitFailure
+	}
+	info("No breaking changes since %s", baseline)
+	return exitOK
+}
+
+func parseSchema(readFile func(string) ([]byte, error), files []string) (*api1.Schema, error) {
+	parser := &api1.Parser{}
+	schema, err := parser.ParseFilesWith(readFile, files...)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.SupplyRouteInfo(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// loadBaseline loads the schema from an `api1.json` file, or from api files
+// of config in a git revision
+func loadBaseline(config *all.Config, baseline string) (*api1.Schema, error) {
+	if stat, err := os.Stat(baseline); err == nil && !stat.IsDir() {
+		content, err := ioutil.ReadFile(baseline)
+		if err != nil {
+			return nil, err
+		}
+		var schema api1.Schema
+		if err := json.Unmarshal(content, &schema); err != nil {
+			return nil, errors.Wrap(err, "invalid api1.json")
+		}
+		schema.NormalizeBaseline()
+		return &schema, nil
+	}
+
+	// paths listed are relative to the top of the work tree, which are
+	// matched relative to the working directory like the current files
+	out, err := git("rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSpace(string(out))
+	out, err = git("ls-tree", "-r", "--full-tree", "--name-only", baseline)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		file, err := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(name))
+		if err != nil {
+			return nil, err
+		}
+		ok, err := config.MatchFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no api files found")
+	}
+	return parseSchema(func(file string) ([]byte, error) {
+		return git("show", baseline+":"+path.Join(prefix, filepath.ToSlash(file)))
+	}, files)
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, errors.Errorf("git %s failed: %s", strings.Join(args, " "), msg)
+	}
+	return out, nil
+}
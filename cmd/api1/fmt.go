@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+)
+
+func runFmt(args []string) int {
+	fs := newFlagSet("fmt")
+	var rf renderFlags
+	rf.registerConfig(fs)
+	write := fs.Bool("w", false, "write result to the files instead of stdout")
+	list := fs.Bool("l", false, "list files whose formatting differs, fails if any without -w")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	_, files, err := rf.findFiles(fs.Args())
+	if err != nil {
+		return report(err)
+	}
+	code := exitOK
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return report(err)
+		}
+		formatted, err := api1.Format(file, string(content))
+		if err != nil {
+			code = report(err)
+			continue
+		}
+		changed := formatted != string(content)
+		if *list && changed {
+			fmt.Println(file)
+			if !*write {
+				code = exitFailure
+			}
+		}
+		if *write {
+			if changed {
+				stat, err := os.Stat(file)
+				if err != nil {
+					return report(err)
+				}
+				if err := ioutil.WriteFile(file, []byte(formatted), stat.Mode()); err != nil {
+					return report(err)
+				}
+			}
+		} else if !*list {
+			fmt.Print(formatted)
+		}
+	}
+	return code
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/all"
+	"github.com/jinzhenj/api1/pkg/golang"
+	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// renderFlags are flags of commands rendering api files, which take
+// precedence over the config file
+type renderFlags struct {
+	fs         *flag.FlagSet
+	config     string
+	targets    string
+	goServer   string
+	goGenerics bool
+	out        string
+	// targets of the flag are rendered even if the config has generators
+	allTargets bool
+}
+
+func (f *renderFlags) register(fs *flag.FlagSet, targets []string) {
+	f.registerConfig(fs)
+	fs.StringVar(&f.targets, "targets", strings.Join(targets, ","),
+		"comma separated targets to generate, available: "+strings.Join(all.AllTargets, ",")+
+			", generators of the config file are used if not set")
+	fs.StringVar(&f.goServer, "go.server", "",
+		"web framework of generated Go routes, available: "+strings.Join(golang.ServerNames(), ",")+
+			" (default gin, or @go.server of groups)")
+	fs.BoolVar(&f.goGenerics, "go.generics", false,
+		"render generic structs as Go generics (requires go1.18), instead of monomorphized structs")
+	fs.StringVar(&f.out, "out", ".", "directory which generated files are written to")
+	fs.StringVar(&f.out, "o", ".", "shorthand of -out")
+}
+
+// registerConfig registers only the config flag, for commands finding api
+// files without rendering them
+func (f *renderFlags) registerConfig(fs *flag.FlagSet) {
+	f.fs = fs
+	fs.StringVar(&f.config, "config", all.DefaultConfigFile, "config file, which is optional if not set")
+}
+
+func (f *renderFlags) isSet(name string) bool {
+	var set bool
+	f.fs.Visit(func(fl *flag.Flag) {
+		set = set || fl.Name == name
+	})
+	return set
+}
+
+// loadConfig loads the config file if it is set or exists
+func (f *renderFlags) loadConfig() (*all.Config, error) {
+	if !f.isSet("config") {
+		exists, err := utils.FileExists(f.config)
+		if err != nil || !exists {
+			return &all.Config{}, err
+		}
+	}
+	return all.LoadConfig(f.config)
+}
+
+// findFiles returns the config, whose inputs are replaced by paths if any,
+// and api files found by it
+func (f *renderFlags) findFiles(paths []string) (*all.Config, []string, error) {
+	config, err := f.loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) > 0 {
+		config.Inputs = paths
+	}
+	files, err := config.FindFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, errors.New("no api files found")
+	}
+	return config, files, nil
+}
+
+// render renders api files found in paths (or inputs of the config), the
+// names of code files are joined with the output directory
+func (f *renderFlags) render(paths []string) ([]all.CodeFile, error) {
+	config, files, err := f.findFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	render := all.NewRender()
+	render.Configure(config)
+	if f.allTargets || f.isSet("targets") || len(render.Targets) == 0 {
+		if render.Targets, err = all.ParseTargets(f.targets); err != nil {
+			return nil, err
+		}
+	}
+	if f.isSet("go.server") {
+		render.GoServer = f.goServer
+	}
+	if f.isSet("go.generics") {
+		render.GoGenerics = f.goGenerics
+	}
+	codeFiles, err := render.RenderFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	for i := range codeFiles {
+		codeFiles[i].Name = filepath.Join(f.out, codeFiles[i].Name)
+	}
+	return codeFiles, nil
+}
+
+// fileState returns "create", "update" or "unchanged" of writing codeFile
+func fileState(codeFile *all.CodeFile) (string, error) {
+	content, err := ioutil.ReadFile(codeFile.Name)
+	if os.IsNotExist(err) {
+		return "create", nil
+	} else if err != nil {
+		return "", err
+	}
+	if string(content) == codeFile.Content {
+		return "unchanged", nil
+	}
+	return "update", nil
+}
+
+func runGenerate(args []string) int {
+	fs := newFlagSet("generate")
+	var rf renderFlags
+	rf.register(fs, all.DefaultTargets)
+	yes := fs.Bool("yes", false, "write files without confirmation")
+	fs.BoolVar(yes, "y", false, "shorthand of -yes")
+	dryRun := fs.Bool("dry-run", false, "print files to write without writing them")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	codeFiles, err := rf.render(fs.Args())
+	if err != nil {
+		return report(err)
+	}
+	if len(codeFiles) == 0 {
+		info("No file generated")
+		return exitOK
+	}
+
+	info("Will write files:")
+	for i := range codeFiles {
+		state, err := fileState(&codeFiles[i])
+		if err != nil {
+			return report(err)
+		}
+		info("    (%s) %s", state, codeFiles[i].Name)
+	}
+	if *dryRun {
+		return exitOK
+	}
+
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "Please confirm [y/n]: ")
+		reader := bufio.NewReader(os.Stdin)
+		input, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(input)) != "y" {
+			info("Give up")
+			return exitFailure
+		}
+	}
+
+	for _, codeFile := range codeFiles {
+		info("Writing %s ...", codeFile.Name)
+		if err := codeFile.WriteFile(); err != nil {
+			return report(err)
+		}
+	}
+
+	info("Done")
+	return exitOK
+}
+
+func runCheck(args []string) int {
+	fs := newFlagSet("check")
+	// all the targets are rendered to find errors of generators
+	rf := renderFlags{allTargets: true}
+	rf.register(fs, all.AllTargets)
+	breaking := fs.String("breaking", "",
+		"`api1.json` or git revision of the previous version, fails if api files have breaking changes since it")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if _, err := rf.render(fs.Args()); err != nil {
+		return report(err)
+	}
+	if *breaking != "" {
+		return checkBreaking(&rf, fs.Args(), *breaking)
+	}
+	info("No problems found")
+	return exitOK
+}
+
+func runDiff(args []string) int {
+	fs := newFlagSet("diff")
+	var rf renderFlags
+	rf.register(fs, all.DefaultTargets)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	codeFiles, err := rf.render(fs.Args())
+	if err != nil {
+		return report(err)
+	}
+	code := exitOK
+	for _, codeFile := range codeFiles {
+		content, err := ioutil.ReadFile(codeFile.Name)
+		oldName := codeFile.Name
+		if os.IsNotExist(err) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return report(err)
+		}
+		if string(content) == codeFile.Content {
+			continue
+		}
+		fmt.Print(utils.UnifiedDiff(oldName, codeFile.Name, string(content), codeFile.Content))
+		code = exitFailure
+	}
+	return code
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/openapi"
+	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the format of documents which can be imported
+const importOpenAPI = "openapi"
+
+func runImport(args []string) int {
+	fs := newFlagSet("import")
+	group := fs.String("group", "", "name of the imported group, defaults to the file name")
+	output := fs.String("o", "", "the api file to write, defaults to <group>.api, or - for stdout")
+	if len(args) > 0 && isHelp(args[0]) {
+		fs.Usage()
+		return exitOK
+	}
+	if len(args) == 0 || args[0] != importOpenAPI {
+		fs.Usage()
+		return exitUsage
+	}
+	if code, ok := parseFlags(fs, args[1:]); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	file := fs.Arg(0)
+	if *group == "" {
+		*group = groupName(file)
+	} else if groupName(*group) != strings.ToLower(*group) {
+		info("invalid group name [%s]", *group)
+		return exitUsage
+	}
+	if *output == "" {
+		*output = *group + ".api"
+	}
+	if *output != "-" {
+		exists, err := utils.FileExists(*output)
+		if err != nil {
+			return report(err)
+		}
+		if exists {
+			return report(errors.Errorf("%s already exists", *output))
+		}
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return report(err)
+	}
+	doc, err := openapi.LoadOpenAPI(content)
+	if err != nil {
+		return report(errors.Wrapf(err, "load [%s] failed", file))
+	}
+	im := &openapi.Importer{Group: *group}
+	imported, err := im.Import(doc)
+	if err != nil {
+		return report(err)
+	}
+	for _, problem := range im.Problems {
+		info("warning: %s", problem)
+	}
+
+	if *output == "-" {
+		fmt.Print(imported)
+	} else {
+		if err := utils.MayCreateDir(filepath.Dir(*output)); err != nil {
+			return report(err)
+		}
+		if err := ioutil.WriteFile(*output, []byte(imported), 0644); err != nil {
+			return report(err)
+		}
+		info("Created %s", *output)
+	}
+	if len(im.Problems) > 0 {
+		info("%d construct(s) cannot be represented, which are skipped or approximated", len(im.Problems))
+	}
+	return exitOK
+}
+
+// groupName converts the file name to a group name, e.g. `pet_store` for
+// `Pet-Store.v1.yaml`
+func groupName(file string) string {
+	name := filepath.Base(file)
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "api" + name
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jinzhenj/api1/pkg/all"
+	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const sampleApiFile = "hello.api"
+
+const sampleApi = `group hello
+
+struct Greeting {
+  message: string
+}
+
+interface HelloApi {
+  # @route GET /hello/:name
+  hello(name: string): Greeting
+}
+`
+
+const sampleConfig = `# api files, dirs or glob patterns, ` + "`**`" + ` matches any dirs
+inputs:
+  - "**/*.api"
+# glob patterns of api files to skip
+ignore: []
+# generators to run, with the dirs of generated files
+generators:
+  openapi:
+    output: doc
+    info:
+      title: hello
+      version: 0.1.0
+  go:
+    output: pkg/api
+    package: api
+    server: gin
+  ts:
+    output: web/api
+`
+
+func runInit(args []string) int {
+	fs := newFlagSet("init")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{filepath.Join(dir, sampleApiFile), sampleApi},
+		{filepath.Join(dir, all.DefaultConfigFile), sampleConfig},
+	}
+	for _, file := range files {
+		exists, err := utils.FileExists(file.name)
+		if err != nil {
+			return report(err)
+		}
+		if exists {
+			return report(errors.Errorf("%s already exists", file.name))
+		}
+	}
+	if err := utils.MayCreateDir(dir); err != nil {
+		return report(err)
+	}
+	for _, file := range files {
+		if err := ioutil.WriteFile(file.name, []byte(file.content), 0644); err != nil {
+			return report(err)
+		}
+		info("Created %s", file.name)
+	}
+	info("Run `api1 generate` to generate docs & code")
+	return exitOK
+}
@@ -1,89 +1,126 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/jinzhenj/api1/pkg/all"
-	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/jinzhenj/api1/pkg/api1"
 )
 
-func main() {
-	files, err := utils.ListFiles(".", isApiFile)
-	if err != nil {
-		fatal(err)
-	}
-	if len(files) == 0 {
-		info("No API files found")
-		return
-	}
+// exit codes of commands
+const (
+	exitOK = 0
+	// problems are found (e.g. diagnostics or differences), or the command
+	// failed
+	exitFailure = 1
+	// the command line is invalid
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
 
-	info("Found API files:")
-	for _, file := range files {
-		info("    %s", file)
+func init() {
+	commands = []command{
+		{"generate", "[flags] [paths...]", "generate docs & code from api files", runGenerate},
+		{"check", "[flags] [paths...]", "check api files without writing files", runCheck},
+		{"fmt", "[flags] [paths...]", "format api files", runFmt},
+		{"diff", "[flags] [paths...]", "show differences between generated files and files on disk", runDiff},
+		{"init", "[dir]", "create a sample api file & config file", runInit},
+		{"import", "openapi [flags] <file>", "import an api file from an OpenAPI 3.0 document (JSON or YAML)", runImport},
 	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	render := all.NewRender()
-	codeFiles, err := render.RenderFiles(files)
-	if err != nil {
-		fatal(err)
+func run(args []string) int {
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelp(args[0])) {
+		// generating is the default command, as api1 ran before subcommands
+		return runGenerate(args)
 	}
-	if len(codeFiles) == 0 {
-		info("No file generated")
-		return
+	if isHelp(args[0]) || args[0] == "help" {
+		usage()
+		return exitOK
 	}
-
-	info("Will write files:")
-	for _, codeFile := range codeFiles {
-		exists, err := utils.FileExists(codeFile.Name)
-		if err != nil {
-			fatal(err)
-		}
-		state := "create"
-		if exists {
-			state = "update"
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
 		}
-		info("    (%s) %s", state, codeFile.Name)
 	}
+	fmt.Fprintf(os.Stderr, "unknown command [%s]\n\n", args[0])
+	usage()
+	return exitUsage
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
 
-	fmt.Fprintf(os.Stderr, "Please confirm [y/n]: ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.Replace(input, "\n", "", -1)
-	if strings.ToLower(input) != "y" {
-		info("Give up")
-		return
+func usage() {
+	info("Usage: api1 <command> [flags] [paths...]\n\nCommands:")
+	for _, cmd := range commands {
+		info("    %-10s %s", cmd.name, cmd.summary)
 	}
+	info("\nPaths are api files, directories (searched recursively) or glob patterns,")
+	info("defaults to the current directory. Run `api1 <command> -h` for flags.")
+	info("\nExit codes: 0 ok, 1 problems found or failed, 2 invalid usage")
+}
 
-	for _, codeFile := range codeFiles {
-		info("Writing %s ...", codeFile.Name)
-		if err := codeFile.WriteFile(); err != nil {
-			fatal(err)
+// newFlagSet returns the flag set of cmd, whose usage prints the flags
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("api1 "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.name == name {
+				info("Usage: api1 %s %s\n\n%s\n\nFlags:", cmd.name, cmd.args, cmd.summary)
+			}
 		}
+		fs.PrintDefaults()
 	}
-
-	info("Done")
+	return fs
 }
 
-func isApiFile(file string) bool {
-	return strings.HasSuffix(file, ".api")
+// parseFlags parses args by fs, returns the exit code and false if the
+// command should exit, e.g. for `-h`
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
 }
 
 func info(s string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, s+"\n", args...)
 }
 
-func fatal(err error, s ...string) {
-	message := "Error"
-	if len(s) > 0 {
-		var args []interface{}
-		for _, arg := range s[1:] {
-			args = append(args, arg)
+// report prints err and returns exitFailure, diagnostics are rendered
+// compiler-style: file:line:column: severity: message
+func report(err error) int {
+	var ds api1.Diagnostics
+	var d api1.Diagnostic
+	if errors.As(err, &ds) {
+		for _, d := range ds {
+			fmt.Fprintln(os.Stderr, d.Error())
 		}
-		message = fmt.Sprintf(s[0], args...)
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(ds))
+	} else if errors.As(err, &d) {
+		fmt.Fprintln(os.Stderr, d.Error())
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
-	fmt.Fprintf(os.Stderr, "%s: %v\n", message, err)
-	os.Exit(1)
+	return exitFailure
 }
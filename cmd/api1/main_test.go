@@ -0,0 +1,207 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chdir changes the working directory to a temporary directory during t
+func chdir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	chdir(t)
+
+	assert.Equal(t, exitUsage, run([]string{"unknown"}))
+	assert.Equal(t, exitUsage, run([]string{"check", "-unknown"}))
+	assert.Equal(t, exitOK, run([]string{"check", "-h"}))
+	assert.Equal(t, exitFailure, run([]string{"check"}), "no api files")
+
+	assert.Equal(t, exitOK, run([]string{"init", "api"}))
+	assert.FileExists(t, "api/hello.api")
+	assert.Equal(t, exitFailure, run([]string{"init", "api"}), "file exists")
+
+	assert.Equal(t, exitOK, run([]string{"check"}))
+	assert.Equal(t, exitOK, run([]string{"check", "api/*.api"}))
+	assert.Equal(t, exitFailure, run([]string{"check", "none/*.api"}))
+	assert.Equal(t, exitUsage, run([]string{"generate", "-targets"}))
+	assert.Equal(t, exitFailure, run([]string{"generate", "-targets", "java"}))
+
+	assert.Equal(t, exitOK, run([]string{"generate", "-dry-run", "-targets", "openapi"}))
+	assert.NoFileExists(t, "doc/openapi.json")
+	assert.Equal(t, exitOK, run([]string{"generate", "--yes", "-targets", "openapi", "-o", "out", "api"}))
+	assert.FileExists(t, "out/doc/openapi.json")
+
+	assert.Equal(t, exitOK, run([]string{"diff", "-targets", "openapi", "-o", "out"}))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join("out", "doc", "openapi.json"), []byte("{}\n"), 0644))
+	assert.Equal(t, exitFailure, run([]string{"diff", "-targets", "openapi", "-o", "out"}))
+
+	assert.NoError(t, ioutil.WriteFile("api/hello.api", []byte("group hello\n\nstruct {\n"), 0644))
+	assert.Equal(t, exitFailure, run([]string{"check"}))
+}
+
+func TestRunConfig(t *testing.T) {
+	chdir(t)
+
+	assert.Equal(t, exitOK, run([]string{"init"}))
+	assert.FileExists(t, "api1.yaml")
+	assert.NoError(t, ioutil.WriteFile("api1.yaml", []byte(`
+inputs: ["*.api"]
+generators:
+  openapi:
+    output: docs
+  python:
+    output: clients/py
+`), 0644))
+	assert.Equal(t, exitOK, run([]string{"generate", "-y"}))
+	assert.FileExists(t, "docs/openapi.json")
+	assert.FileExists(t, "clients/py/hello.py")
+	assert.NoFileExists(t, "pkg/api/hello.go")
+
+	// flags take precedence over the config
+	assert.Equal(t, exitOK, run([]string{"generate", "-y", "-targets", "go"}))
+	assert.FileExists(t, "pkg/api/hello.go")
+
+	assert.NoError(t, ioutil.WriteFile("invalid.yaml", []byte("generators:\n  go:\n    server: beego\n"), 0644))
+	assert.Equal(t, exitFailure, run([]string{"check", "-config", "invalid.yaml"}))
+	assert.Equal(t, exitFailure, run([]string{"check", "-config", "none.yaml"}))
+}
+
+func TestRunBreaking(t *testing.T) {
+	chdir(t)
+
+	assert.Equal(t, exitOK, run([]string{"init"}))
+	assert.Equal(t, exitOK, run([]string{"generate", "-y", "-targets", "openapi"}))
+	assert.FileExists(t, "doc/api1.json")
+	assert.Equal(t, exitOK, run([]string{"check", "-breaking", "doc/api1.json"}))
+
+	// adding a nullable field is compatible
+	added := strings.Replace(sampleApi, "message: string\n", "message: string\n  lang: string?\n", 1)
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(added), 0644))
+	assert.Equal(t, exitOK, run([]string{"check", "-breaking", "doc/api1.json"}))
+
+	removed := strings.Replace(sampleApi, "message: string\n", "", 1)
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(removed), 0644))
+	assert.Equal(t, exitFailure, run([]string{"check", "-breaking", "doc/api1.json"}))
+	assert.Equal(t, exitFailure, run([]string{"check", "-breaking", "none"}))
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not found")
+	}
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(sampleApi), 0644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", sampleApiFile},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		_, err := git(args...)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, exitOK, run([]string{"check", "-breaking", "HEAD"}))
+	routed := strings.Replace(sampleApi, "GET /hello/:name", "POST /hello/:name", 1)
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(routed), 0644))
+	assert.Equal(t, exitFailure, run([]string{"check", "-breaking", "HEAD"}))
+
+	// files are listed relative to the working dir in sub dirs
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(sampleApi), 0644))
+	assert.NoError(t, os.Mkdir("sub", 0755))
+	assert.NoError(t, os.Chdir("sub"))
+	assert.Equal(t, exitOK, run([]string{"check", "-breaking", "HEAD", ".."}))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join("..", sampleApiFile), []byte(routed), 0644))
+	assert.Equal(t, exitFailure, run([]string{"check", "-breaking", "HEAD", ".."}))
+}
+
+func TestRunFmt(t *testing.T) {
+	chdir(t)
+
+	assert.Equal(t, exitOK, run([]string{"init"}))
+	assert.Equal(t, exitOK, run([]string{"fmt", "-l"}))
+
+	unformatted := strings.Replace(sampleApi, "  message: string", "\tmessage :string", 1)
+	assert.NoError(t, ioutil.WriteFile(sampleApiFile, []byte(unformatted), 0644))
+	assert.Equal(t, exitFailure, run([]string{"fmt", "-l"}))
+	assert.Equal(t, exitOK, run([]string{"fmt", sampleApiFile}))
+	content, err := ioutil.ReadFile(sampleApiFile)
+	assert.NoError(t, err)
+	assert.Equal(t, unformatted, string(content))
+
+	assert.Equal(t, exitOK, run([]string{"fmt", "-w", "-l"}))
+	content, err = ioutil.ReadFile(sampleApiFile)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleApi, string(content))
+
+	assert.NoError(t, ioutil.WriteFile("invalid.api", []byte("group hello\n\nstruct {\n"), 0644))
+	assert.Equal(t, exitFailure, run([]string{"fmt", "-w", "."}))
+	assert.Equal(t, exitOK, run([]string{"fmt", "-l", sampleApiFile}))
+}
+
+func TestRunImport(t *testing.T) {
+	chdir(t)
+
+	spec := `{
+  "openapi": "3.0.3",
+  "info": {"title": "Pet Store", "version": "1.0.0"},
+  "paths": {
+    "/pets/{pet-id}": {
+      "get": {
+        "tags": ["PetApi"],
+        "operationId": "getPet",
+        "parameters": [{"name": "pet-id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+          "404": {"description": "Not Found"}
+        }
+      }
+    }
+  },
+  "components": {"schemas": {
+    "Pet": {"type": "object", "required": ["name"], "properties": {
+      "name": {"type": "string"},
+      "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+    }}
+  }}
+}`
+	assert.NoError(t, ioutil.WriteFile("pet-store.json", []byte(spec), 0644))
+	assert.Equal(t, exitUsage, run([]string{"import"}))
+	assert.Equal(t, exitUsage, run([]string{"import", "swagger", "pet-store.json"}))
+	assert.Equal(t, exitUsage, run([]string{"import", "openapi", "-group", "pet-store", "pet-store.json"}))
+	assert.Equal(t, exitFailure, run([]string{"import", "openapi", "none.json"}))
+
+	assert.Equal(t, exitOK, run([]string{"import", "openapi", "pet-store.json"}))
+	content, err := ioutil.ReadFile("pet_store.api")
+	assert.NoError(t, err)
+	assert.Equal(t, `# Pet Store
+group pet_store
+
+struct Pet {
+  name: string
+  tags: [string]?
+}
+
+error NotFound(404)
+
+interface PetApi {
+  # @route GET /pets/:petId
+  # @throws NotFound
+  getPet(petId: int): Pet
+}
+`, string(content))
+	assert.Equal(t, exitOK, run([]string{"check", "pet_store.api"}))
+	assert.Equal(t, exitFailure, run([]string{"import", "openapi", "pet-store.json"}), "file exists")
+	assert.Equal(t, exitOK, run([]string{"import", "openapi", "-group", "pets", "-o", "api/pets.api", "pet-store.json"}))
+	assert.FileExists(t, "api/pets.api")
+}
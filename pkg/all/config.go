@@ -0,0 +1,316 @@
+package all
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/golang"
+	"github.com/jinzhenj/api1/pkg/openapi"
+	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the name of the config file loaded by the command line
+const DefaultConfigFile = "api1.yaml"
+
+// Config is the project configuration, paths are relative to the working dir
+type Config struct {
+	// Inputs are api files, dirs (searched recursively) or glob patterns of
+	// them, `**` matches any dirs. Defaults to the working dir
+	Inputs []string `yaml:"inputs"`
+	// Ignore are glob patterns of api files which are not loaded
+	Ignore []string `yaml:"ignore"`
+	// Generators to run, DefaultTargets if none is configured
+	Generators GeneratorsConfig `yaml:"generators"`
+}
+
+type GeneratorsConfig struct {
+	OpenAPI    *OpenAPIConfig `yaml:"openapi"`
+	Go         *GoConfig      `yaml:"go"`
+	TypeScript *OutputConfig  `yaml:"ts"`
+	Python     *OutputConfig  `yaml:"python"`
+}
+
+type OpenAPIConfig struct {
+	// Output is the dir of `api1.json`, `openapi.json` & `openapi.go`,
+	// defaults to `doc`
+	Output  string          `yaml:"output"`
+	Info    OpenAPIInfo     `yaml:"info"`
+	Servers []OpenAPIServer `yaml:"servers"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+type OpenAPIServer struct {
+	URL         string `yaml:"url"`
+	Description string `yaml:"description"`
+}
+
+type GoConfig struct {
+	// Output defaults to `pkg/api`
+	Output string `yaml:"output"`
+	// Package defaults to the last element of Output
+	Package string `yaml:"package"`
+	// Server is the web framework of routes, see golang.Render.Server
+	Server string `yaml:"server"`
+	// Generics renders generic structs as Go generics, see golang.Render.Generics
+	Generics bool `yaml:"generics"`
+}
+
+type OutputConfig struct {
+	// Output defaults to `web/api` for ts, and `python/api` for python
+	Output string `yaml:"output"`
+}
+
+var reIdentifier = utils.Compile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ConfigError is returned if the config is invalid, with a problem per field
+type ConfigError struct {
+	File     string
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config %s:\n    %s", e.File, strings.Join(e.Problems, "\n    "))
+}
+
+// LoadConfig reads & validates the config file, unknown fields are errors
+func LoadConfig(file string) (*Config, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "invalid config %s", file)
+	}
+	if problems := c.Validate(); len(problems) > 0 {
+		return nil, &ConfigError{File: file, Problems: problems}
+	}
+	return &c, nil
+}
+
+// Validate returns problems of the config, e.g. `generators.go.server:
+// unknown server [foo]`
+func (c *Config) Validate() []string {
+	var problems []string
+	report := func(field string, format string, args ...interface{}) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+	checkPatterns := func(field string, patterns []string) {
+		for i, pattern := range patterns {
+			if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil || pattern == "" {
+				report(fmt.Sprintf("%s[%d]", field, i), "invalid glob pattern [%s]", pattern)
+			}
+		}
+	}
+	checkOutput := func(field string, output string) {
+		if filepath.IsAbs(output) || strings.HasPrefix(filepath.ToSlash(output), "/") {
+			report(field, "should be a relative path, got [%s]", output)
+		}
+	}
+	checkPatterns("inputs", c.Inputs)
+	checkPatterns("ignore", c.Ignore)
+
+	g := &c.Generators
+	if g.OpenAPI != nil {
+		checkOutput("generators.openapi.output", g.OpenAPI.Output)
+		for i, server := range g.OpenAPI.Servers {
+			if server.URL == "" {
+				report(fmt.Sprintf("generators.openapi.servers[%d].url", i), "is required")
+			}
+		}
+	}
+	if g.Go != nil {
+		checkOutput("generators.go.output", g.Go.Output)
+		if g.Go.Package != "" && !reIdentifier.MatchString(g.Go.Package) {
+			report("generators.go.package", "invalid package name [%s]", g.Go.Package)
+		}
+		if g.Go.Server != "" {
+			var ok bool
+			for _, name := range golang.ServerNames() {
+				ok = ok || name == g.Go.Server
+			}
+			if !ok {
+				report("generators.go.server", "unknown server [%s], expecting one of [%s]",
+					g.Go.Server, strings.Join(golang.ServerNames(), ", "))
+			}
+		}
+	}
+	if g.TypeScript != nil {
+		checkOutput("generators.ts.output", g.TypeScript.Output)
+	}
+	if g.Python != nil {
+		checkOutput("generators.python.output", g.Python.Output)
+	}
+	return problems
+}
+
+// Targets returns targets of configured generators
+func (c *Config) Targets() []string {
+	var targets []string
+	g := &c.Generators
+	for _, t := range []struct {
+		target     string
+		configured bool
+	}{
+		{TargetOpenAPI, g.OpenAPI != nil},
+		{TargetGo, g.Go != nil},
+		{TargetTypeScript, g.TypeScript != nil},
+		{TargetPython, g.Python != nil},
+	} {
+		if t.configured {
+			targets = append(targets, t.target)
+		}
+	}
+	return targets
+}
+
+// Configure sets targets & options of generators by c
+func (r *Render) Configure(c *Config) {
+	r.Targets = c.Targets()
+	g := &c.Generators
+	if g.OpenAPI != nil {
+		r.OpenAPIDir = g.OpenAPI.Output
+		r.openapiRender.Info = openapi.Info{
+			Title:       g.OpenAPI.Info.Title,
+			Version:     g.OpenAPI.Info.Version,
+			Description: g.OpenAPI.Info.Description,
+		}
+		r.openapiRender.Servers = nil
+		for _, server := range g.OpenAPI.Servers {
+			r.openapiRender.Servers = append(r.openapiRender.Servers, openapi.Server{
+				Url:         server.URL,
+				Description: server.Description,
+			})
+		}
+	}
+	if g.Go != nil {
+		r.GoServer = g.Go.Server
+		r.GoGenerics = g.Go.Generics
+		r.golangRender.OutputDir = g.Go.Output
+		r.golangRender.Package = g.Go.Package
+	}
+	if g.TypeScript != nil {
+		r.tsRender.OutputDir = g.TypeScript.Output
+	}
+	if g.Python != nil {
+		r.pyRender.OutputDir = g.Python.Output
+	}
+}
+
+// FindFiles returns api files of Inputs which are not ignored
+func (c *Config) FindFiles() ([]string, error) {
+	inputs := c.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{"."}
+	}
+	var files []string
+	seen := make(map[string]bool)
+	add := func(file string) error {
+		file = filepath.Clean(file)
+		ignored, err := c.ignored(file)
+		if err == nil && !ignored && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+		return err
+	}
+	for _, input := range inputs {
+		matches := []string{input}
+		if strings.ContainsAny(input, "*?[") {
+			var err error
+			if matches, err = utils.Glob(input); err != nil {
+				return nil, errors.Wrapf(err, "invalid pattern [%s]", input)
+			}
+			if len(matches) == 0 {
+				return nil, errors.Errorf("no files match pattern [%s]", input)
+			}
+			sort.Strings(matches)
+		}
+		for _, match := range matches {
+			stat, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !stat.IsDir() {
+				if err := add(match); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			dirFiles, err := utils.ListFiles(match, isApiFile)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range dirFiles {
+				if err := add(file); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// MatchFile reports whether file is an api file of Inputs which is not
+// ignored, without accessing the file system, e.g. for files of a git
+// revision. Patterns match files like FindFiles, and dirs match files in them.
+func (c *Config) MatchFile(file string) (bool, error) {
+	if !isApiFile(file) {
+		return false, nil
+	}
+	inputs := c.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{"."}
+	}
+	file = filepath.Clean(file)
+	for _, input := range inputs {
+		input = filepath.Clean(input)
+		var ok bool
+		if strings.ContainsAny(input, "*?[") {
+			var err error
+			if ok, err = utils.MatchGlob(input, file); err != nil {
+				return false, errors.Wrapf(err, "invalid pattern [%s]", input)
+			}
+		} else {
+			ok = file == input || strings.HasPrefix(file, input+string(filepath.Separator))
+			if input == "." {
+				// files outside of the dir, e.g. `../a.api`
+				ok = file != ".." && !strings.HasPrefix(file, ".."+string(filepath.Separator))
+			}
+		}
+		if ok {
+			ignored, err := c.ignored(file)
+			return !ignored, err
+		}
+	}
+	return false, nil
+}
+
+func (c *Config) ignored(file string) (bool, error) {
+	for _, pattern := range c.Ignore {
+		if ok, err := utils.MatchGlob(pattern, file); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func isApiFile(file string) bool {
+	return strings.HasSuffix(file, ".api")
+}
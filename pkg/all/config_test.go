@@ -0,0 +1,243 @@
+package all
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"api1.yaml": `
+inputs:
+  - "api/**/*.api"
+ignore:
+  - "**/internal/*.api"
+generators:
+  openapi:
+    output: docs/api
+    info:
+      title: demo
+      version: 1.0.0
+    servers:
+      - url: https://example.com/api
+  go:
+    output: internal/api
+    package: apis
+    server: chi
+    generics: true
+  python:
+    output: client
+`,
+		"empty.yaml": "",
+		"unknown.yaml": `
+generators:
+  java: {}
+`,
+		"invalid.yaml": `
+inputs: ["api/["]
+generators:
+  go:
+    package: my-api
+    server: beego
+  ts:
+    output: /web
+`,
+	})
+
+	c, err := LoadConfig(filepath.Join(dir, "api1.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api/**/*.api"}, c.Inputs)
+	assert.Equal(t, []string{"**/internal/*.api"}, c.Ignore)
+	assert.Equal(t, &OpenAPIConfig{
+		Output:  "docs/api",
+		Info:    OpenAPIInfo{Title: "demo", Version: "1.0.0"},
+		Servers: []OpenAPIServer{{URL: "https://example.com/api"}},
+	}, c.Generators.OpenAPI)
+	assert.Equal(t, &GoConfig{Output: "internal/api", Package: "apis", Server: "chi", Generics: true}, c.Generators.Go)
+	assert.Nil(t, c.Generators.TypeScript)
+	assert.Equal(t, []string{TargetOpenAPI, TargetGo, TargetPython}, c.Targets())
+
+	c, err = LoadConfig(filepath.Join(dir, "empty.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, c.Targets())
+
+	_, err = LoadConfig(filepath.Join(dir, "unknown.yaml"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "field java not found")
+
+	_, err = LoadConfig(filepath.Join(dir, "invalid.yaml"))
+	assert.Equal(t, &ConfigError{
+		File: filepath.Join(dir, "invalid.yaml"),
+		Problems: []string{
+			"inputs[0]: invalid glob pattern [api/[]",
+			"generators.go.package: invalid package name [my-api]",
+			"generators.go.server: unknown server [beego], expecting one of [chi, echo, gin, nethttp]",
+			"generators.ts.output: should be a relative path, got [/web]",
+		},
+	}, err)
+}
+
+func TestConfigRender(t *testing.T) {
+	dir := t.TempDir()
+	api := `group ping
+
+interface Api {
+  # @route GET /ping
+  ping(): string
+}
+`
+	writeFiles(t, dir, map[string]string{
+		"api/user.api":          "group user\n",
+		"api/v1/order.api":      "group order\n",
+		"api/internal/ping.api": api,
+		"api/readme.md":         "",
+	})
+
+	c := &Config{
+		Inputs: []string{filepath.Join(dir, "api/**/*.api")},
+		Ignore: []string{"**/internal/*.api"},
+		Generators: GeneratorsConfig{
+			OpenAPI: &OpenAPIConfig{
+				Output: "docs/api",
+				Info:   OpenAPIInfo{Title: "demo", Version: "1.0.0"},
+			},
+			Go: &GoConfig{Output: "internal/apis", Package: "apis"},
+		},
+	}
+	files, err := c.FindFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "api/user.api"),
+		filepath.Join(dir, "api/v1/order.api"),
+	}, files)
+
+	c.Inputs = []string{filepath.Join(dir, "api/none/*.api")}
+	_, err = c.FindFiles()
+	assert.EqualError(t, err, "no files match pattern ["+c.Inputs[0]+"]")
+
+	c.Inputs = []string{filepath.Join(dir, "api/internal")}
+	c.Ignore = nil
+	files, err = c.FindFiles()
+	assert.NoError(t, err)
+
+	r := NewRender()
+	r.Configure(c)
+	assert.Equal(t, []string{TargetOpenAPI, TargetGo}, r.Targets)
+	codeFiles, err := r.RenderFiles(files)
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range codeFiles {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"docs/api/api1.json",
+		"docs/api/openapi.json",
+		"docs/api/openapi.go",
+		"internal/apis/ping.go",
+		"internal/apis/ping_route.go",
+		"internal/apis/ping_client.go",
+		"internal/apis/zz_helper.go",
+		"internal/apis/zz_client.go",
+	}, names)
+	assert.Contains(t, codeFiles[1].Content, `"title": "demo"`)
+	assert.Contains(t, codeFiles[2].Content, "package api\n")
+	assert.Contains(t, codeFiles[3].Content, "package apis\n")
+}
+
+func TestConfigMatchFile(t *testing.T) {
+	c := &Config{
+		Inputs: []string{"api/**/*.api", "common", "ping.api"},
+		Ignore: []string{"**/internal/*.api"},
+	}
+	for file, expected := range map[string]bool{
+		"api/user.api":          true,
+		"api/v1/order.api":      true,
+		"api/internal/ping.api": false,
+		"api/readme.md":         false,
+		"common/types.api":      true,
+		"commons/types.api":     false,
+		"ping.api":              true,
+		"pong.api":              false,
+	} {
+		ok, err := c.MatchFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, file)
+	}
+	// the working dir by default, or dirs outside of it
+	for input, expected := range map[string]bool{
+		".":    false,
+		"..":   true,
+		"../*": true,
+	} {
+		c := &Config{Inputs: []string{input}}
+		ok, err := c.MatchFile("../user.api")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, input)
+	}
+}
+
+func TestConfigRenderGenerics(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"api/page.api": `group page
+
+struct Page<T> {
+  items: [T]
+  total: int
+}
+
+struct User {
+  name: string
+}
+
+interface Api {
+  # @route GET /users
+  listUsers(): Page<User>
+}
+`,
+		"api1.yaml": `
+inputs:
+  - "api/*.api"
+generators:
+  go:
+    output: pkg/api
+    generics: true
+`,
+	})
+
+	c, err := LoadConfig(filepath.Join(dir, "api1.yaml"))
+	assert.NoError(t, err)
+	c.Inputs = []string{filepath.Join(dir, c.Inputs[0])}
+	files, err := c.FindFiles()
+	assert.NoError(t, err)
+
+	r := NewRender()
+	r.Configure(c)
+	assert.True(t, r.GoGenerics)
+	codeFiles, err := r.RenderFiles(files)
+	assert.NoError(t, err)
+	assert.Equal(t, "pkg/api/page.go", codeFiles[0].Name)
+	assert.Contains(t, codeFiles[0].Content, "type Page[T any] struct {")
+	assert.Contains(t, codeFiles[0].Content, "Page[User]")
+	assert.NotContains(t, codeFiles[0].Content, "Page_User")
+
+	c.Generators.Go.Generics = false
+	r = NewRender()
+	r.Configure(c)
+	codeFiles, err = r.RenderFiles(files)
+	assert.NoError(t, err)
+	assert.Contains(t, codeFiles[0].Content, "type Page_User struct {")
+}
@@ -1,16 +1,50 @@
 package all
 
 import (
+	"path"
+	"strings"
+
 	"github.com/jinzhenj/api1/pkg/api1"
 	"github.com/jinzhenj/api1/pkg/golang"
 	"github.com/jinzhenj/api1/pkg/openapi"
+	"github.com/jinzhenj/api1/pkg/python"
+	"github.com/jinzhenj/api1/pkg/typescript"
 	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// code generating targets
+const (
+	TargetOpenAPI    = "openapi"
+	TargetGo         = "go"
+	TargetTypeScript = "ts"
+	TargetPython     = "python"
 )
 
+const defaultOpenAPIDir = "doc"
+
+// AllTargets are all the supported targets
+var AllTargets = []string{TargetOpenAPI, TargetGo, TargetTypeScript, TargetPython}
+
+// DefaultTargets are targets generated if not specified
+var DefaultTargets = []string{TargetOpenAPI, TargetGo, TargetTypeScript}
+
 type Render struct {
+	// Targets to generate, DefaultTargets if empty
+	Targets []string
+	// GoServer is the web framework of generated Go routes, see golang.Render.Server
+	GoServer string
+	// GoGenerics renders generic structs as Go generics, see golang.Render.Generics
+	GoGenerics bool
+	// OpenAPIDir is the dir of `api1.json`, `openapi.json` & `openapi.go`,
+	// defaults to `doc`
+	OpenAPIDir string
+
 	parser        *api1.Parser
 	openapiRender *openapi.Render
 	golangRender  *golang.Render
+	tsRender      *typescript.Render
+	pyRender      *python.Render
 }
 
 func NewRender() *Render {
@@ -18,7 +52,43 @@ func NewRender() *Render {
 		parser:        &api1.Parser{},
 		openapiRender: &openapi.Render{},
 		golangRender:  &golang.Render{},
+		tsRender:      &typescript.Render{},
+		pyRender:      &python.Render{},
+	}
+}
+
+// ParseTargets parses comma separated targets, e.g. `go,python`
+func ParseTargets(s string) ([]string, error) {
+	var targets []string
+	for _, target := range strings.Split(s, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		var ok bool
+		for _, t := range AllTargets {
+			ok = ok || t == target
+		}
+		if !ok {
+			return nil, errors.Errorf("unknown target [%s], expecting one of [%s]",
+				target, strings.Join(AllTargets, ", "))
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func (r *Render) hasTarget(target string) bool {
+	targets := r.Targets
+	if len(targets) == 0 {
+		targets = DefaultTargets
+	}
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
 	}
+	return false
 }
 
 func (r *Render) RenderFiles(files []string) ([]CodeFile, error) {
@@ -30,43 +100,82 @@ func (r *Render) RenderFiles(files []string) ([]CodeFile, error) {
 	if err = schema.SupplyRouteInfo(); err != nil {
 		return nil, err
 	}
-	openAPI, err := r.openapiRender.Render(schema)
-	if err != nil {
-		return nil, err
-	}
-	goFiles, err := r.golangRender.Render(schema)
-	if err != nil {
-		return nil, err
-	}
-	codeFiles = append(codeFiles, CodeFile{
-		Name:    "doc/api1.json",
-		Content: utils.ToJson(schema) + "\n",
-	})
-	codeFiles = append(codeFiles, CodeFile{
-		Name:    "doc/openapi.json",
-		Content: utils.ToJson(openAPI) + "\n",
-	})
-	codeFiles = append(codeFiles, CodeFile{
-		Name:    "doc/openapi.go",
-		Content: renderOpenAPIGoFile(openAPI),
-	})
-	for _, goFile := range goFiles {
+	if r.hasTarget(TargetOpenAPI) {
+		openAPI, err := r.openapiRender.Render(schema)
+		if err != nil {
+			return nil, err
+		}
+		dir := strings.Trim(r.OpenAPIDir, "/")
+		if dir == "" {
+			dir = defaultOpenAPIDir
+		}
+		codeFiles = append(codeFiles, CodeFile{
+			Name:    dir + "/api1.json",
+			Content: utils.ToJson(schema) + "\n",
+		})
+		codeFiles = append(codeFiles, CodeFile{
+			Name:    dir + "/openapi.json",
+			Content: utils.ToJson(openAPI) + "\n",
+		})
 		codeFiles = append(codeFiles, CodeFile{
-			Name:    goFile.Name,
-			Content: goFile.Code(),
+			Name:    dir + "/openapi.go",
+			Content: renderOpenAPIGoFile(openAPI, path.Base(dir)),
 		})
 	}
+	if r.hasTarget(TargetGo) {
+		r.golangRender.Server = r.GoServer
+		r.golangRender.Generics = r.GoGenerics
+		goFiles, err := r.golangRender.Render(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, goFile := range goFiles {
+			codeFiles = append(codeFiles, CodeFile{
+				Name:    goFile.Name,
+				Content: goFile.Code(),
+			})
+		}
+	}
+	if r.hasTarget(TargetTypeScript) {
+		tsFiles, err := r.tsRender.Render(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, tsFile := range tsFiles {
+			codeFiles = append(codeFiles, CodeFile{
+				Name:    tsFile.Name,
+				Content: tsFile.Code(),
+			})
+		}
+	}
+	if r.hasTarget(TargetPython) {
+		pyFiles, err := r.pyRender.Render(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, pyFile := range pyFiles {
+			codeFiles = append(codeFiles, CodeFile{
+				Name:    pyFile.Name,
+				Content: pyFile.Code(),
+			})
+		}
+	}
 	return codeFiles, nil
 }
 
-func renderOpenAPIGoFile(openAPI *openapi.OpenAPI) string {
+// renderOpenAPIGoFile renders openAPI as a constant of package pkg, which
+// falls back to `doc` if it is not an identifier
+func renderOpenAPIGoFile(openAPI *openapi.OpenAPI, pkg string) string {
+	if !reIdentifier.MatchString(pkg) {
+		pkg = defaultOpenAPIDir
+	}
 	openAPI.Servers = []openapi.Server{{
 		Url: "{url}",
 		Variables: map[string]openapi.ServerVariable{
 			"url": {Default: "{{.BasePath}}"},
 		},
 	}}
-	code := "package doc\n\n"
+	code := "package " + pkg + "\n\n"
 	code += "const OpenAPI = `" + utils.ToJson(openAPI) + "`\n"
 	return code
 }
@@ -1,9 +1,9 @@
 package api1
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
 var builtinTypes []ScalarType = []ScalarType{
@@ -15,134 +15,320 @@ var builtinTypes []ScalarType = []ScalarType{
 	{HasName: HasName{Name: "any"}},
 }
 
+// Check checks the whole schema, and returns all the problems found
+// as sorted Diagnostics.
+//
+// Types are resolved per group: besides builtin types, a group sees its own
+// types, and types of the imported groups (or of all the groups if it imports
+// nothing). Resolved references are qualified by TypeRef.Group.
 func (schema *Schema) Check() error {
-	names := make(map[string]bool)
+	var diags Diagnostics
+	// names & types are keyed by qualified names, e.g. `common.Status`,
+	// builtin types are keyed by plain names
+	names := make(map[string]Pos)
 	types := make(map[string]interface{})
 
-	addName := func(name string) error {
-		if _, ok := names[name]; ok {
-			return errors.Errorf("Type [%s] defined more than once", name)
+	report := func(pos Pos, code string, format string, args ...interface{}) {
+		diags = append(diags, newError(pos, code, format, args...))
+	}
+	addName := func(group string, name string, pos Pos) bool {
+		prev, ok := names[qualName(group, name)]
+		if !ok {
+			prev, ok = names[name]
 		}
-		names[name] = true
-		return nil
+		if ok {
+			if prev.IsValid() {
+				report(pos, CodeDuplicated,
+					"Type [%s] defined more than once, previous definition at %s", name, prev)
+			} else {
+				report(pos, CodeDuplicated,
+					"Type [%s] defined more than once", name)
+			}
+			return false
+		}
+		names[qualName(group, name)] = pos
+		return true
 	}
-	addType := func(name string, t interface{}) error {
-		if err := addName(name); err != nil {
-			return err
+	addType := func(group string, name string, pos Pos, t interface{}) {
+		if addName(group, name, pos) {
+			types[qualName(group, name)] = t
 		}
-		types[name] = t
-		return nil
 	}
-	var checkType func(t *TypeRef, nullable bool) error
-	checkType = func(t *TypeRef, nullable bool) error {
+
+	// errors are keyed by qualified names, which share names with types
+	errorTypes := make(map[string]interface{})
+
+	// resolveIn finds the type (or error, by kind) referenced in group g
+	// from defs, and qualifies t with the group defining it
+	resolveIn := func(t *TypeRef, g *ApiGroup, defs map[string]interface{}, kind string) (interface{}, bool) {
+		if t.Group != "" {
+			if !hasGroup(schema, t.Group) {
+				report(t.Pos, CodeUnknownType, "Group [%s] cannot be found", t.Group)
+				return nil, false
+			}
+			if t.Group != g.Name && len(g.Imports) > 0 && !imports(g, t.Group) {
+				report(t.Pos, CodeImport, "Group [%s] is not imported", t.Group)
+				return nil, false
+			}
+			typ, ok := defs[t.QualName()]
+			if !ok {
+				report(t.Pos, CodeUnknownType, "%s [%s] cannot be found", kind, t.QualName())
+			}
+			return typ, ok
+		}
+		if typ, ok := defs[t.Name]; ok {
+			return typ, true
+		}
+		if typ, ok := defs[qualName(g.Name, t.Name)]; ok {
+			t.Group = g.Name
+			return typ, true
+		}
+		var found []string
+		for _, name := range visibleGroups(schema, g) {
+			if _, ok := defs[qualName(name, t.Name)]; ok && name != g.Name {
+				found = append(found, name)
+			}
+		}
+		switch len(found) {
+		case 0:
+			report(t.Pos, CodeUnknownType, "%s [%s] cannot be found", kind, t.Name)
+			return nil, false
+		case 1:
+			t.Group = found[0]
+			return defs[t.QualName()], true
+		default:
+			report(t.Pos, CodeAmbiguous,
+				"%s [%s] is ambiguous, found in groups [%s]", kind, t.Name, strings.Join(found, ", "))
+			return nil, false
+		}
+	}
+	resolveType := func(t *TypeRef, g *ApiGroup) (interface{}, bool) {
+		return resolveIn(t, g, types, "Type")
+	}
+
+	// g is the group where t is referenced, params are type params in scope,
+	// e.g. `T` in `struct Page<T>`
+	var checkType func(t *TypeRef, nullable bool, pos Pos, g *ApiGroup, params map[string]bool)
+	var checkMapKey func(t *TypeRef)
+	checkType = func(t *TypeRef, nullable bool, pos Pos, g *ApiGroup, params map[string]bool) {
 		if t == nil {
-			if nullable {
-				return nil
+			if !nullable {
+				report(pos, CodeUnknownType, "Type cannot be empty")
 			}
-			return errors.New("Type cannot be empty")
+			return
 		}
 		if t.Name != "" {
-			_, ok := types[t.Name]
+			if t.Group == "" && params[t.Name] {
+				if len(t.TypeArgs) > 0 {
+					report(t.Pos, CodeGeneric,
+						"Type param [%s] cannot have type arguments", t.Name)
+				}
+				return
+			}
+			typ, ok := resolveType(t, g)
 			if !ok {
-				return errors.Errorf("Type [%s] cannot be found", t.Name)
+				return
 			}
-			return nil
+			var typeParams []string
+			if st, ok := typ.(StructType); ok {
+				typeParams = st.TypeParams
+			}
+			if len(typeParams) == 0 && len(t.TypeArgs) > 0 {
+				report(t.Pos, CodeGeneric, "Type [%s] is not generic", t.Name)
+			} else if len(t.TypeArgs) != len(typeParams) {
+				report(t.Pos, CodeGeneric,
+					"Type [%s] expects %d type argument(s), found %d",
+					t.Name, len(typeParams), len(t.TypeArgs))
+			}
+			for i := range t.TypeArgs {
+				checkType(&t.TypeArgs[i], false, t.Pos, g, params)
+			}
+			return
+		}
+		if t.KeyType != nil {
+			checkType(t.KeyType, false, t.Pos, g, params)
+			checkMapKey(t.KeyType)
 		}
 		if t.ItemType != nil {
-			return checkType(t.ItemType, false)
+			checkType(t.ItemType, false, t.Pos, g, params)
+		}
+	}
+	// map keys are restricted to string, int & enum types
+	checkMapKey = func(t *TypeRef) {
+		if t.Nullable {
+			report(t.Pos, CodeMapKey, "Map key type cannot be nullable")
+		}
+		if t.Name == "string" || t.Name == "int" {
+			return
+		}
+		if _, ok := types[t.QualName()].(EnumType); ok {
+			return
+		}
+		if t.Name == "" {
+			report(t.Pos, CodeMapKey,
+				"Map key type must be string, int or enum")
+		} else if _, ok := types[t.QualName()]; ok {
+			report(t.Pos, CodeMapKey,
+				"Map key type must be string, int or enum, found [%s]", t.Name)
 		}
-		return nil
 	}
 
 	for _, t := range builtinTypes {
-		addType(t.Name, t)
+		addType("", t.Name, t.Pos, t)
 	}
 
+	// check imports can be resolved
+	resolveImports(schema, report)
+
 	// check no duplications
 	for _, g := range schema.Groups {
 		for _, sc := range g.ScalarTypes {
-			if err := addType(sc.Name, sc); err != nil {
-				return err
-			}
+			addType(g.Name, sc.Name, sc.Pos, sc)
 		}
 		for _, en := range g.EnumTypes {
-			if err := addType(en.Name, en); err != nil {
-				return err
-			}
-			var optionNames []string
+			addType(g.Name, en.Name, en.Pos, en)
+			var options []named
 			for _, option := range en.Options {
-				optionNames = append(optionNames, option.Name)
+				options = append(options, named{option.HasName, option.HasPos})
 			}
-			dup := duplicated(optionNames)
-			if len(dup) > 0 {
-				return errors.Errorf("Enum [%s] has duplicated options [%s]",
-					en.Name, strings.Join(dup, ", "))
+			for _, d := range duplicated(options) {
+				report(d.Pos, CodeDuplicated,
+					"Enum [%s] has duplicated option [%s]", en.Name, d.Name)
 			}
 		}
 		for _, st := range g.StructTypes {
-			if err := addType(st.Name, st); err != nil {
-				return err
-			}
-			var fieldNames []string
+			addType(g.Name, st.Name, st.Pos, st)
+			var fields []named
 			for _, field := range st.Fields {
-				fieldNames = append(fieldNames, field.Name)
+				fields = append(fields, named{field.HasName, field.HasPos})
 			}
-			dup := duplicated(fieldNames)
-			if len(dup) > 0 {
-				return errors.Errorf("Struct [%s] has duplicated fields [%s]",
-					st.Name, strings.Join(dup, ", "))
+			for _, d := range duplicated(fields) {
+				report(d.Pos, CodeDuplicated,
+					"Struct [%s] has duplicated field [%s]", st.Name, d.Name)
 			}
 		}
-		for _, iface := range g.Ifaces {
-			if err := addName(iface.Name); err != nil {
-				return err
+		for _, un := range g.UnionTypes {
+			addType(g.Name, un.Name, un.Pos, un)
+		}
+		for _, er := range g.ErrorTypes {
+			if addName(g.Name, er.Name, er.Pos) {
+				errorTypes[qualName(g.Name, er.Name)] = er
 			}
-			var funNames []string
+		}
+		for _, iface := range g.Ifaces {
+			addName(g.Name, iface.Name, iface.Pos)
+			var funs []named
 			for _, fun := range iface.Funs {
-				funNames = append(funNames, fun.Name)
+				funs = append(funs, named{fun.HasName, fun.HasPos})
 			}
-			dup := duplicated(funNames)
-			if len(dup) > 0 {
-				return errors.Errorf("Interface [%s] has duplicated functions [%s]",
-					iface.Name, strings.Join(dup, ", "))
+			for _, d := range duplicated(funs) {
+				report(d.Pos, CodeDuplicated,
+					"Interface [%s] has duplicated function [%s]", iface.Name, d.Name)
 			}
 			for _, fun := range iface.Funs {
-				var paramNames []string
+				var params []named
 				for _, param := range fun.Params {
-					paramNames = append(paramNames, param.Name)
+					params = append(params, named{param.HasName, param.HasPos})
 				}
-				dup := duplicated(paramNames)
-				if len(dup) > 0 {
-					return errors.Errorf("Function [%s.%s] has duplicated params [%s]",
-						iface.Name, fun.Name, strings.Join(dup, ", "))
+				for _, d := range duplicated(params) {
+					report(d.Pos, CodeDuplicated,
+						"Function [%s.%s] has duplicated param [%s]",
+						iface.Name, fun.Name, d.Name)
 				}
 			}
 		}
 	}
 
 	// check referenced types can all be found
-	for _, g := range schema.Groups {
+	for i := range schema.Groups {
+		g := &schema.Groups[i]
 		for _, st := range g.StructTypes {
-			for _, field := range st.Fields {
-				if err := checkType(field.Type, false); err != nil {
-					return err
+			params := make(map[string]bool)
+			for _, param := range st.TypeParams {
+				_, isBuiltin := types[param]
+				_, isDefined := types[qualName(g.Name, param)]
+				if params[param] {
+					report(st.Pos, CodeDuplicated,
+						"Struct [%s] has duplicated type param [%s]", st.Name, param)
+				} else if isBuiltin || isDefined {
+					report(st.Pos, CodeGeneric,
+						"Struct [%s] type param [%s] conflicts with type [%s]",
+						st.Name, param, param)
 				}
+				params[param] = true
+			}
+			for i := range st.Extends {
+				checkType(&st.Extends[i], false, st.Pos, g, params)
+			}
+			for _, field := range st.Fields {
+				checkType(field.Type, false, field.Pos, g, params)
+			}
+		}
+		for _, un := range g.UnionTypes {
+			for i := range un.Types {
+				checkType(&un.Types[i], false, un.Pos, g, nil)
 			}
 		}
+		for _, er := range g.ErrorTypes {
+			checkType(er.Payload, true, er.Pos, g, nil)
+		}
 		for _, iface := range g.Ifaces {
-			for _, fun := range iface.Funs {
-				if err := checkType(fun.Type, true); err != nil {
-					return err
+			for i := range iface.Funs {
+				fun := &iface.Funs[i]
+				checkType(fun.Type, true, fun.Pos, g, nil)
+				if fun.Stream != nil {
+					checkType(fun.Stream.Item, true, fun.Stream.Pos, g, nil)
 				}
+				checkStream(fun, iface.Name, report)
 				for _, param := range fun.Params {
-					if err := checkType(param.Type, false); err != nil {
-						return err
+					checkType(param.Type, false, param.Pos, g, nil)
+				}
+				fun.Throws = nil
+				for _, t := range parseThrows(fun, iface.Name, report) {
+					if _, ok := resolveIn(&t, g, errorTypes, "Error"); ok {
+						fun.Throws = append(fun.Throws, t)
 					}
 				}
 			}
 		}
 	}
 
+	// check errors respond client or server error statuses with struct data
+	checkErrorTypes(schema, types, report)
+
+	// check default values & constraints fit the types, after types are resolved
+	for _, g := range schema.Groups {
+		for _, st := range g.StructTypes {
+			params := make(map[string]bool)
+			for _, param := range st.TypeParams {
+				params[param] = true
+			}
+			for _, field := range st.Fields {
+				checkDefault(field.Default, field.Type, params, types, report)
+				checkConstraints(&field.HasComments, field.Pos, field.Type, field.Default, params, types, report)
+			}
+		}
+		for _, iface := range g.Ifaces {
+			for _, fun := range iface.Funs {
+				for _, param := range fun.Params {
+					checkDefault(param.Default, param.Type, nil, types, report)
+					checkConstraints(&param.HasComments, param.Pos, param.Type, param.Default, nil, types, report)
+				}
+			}
+		}
+	}
+
+	// check generic structs can be instantiated
+	if _, err := schema.Instances(); err != nil {
+		diags.Add(err)
+	}
+
+	// check struct inheritance
+	checkStructExtends(schema, types, report)
+
+	// check union types
+	checkUnions(schema, types, report)
+
 	// check enum options' value have same type
 	for _, g := range schema.Groups {
 		for _, en := range g.EnumTypes {
@@ -157,7 +343,8 @@ func (schema *Schema) Check() error {
 				}
 			}
 			if findIntVal && (findNoVal || findStrVal) {
-				return errors.Errorf("Enum [%s] has mixed value types", en.Name)
+				report(en.Pos, CodeEnumValue,
+					"Enum [%s] has mixed value types", en.Name)
 			}
 		}
 	}
@@ -165,22 +352,345 @@ func (schema *Schema) Check() error {
 	// group duplicated???
 	// check pkg not empty
 	// check struct, enum, interface is not empty
-	return nil
+	return diags.Err()
 }
 
-func duplicated(items []string) []string {
-	m := make(map[string]int)
-	for _, item := range items {
-		if _, ok := m[item]; !ok {
-			m[item] = 0
+var reIdent = regexp.MustCompile("^[A-Za-z_][0-9A-Za-z_]*$")
+
+type reportFunc func(pos Pos, code string, format string, args ...interface{})
+
+// checkStructExtends checks parents of structs are all structs, and there is
+// neither circular inheritance nor conflicting fields.
+func checkStructExtends(schema *Schema, types map[string]interface{}, report reportFunc) {
+	// structs are keyed by qualified names
+	structs := make(map[string]StructType)
+	for _, g := range schema.Groups {
+		for _, st := range g.StructTypes {
+			for _, parent := range st.Extends {
+				if parent.Name == "" || parent.Nullable {
+					report(parent.Pos, CodeExtends,
+						"Struct [%s] can only extend structs", st.Name)
+					continue
+				}
+				_, isStruct := types[parent.QualName()].(StructType)
+				_, found := types[parent.QualName()]
+				if found && !isStruct || parent.Group == "" && isTypeParam(st, parent.Name) {
+					report(parent.Pos, CodeExtends,
+						"Struct [%s] can only extend structs, found [%s]",
+						st.Name, parent.Name)
+				}
+			}
+			if _, ok := structs[qualName(g.Name, st.Name)]; !ok {
+				structs[qualName(g.Name, st.Name)] = st
+			}
 		}
-		m[item]++
 	}
-	var duplicated []string
-	for item, count := range m {
-		if count > 1 {
-			duplicated = append(duplicated, item)
+
+	// detect cycles, structs in cycles are skipped in the following checks
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	cyclic := make(map[string]bool)
+	var visit func(key string, path []string)
+	visit = func(key string, path []string) {
+		st, ok := structs[key]
+		if !ok || state[key] == visited {
+			return
+		}
+		path = append(path, key)
+		if state[key] == visiting {
+			var start int
+			for i, n := range path {
+				if n == key {
+					start = i
+					break
+				}
+			}
+			var names []string
+			for _, n := range path[start:] {
+				cyclic[n] = true
+				names = append(names, structs[n].Name)
+			}
+			report(st.Pos, CodeExtends, "Struct [%s] has circular inheritance [%s]",
+				st.Name, strings.Join(names, " -> "))
+			return
+		}
+		state[key] = visiting
+		for _, parent := range st.Extends {
+			visit(parent.QualName(), path)
+		}
+		state[key] = visited
+	}
+	for _, g := range schema.Groups {
+		for _, st := range g.StructTypes {
+			visit(qualName(g.Name, st.Name), nil)
+		}
+	}
+
+	// fields returns all the fields of a struct (including inherited ones)
+	type inheritedField struct {
+		name string
+		from string
+	}
+	memo := make(map[string][]inheritedField)
+	var fields func(key string) []inheritedField
+	fields = func(key string) []inheritedField {
+		if a, ok := memo[key]; ok {
+			return a
+		}
+		st := structs[key]
+		var a []inheritedField
+		for _, parent := range st.Extends {
+			if _, ok := structs[parent.QualName()]; ok && !cyclic[parent.QualName()] {
+				for _, f := range fields(parent.QualName()) {
+					a = append(a, inheritedField{name: f.name, from: parent.Name})
+				}
+			}
+		}
+		for _, f := range st.Fields {
+			a = append(a, inheritedField{name: f.Name, from: st.Name})
+		}
+		memo[key] = a
+		return a
+	}
+	for _, g := range schema.Groups {
+		for _, st := range g.StructTypes {
+			if len(st.Extends) == 0 || cyclic[qualName(g.Name, st.Name)] {
+				continue
+			}
+			inherited := make(map[string]string)
+			reported := make(map[string]bool)
+			for _, parent := range st.Extends {
+				if _, ok := structs[parent.QualName()]; !ok || cyclic[parent.QualName()] {
+					continue
+				}
+				for _, f := range fields(parent.QualName()) {
+					from, ok := inherited[f.name]
+					if !ok {
+						inherited[f.name] = parent.Name
+					} else if from != parent.Name && !reported[f.name] {
+						report(parent.Pos, CodeExtends,
+							"Struct [%s] inherits conflicting field [%s] from [%s] and [%s]",
+							st.Name, f.name, from, parent.Name)
+						reported[f.name] = true
+					}
+				}
+			}
+			for _, f := range st.Fields {
+				if from, ok := inherited[f.Name]; ok {
+					report(f.Pos, CodeExtends,
+						"Struct [%s] field [%s] conflicts with the one inherited from [%s]",
+						st.Name, f.Name, from)
+				}
+			}
+		}
+	}
+}
+
+// checkUnions checks union types are composed of different structs, and all
+// of them have the discriminator field (if specified) of string type.
+func checkUnions(schema *Schema, types map[string]interface{}, report reportFunc) {
+	// findField finds field in struct, including inherited ones
+	var findField func(key string, name string, visited map[string]bool) *StructField
+	findField = func(key string, name string, visited map[string]bool) *StructField {
+		st, ok := types[key].(StructType)
+		if !ok || visited[key] {
+			return nil
+		}
+		visited[key] = true
+		for i := range st.Fields {
+			if st.Fields[i].Name == name {
+				return &st.Fields[i]
+			}
+		}
+		for _, parent := range st.Extends {
+			if f := findField(parent.QualName(), name, visited); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+
+	for _, g := range schema.Groups {
+		for _, un := range g.UnionTypes {
+			if len(un.Types) < 2 {
+				report(un.Pos, CodeUnion,
+					"Union [%s] must have at least 2 types", un.Name)
+			}
+			discriminator, _ := un.SemComments["discriminator"].(string)
+			if _, ok := un.SemComments["discriminator"]; ok && discriminator == "" {
+				report(un.Pos, CodeUnion,
+					"Union [%s] has invalid discriminator", un.Name)
+			}
+			var members []named
+			for _, t := range un.Types {
+				if t.Name == "" || t.Nullable {
+					report(t.Pos, CodeUnion,
+						"Union [%s] can only be composed of structs", un.Name)
+					continue
+				}
+				member := t.QualName()
+				if t.Group == g.Name {
+					member = t.Name
+				}
+				members = append(members, named{HasName{member}, t.HasPos})
+				st, ok := types[t.QualName()].(StructType)
+				if !ok {
+					if _, ok := types[t.QualName()]; ok {
+						report(t.Pos, CodeUnion,
+							"Union [%s] can only be composed of structs, found [%s]",
+							un.Name, t.Name)
+					}
+					continue
+				}
+				if len(st.TypeParams) > 0 {
+					report(t.Pos, CodeUnion,
+						"Union [%s] cannot be composed of generic struct [%s]",
+						un.Name, t.Name)
+					continue
+				}
+				if discriminator == "" {
+					continue
+				}
+				f := findField(t.QualName(), discriminator, make(map[string]bool))
+				if f == nil {
+					report(t.Pos, CodeUnion,
+						"Struct [%s] in union [%s] has no discriminator field [%s]",
+						t.Name, un.Name, discriminator)
+				} else if f.Type == nil || f.Type.Name != "string" || f.Type.Nullable {
+					report(t.Pos, CodeUnion,
+						"Struct [%s] in union [%s] has discriminator field [%s] not of type string",
+						t.Name, un.Name, discriminator)
+				}
+			}
+			for _, d := range duplicated(members) {
+				report(d.Pos, CodeDuplicated,
+					"Union [%s] has duplicated type [%s]", un.Name, d.Name)
+			}
+		}
+	}
+}
+
+// parseThrows returns errors in `@throws` of fun, e.g. `NotFound, common.Conflict`
+func parseThrows(fun *Fun, iface string, report reportFunc) []TypeRef {
+	v, ok := fun.SemComments["throws"]
+	if !ok {
+		return nil
+	}
+	s, _ := v.(string)
+	var throws []TypeRef
+	var names []named
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		t := TypeRef{}
+		t.Pos = fun.Pos
+		parts := strings.Split(name, ".")
+		if len(parts) == 2 {
+			t.Group, t.Name = parts[0], parts[1]
+		} else if len(parts) == 1 {
+			t.Name = parts[0]
+		}
+		if !reIdent.MatchString(t.Name) || t.Group != "" && !reIdent.MatchString(t.Group) {
+			report(fun.Pos, CodeErrorType, "Function [%s.%s] has invalid throws [%s]",
+				iface, fun.Name, s)
+			return nil
+		}
+		throws = append(throws, t)
+		names = append(names, named{HasName{name}, t.HasPos})
+	}
+	for _, d := range duplicated(names) {
+		report(d.Pos, CodeDuplicated, "Function [%s.%s] throws [%s] more than once",
+			iface, fun.Name, d.Name)
+	}
+	return throws
+}
+
+// checkStream checks the content type of `@produces` matches the stream, and
+// sets it to the stream
+func checkStream(fun *Fun, iface string, report reportFunc) {
+	v, ok := fun.SemComments["produces"]
+	produces := strings.ToLower(strings.TrimSpace(fmt.Sprint(v)))
+	if fun.Stream == nil {
+		if ok {
+			report(fun.Pos, CodeStream, "Function [%s.%s] with @produces should return stream", iface, fun.Name)
+		}
+		return
+	}
+	switch {
+	case !ok && fun.Stream.Item != nil:
+		fun.Stream.ContentType = MimeEventStream
+	case !ok:
+		fun.Stream.ContentType = MimeOctetStream
+	case fun.Stream.Item != nil && produces != MimeEventStream:
+		report(fun.Pos, CodeStream,
+			"Function [%s.%s] returning stream<T> should produce %s, found [%s], return stream of bytes instead",
+			iface, fun.Name, MimeEventStream, produces)
+	case fun.Stream.Item == nil && (produces == MimeEventStream || produces == "" || produces == mimeJSON):
+		report(fun.Pos, CodeStream,
+			"Function [%s.%s] returning stream of bytes cannot produce [%s]", iface, fun.Name, produces)
+	default:
+		fun.Stream.ContentType = produces
+	}
+}
+
+// checkErrorTypes checks statuses of errors are 4xx or 5xx, and payloads are
+// non-generic structs
+func checkErrorTypes(schema *Schema, types map[string]interface{}, report reportFunc) {
+	for _, g := range schema.Groups {
+		for _, er := range g.ErrorTypes {
+			if er.Status < 400 || er.Status > 599 {
+				report(er.Pos, CodeErrorType,
+					"Error [%s] status must be 4xx or 5xx, found [%d]", er.Name, er.Status)
+			}
+			t := er.Payload
+			if t == nil {
+				continue
+			}
+			if t.Name == "" || t.Nullable {
+				report(t.Pos, CodeErrorType, "Error [%s] payload must be a struct", er.Name)
+				continue
+			}
+			typ, found := types[t.QualName()]
+			if !found {
+				// reported as unknown type
+				continue
+			}
+			if st, ok := typ.(StructType); !ok {
+				report(t.Pos, CodeErrorType,
+					"Error [%s] payload must be a struct, found [%s]", er.Name, t.Name)
+			} else if len(st.TypeParams) > 0 {
+				report(t.Pos, CodeErrorType,
+					"Error [%s] payload cannot be generic struct [%s]", er.Name, t.Name)
+			}
+		}
+	}
+}
+
+func isTypeParam(st StructType, name string) bool {
+	for _, param := range st.TypeParams {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+type named struct {
+	HasName
+	HasPos
+}
+
+// duplicated returns items whose name appeared before
+func duplicated(items []named) []named {
+	var dup []named
+	m := make(map[string]bool)
+	for _, item := range items {
+		if m[item.Name] {
+			dup = append(dup, item)
 		}
+		m[item.Name] = true
 	}
-	return duplicated
+	return dup
 }
@@ -1,6 +1,10 @@
 package api1
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -150,16 +154,914 @@ func TestCheck(t *testing.T) {
 		}
 	`
 
-	testcases := []string{t1, t01, t2, t3, t4, t5, t6, t7, t8, t9, t10, t14, t15}
+	t16 := `
+    # test map key type
+		group t16
+
+		struct S16 {
+			f1: {[string]: int}
+			f2: {float: int}
+			f3: map<string?, int>
+		}
+	`
+
+	t17 := `
+		group t17
+
+		enum E17 {
+			O1
+		}
+
+		struct S17 {
+			f1: {string: int}
+			f2: {E17: [S17]}
+			f3: map<int, {string: string}>?
+		}
+	`
+
+	testcases := []string{t1, t01, t2, t3, t4, t5, t6, t7, t8, t9, t10, t14, t15, t16}
 	for _, testcase := range testcases {
 		_, err = parser.Parse(testcase)
 		t.Log(err)
 		assert.Error(t, err)
 	}
 
-	testcases2 := []string{t11, t12, t13}
+	testcases2 := []string{t11, t12, t13, t17}
 	for _, testcase := range testcases2 {
 		_, err = parser.Parse(testcase)
 		assert.NoError(t, err)
 	}
 }
+
+func TestCheckDiagnostic(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct T1 {
+	f1: int
+	f2: [unknown]
+}
+`
+	_, err := parser.Parse(t1)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, Pos{Line: 5, Column: 7}, ds[0].Pos)
+	assert.Equal(t, CodeUnknownType, ds[0].Code)
+	assert.Equal(t, "5:7: error: Type [unknown] cannot be found [unknown-type]", ds[0].Error())
+
+	t2 := `group t2
+
+interface T2 {
+	f1(p1: int, p1: string): int
+}
+`
+	_, err = parser.Parse(t2)
+	assert.ErrorAs(t, err, &ds)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, Pos{Line: 4, Column: 14}, ds[0].Pos)
+	assert.Equal(t, CodeDuplicated, ds[0].Code)
+
+	t3 := `group t3
+
+scalar T3
+
+enum T3 {
+  O1
+}
+`
+	_, err = parser.Parse(t3)
+	assert.ErrorAs(t, err, &ds)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, "5:1: error: Type [T3] defined more than once, "+
+		"previous definition at 3:1 [duplicated]", ds[0].Error())
+}
+
+func TestCheckAllErrors(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+enum E1 {
+	O1 = 1
+	O2
+	O1
+}
+
+struct S1 {
+	f1: unknown1
+	f1: [unknown2]
+}
+`
+	t2 := `group t1
+
+scalar S1
+
+interface I2 {
+	f1(p1: int, p1: E1): unknown3
+}
+`
+	dir := t.TempDir()
+	f1 := filepath.Join(dir, "t1.api")
+	f2 := filepath.Join(dir, "t2.api")
+	assert.NoError(t, ioutil.WriteFile(f1, []byte(t1), 0644))
+	assert.NoError(t, ioutil.WriteFile(f2, []byte(t2), 0644))
+
+	_, err := parser.ParseFiles(f2, f1)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, strings.TrimPrefix(d.Error(), dir+"/"))
+	}
+	assert.Equal(t, []string{
+		"t1.api:3:1: error: Enum [E1] has mixed value types [enum-value]",
+		"t1.api:6:2: error: Enum [E1] has duplicated option [O1] [duplicated]",
+		"t1.api:9:1: error: Type [S1] defined more than once, previous definition at " +
+			f2 + ":3:1 [duplicated]",
+		"t1.api:10:6: error: Type [unknown1] cannot be found [unknown-type]",
+		"t1.api:11:2: error: Struct [S1] has duplicated field [f1] [duplicated]",
+		"t1.api:11:7: error: Type [unknown2] cannot be found [unknown-type]",
+		"t2.api:6:14: error: Function [I2.f1] has duplicated param [p1] [duplicated]",
+		"t2.api:6:23: error: Type [unknown3] cannot be found [unknown-type]",
+	}, lines)
+}
+
+func TestCheckExtends(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct Base {
+	id: int
+}
+
+struct Audit {
+	createdAt: string
+}
+
+struct A extends Base, Audit {
+	name: string
+}
+
+struct B extends A {
+	age: int
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Base", schema.Groups[0].StructTypes[2].Extends[0].Name)
+	assert.Equal(t, "Audit", schema.Groups[0].StructTypes[2].Extends[1].Name)
+
+	t2 := `group t2
+
+enum E {
+	O1
+}
+
+struct Base {
+	id: int
+}
+
+struct Base2 extends Base {
+	name: string
+}
+
+struct A extends B {
+	a: int
+}
+
+struct B extends C {
+	b: int
+}
+
+struct C extends A {
+	c: int
+}
+
+struct D extends E, [Base], Unknown {
+	d: int
+}
+
+struct F extends Base, Base2 {
+	id: string
+}
+`
+	_, err = parser.Parse(t2)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"15:1: error: Struct [A] has circular inheritance [A -> B -> C -> A] [extends]",
+		"27:18: error: Struct [D] can only extend structs, found [E] [extends]",
+		"27:21: error: Struct [D] can only extend structs [extends]",
+		"27:29: error: Type [Unknown] cannot be found [unknown-type]",
+		"31:24: error: Struct [F] inherits conflicting field [id] from [Base] and [Base2] [extends]",
+		"32:2: error: Struct [F] field [id] conflicts with the one inherited from [Base] [extends]",
+	}, lines)
+}
+
+func TestCheckUnion(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct Base {
+	kind: string
+}
+
+struct A extends Base {
+	a: int
+}
+
+struct B {
+	kind: string
+}
+
+# @discriminator kind
+union U1 = A | B
+
+union U2 =
+	A |
+	B
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(schema.Groups[0].UnionTypes))
+	assert.Equal(t, "kind", schema.Groups[0].UnionTypes[0].SemComments["discriminator"])
+	assert.Equal(t, "B", schema.Groups[0].UnionTypes[1].Types[1].Name)
+
+	t2 := `group t2
+
+struct A {
+	kind: int
+}
+
+struct B {
+	b: int
+}
+
+enum E {
+	O1
+}
+
+# @discriminator kind
+union U1 = A | B | E | A | Unknown
+
+union U2 = [A] | B?
+
+union U3 = A
+`
+	_, err = parser.Parse(t2)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"16:12: error: Struct [A] in union [U1] has discriminator field [kind] not of type string [union]",
+		"16:16: error: Struct [B] in union [U1] has no discriminator field [kind] [union]",
+		"16:20: error: Union [U1] can only be composed of structs, found [E] [union]",
+		"16:24: error: Struct [A] in union [U1] has discriminator field [kind] not of type string [union]",
+		"16:24: error: Union [U1] has duplicated type [A] [duplicated]",
+		"16:28: error: Type [Unknown] cannot be found [unknown-type]",
+		"18:12: error: Union [U2] can only be composed of structs [union]",
+		"18:18: error: Union [U2] can only be composed of structs [union]",
+		"20:1: error: Union [U3] must have at least 2 types [union]",
+	}, lines)
+}
+
+func TestCheckErrorType(t *testing.T) {
+	parser := Parser{}
+
+	common := `group common
+
+struct Detail {
+	reason: string
+}
+
+error Conflict(409): Detail
+`
+	user := `group user
+
+# user cannot be found
+error NotFound(404): common.Detail
+error Forbidden(403)
+
+interface UserApi {
+	# @route GET /users/:id
+	# @throws NotFound, Forbidden, Conflict
+	getUser(id: int)
+}
+`
+	schema, err := parser.Parse(common, user)
+	assert.NoError(t, err)
+	er := schema.Groups[1].ErrorTypes[0]
+	assert.Equal(t, "NotFound", er.Name)
+	assert.Equal(t, 404, er.Status)
+	assert.Equal(t, []string{"user cannot be found"}, er.Comments)
+	assert.Equal(t, "common.Detail", er.Payload.QualName())
+	assert.Nil(t, schema.Groups[1].ErrorTypes[1].Payload)
+
+	var throws []string
+	for _, t := range schema.Groups[1].Ifaces[0].Funs[0].Throws {
+		throws = append(throws, t.QualName())
+	}
+	assert.Equal(t, []string{"user.NotFound", "user.Forbidden", "common.Conflict"}, throws)
+	conflict, ok := schema.FindErrorType(&schema.Groups[1].Ifaces[0].Funs[0].Throws[2])
+	assert.True(t, ok)
+	assert.Equal(t, 409, conflict.Status)
+
+	broken := `group broken
+
+enum E {
+	O1
+}
+
+struct Page<T> {
+	items: [T]
+}
+
+error E1(200)
+error E2(400): E
+error E3(400): Page<E>
+error E4(500): [E]
+error E1(500)
+
+interface Api {
+	# @throws E2, Unknown
+	f1()
+
+	# @throws E2, E2
+	f2()
+
+	# @throws E2,
+	f3()
+}
+`
+	_, err = parser.Parse(broken)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"11:1: error: Error [E1] status must be 4xx or 5xx, found [200] [error-type]",
+		"12:16: error: Error [E2] payload must be a struct, found [E] [error-type]",
+		"13:16: error: Error [E3] payload cannot be generic struct [Page] [error-type]",
+		"14:16: error: Error [E4] payload must be a struct [error-type]",
+		"15:1: error: Type [E1] defined more than once, previous definition at 11:1 [duplicated]",
+		"19:2: error: Error [Unknown] cannot be found [unknown-type]",
+		"22:2: error: Function [Api.f2] throws [E2] more than once [duplicated]",
+		"25:2: error: Function [Api.f3] has invalid throws [E2,] [error-type]",
+	}, lines)
+}
+
+func TestCheckStream(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct Page<T> {
+	items: [T]
+}
+
+struct Event {
+	name: string
+}
+
+interface Api {
+	watch(): stream<Page<Event>>
+
+	download(): stream
+
+	# @produces text/csv
+	export(): stream
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	funs := schema.Groups[0].Ifaces[0].Funs
+	assert.Nil(t, funs[0].Type)
+	assert.Equal(t, "t1.Page", funs[0].Stream.Item.QualName())
+	assert.Equal(t, MimeEventStream, funs[0].Stream.ContentType)
+	assert.Nil(t, funs[1].Stream.Item)
+	assert.Equal(t, MimeOctetStream, funs[1].Stream.ContentType)
+	assert.Equal(t, "text/csv", funs[2].Stream.ContentType)
+	instances, err := schema.Instances()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(instances))
+
+	broken := `group broken
+
+interface Api {
+	f1(): stream<Unknown>
+
+	# @produces application/octet-stream
+	f2(): stream<string>
+
+	# @produces text/event-stream
+	f3(): stream
+
+	# @produces text/csv
+	f4(): string
+
+	f5(s: stream)
+}
+`
+	_, err = parser.Parse(broken)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"4:15: error: Type [Unknown] cannot be found [unknown-type]",
+		"7:2: error: Function [Api.f2] returning stream<T> should produce text/event-stream, " +
+			"found [application/octet-stream], return stream of bytes instead [stream]",
+		"10:2: error: Function [Api.f3] returning stream of bytes cannot produce [text/event-stream] [stream]",
+		"13:2: error: Function [Api.f4] with @produces should return stream [stream]",
+		"15:8: error: Type [stream] cannot be found [unknown-type]",
+	}, lines)
+
+	_, err = parser.Parse("group g\ninterface Api {\n\tf(): stream<int, int>\n}\n")
+	assert.EqualError(t, err, "3:7: error: invalid stream type, expected stream or stream<T> [syntax]")
+}
+
+func TestCheckGeneric(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct Page<T> {
+	items: [T]
+	total: int
+}
+
+struct Pair<K, V> {
+	key: K
+	value: V?
+}
+
+struct User {
+	name: string
+}
+
+struct UserPage extends Page<User> {
+	next: Pair<string, Page<User>>?
+}
+
+interface I {
+	# @route GET /users
+	listUsers(page: int?): Page<User>
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"K", "V"}, schema.Groups[0].StructTypes[1].TypeParams)
+	ret := schema.Groups[0].Ifaces[0].Funs[0].Type
+	assert.Equal(t, "Page", ret.Name)
+	assert.Equal(t, "User", ret.TypeArgs[0].Name)
+
+	instances, err := schema.Instances()
+	assert.NoError(t, err)
+	var names []string
+	for _, inst := range instances {
+		names = append(names, inst.Struct.Name)
+	}
+	assert.Equal(t, []string{"Page_User", "Pair_String_Page_User"}, names)
+	assert.Equal(t, "User", instances[0].Struct.Fields[0].Type.ItemType.Name)
+	assert.Equal(t, true, instances[1].Struct.Fields[1].Type.Nullable)
+
+	t2 := `group t2
+
+struct Page<T, T> {
+	items: [T<int>]
+}
+
+struct Box<int> {
+	value: int
+}
+
+struct User {
+	name: string
+}
+
+struct A {
+	a: Page
+	b: Page<User, User, User>
+	c: User<int>
+}
+
+struct B<T> extends T {
+	b: int
+}
+
+struct Loop<T> {
+	next: Loop<[T]>?
+}
+
+struct C {
+	loop: Loop<int>
+}
+`
+	_, err = parser.Parse(t2)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"3:1: error: Struct [Page] has duplicated type param [T] [duplicated]",
+		"4:10: error: Type param [T] cannot have type arguments [generic]",
+		"7:1: error: Struct [Box] type param [int] conflicts with type [int] [generic]",
+		"16:5: error: Type [Page] expects 2 type argument(s), found 0 [generic]",
+		"17:5: error: Type [Page] expects 2 type argument(s), found 3 [generic]",
+		"18:5: error: Type [User] is not generic [generic]",
+		"21:21: error: Struct [B] can only extend structs, found [T] [extends]",
+		"26:8: error: Struct [Loop] is instantiated recursively [generic]",
+	}, lines)
+}
+
+func TestCheckGenericGroups(t *testing.T) {
+	parser := Parser{}
+
+	common := `group common
+
+struct Page<T> {
+	items: [T]
+	total: int
+}
+`
+	a := `group a
+
+struct User {
+	name: string
+}
+
+interface A {
+	# @route GET /a
+	list(): common.Page<User>
+	# @route GET /a/pages
+	pages(): common.Page<common.Page<User>>
+}
+`
+	b := `group b
+
+struct User {
+	id: int
+}
+
+interface B {
+	# @route GET /b
+	list(): common.Page<User>
+	# @route GET /b/ints
+	ints(): common.Page<int>
+}
+`
+	schema, err := parser.Parse(common, a, b)
+	assert.NoError(t, err)
+	instances, err := schema.Instances()
+	assert.NoError(t, err)
+	var names []string
+	for _, inst := range instances {
+		names = append(names, inst.Group+"."+inst.Struct.Name)
+	}
+	// instances belong to groups of type args, and are named apart
+	assert.Equal(t, []string{
+		"a.Page_AUser",
+		"b.Page_BUser",
+		"common.Page_Int",
+		"a.Page_Page_User",
+	}, names)
+	ret := schema.Groups[2].Ifaces[0].Funs[0].Type
+	assert.Equal(t, "Page_BUser", instances.Find(ret).Struct.Name)
+	assert.Equal(t, "b.User", instances.Find(ret).Struct.Fields[0].Type.ItemType.QualName())
+
+	dup := `group dup
+
+struct Page<T> {
+	items: [T]
+}
+
+struct Page_User {
+	next: string
+}
+
+struct User {
+	name: string
+}
+
+struct UserPage {
+	page: Page<User>
+}
+`
+	_, err = parser.Parse(dup)
+	assert.EqualError(t, err, "16:8: error: Struct instance [dup.Page<dup.User>] is named [Page_User], "+
+		"which is defined as [dup.Page_User] [generic]")
+}
+
+func TestCheckImport(t *testing.T) {
+	parser := Parser{}
+
+	common := `group common
+
+enum Status {
+	Active
+	Disabled
+}
+`
+	user := `group user
+
+import "common/types.api"
+
+enum Status {
+	Online
+	Offline
+}
+
+struct User {
+	status: Status
+	accountStatus: common.Status
+}
+`
+	order := `group order
+
+# relative to the importing file
+import "../common/types.api"
+
+struct Order {
+	status: Status
+}
+`
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		return file
+	}
+	f1 := write("common/types.api", common)
+	f2 := write("user/user.api", user)
+	f3 := write("order/order.api", order)
+
+	schema, err := parser.ParseFiles(f1, f2, f3)
+	assert.NoError(t, err)
+	assert.Equal(t, "common", schema.Groups[1].Imports[0].Group)
+	fields := schema.Groups[1].StructTypes[0].Fields
+	assert.Equal(t, "user.Status", fields[0].Type.QualName())
+	assert.Equal(t, "common.Status", fields[1].Type.QualName())
+	assert.Equal(t, "common.Status", schema.Groups[2].StructTypes[0].Fields[0].Type.QualName())
+
+	// imported files are loaded automatically
+	schema, err = parser.ParseFiles(f3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(schema.Groups))
+
+	legacy := `group legacy
+
+struct Legacy {
+	status: Status
+	order: order.Order
+}
+`
+	broken := `group broken
+
+import "missing.api"
+import "order/order.api"
+
+struct Broken {
+	order: order.Order
+	user: user.User
+	unknown: unknown.Unknown
+	status: order.Status
+}
+`
+	f4 := write("legacy.api", legacy)
+	f5 := write("broken.api", broken)
+	_, err = parser.ParseFiles(f1, f2, f3, f4, f5)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, strings.TrimPrefix(d.Error(), dir+"/"))
+	}
+	assert.Equal(t, []string{
+		"broken.api:3:1: error: Import [missing.api] cannot be resolved [import]",
+		"broken.api:8:8: error: Group [user] is not imported [import]",
+		"broken.api:9:11: error: Group [unknown] cannot be found [unknown-type]",
+		"broken.api:10:10: error: Type [order.Status] cannot be found [unknown-type]",
+		"legacy.api:4:10: error: Type [Status] is ambiguous, found in groups [common, user] [ambiguous]",
+	}, lines)
+}
+
+func TestCheckDefault(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+enum Role {
+	Admin = "admin"
+	Normal = "normal"
+}
+
+struct User {
+	name: string = "anonymous"
+	age: int = 18
+	score: float = 1
+	active: boolean = false
+	role: Role = Normal
+	tags: [string] = ["a", "b",]
+	nickname: string? = null
+}
+
+interface I {
+	f1(page: int = 1, roles: [Role] = [Admin], q: string? = null)
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	fields := schema.Groups[0].StructTypes[0].Fields
+	assert.Equal(t, &Literal{HasPos: HasPos{Pos{Line: 9, Column: 17}},
+		Kind: LiteralString, Text: "anonymous"}, fields[0].Default)
+	assert.Equal(t, LiteralNull, fields[6].Default.Kind)
+	assert.Equal(t, "normal", schema.LiteralValue(fields[4].Default, fields[4].Type))
+	assert.Equal(t, float64(1), schema.LiteralValue(fields[2].Default, fields[2].Type))
+	param := schema.Groups[0].Ifaces[0].Funs[0].Params[1]
+	assert.Equal(t, []interface{}{"admin"}, schema.LiteralValue(param.Default, param.Type))
+
+	t2 := `group t2
+
+enum Role {
+	Admin
+}
+
+struct S {
+	id: int
+}
+
+struct Box<T> {
+	value: T = 1
+}
+
+struct User {
+	name: string = 1
+	age: int = 1.5
+	role: Role = Unknown
+	tags: [string] = "a"
+	ids: [int] = [1, "2"]
+	s: S = null
+	m: {string: int} = null
+	n: int = null
+}
+
+interface I {
+	f1(p1: Role = "Admin", p2: object = 1)
+}
+`
+	_, err = parser.Parse(t2)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"12:13: error: Default value is not supported for type [T] [default]",
+		"16:17: error: Default value [1] does not match type [string] [default]",
+		"17:13: error: Default value [1.5] does not match type [int] [default]",
+		"18:15: error: Enum [Role] has no option [Unknown] [default]",
+		"19:19: error: Default value [\"a\"] does not match type [[string]] [default]",
+		"20:19: error: Default value [\"2\"] does not match type [int] [default]",
+		"21:9: error: Default value [null] does not match non-nullable type [t2.S] [default]",
+		"22:21: error: Default value [null] does not match non-nullable type [{string: int}] [default]",
+		"23:11: error: Default value [null] does not match non-nullable type [int] [default]",
+		"27:16: error: Default value [\"Admin\"] does not match type [t2.Role] [default]",
+		"27:38: error: Default value is not supported for type [object] [default]",
+	}, lines)
+}
+
+func TestCheckConstraints(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+# @openapi.type integer
+scalar Timestamp
+
+struct User {
+	# @minLength 1
+	# @maxLength 32
+	# @pattern ^[a-z]+$
+	name: string = "anonymous"
+	# @minimum 0
+	# @maximum 150
+	age: int?
+	# @minimum:json 0.5
+	score: float
+	# @maxItems 3
+	tags: [string] = ["a"]
+	# @minimum 0
+	createdAt: Timestamp
+}
+
+interface I {
+	f1(
+		# @minimum 1
+		page: int = 1
+	)
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+	fields := schema.Groups[0].StructTypes[0].Fields
+	cons, err := GetConstraints(fields[0].SemComments)
+	assert.NoError(t, err)
+	one, max := 1, 32
+	assert.Equal(t, &Constraints{MinLength: &one, MaxLength: &max, Pattern: "^[a-z]+$"}, cons)
+	cons, err = GetConstraints(fields[2].SemComments)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, *cons.Minimum)
+	cons, err = GetConstraints(schema.Groups[0].Ifaces[0].Funs[0].SemComments)
+	assert.NoError(t, err)
+	assert.Nil(t, cons)
+
+	t2 := `group t2
+
+enum Role {
+	Admin
+}
+
+struct User {
+	# @minimum abc
+	a: int
+	# @minLength -1
+	b: string
+	# @pattern (
+	c: string
+	# @minimum 10
+	# @maximum 1
+	d: int
+	# @maxLength 10
+	e: int
+	# @minItems 1
+	f: {string: int}
+	# @minimum 0.5
+	g: int
+	# @minimum 1
+	h: Role
+	# @maxLength 3
+	i: string = "abcd"
+	# @minItems 1
+	j: [int] = []
+	# @minimum 1
+	# @minimum 2
+	k: int
+}
+
+interface I {
+	f1(
+		# @minimum 1
+		page: int = 0
+	)
+}
+`
+	_, err = parser.Parse(t2)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"9:2: error: Invalid constraint [@minimum abc], a number is expected [constraint]",
+		"11:2: error: Invalid constraint [@minLength -1], a non-negative integer is expected [constraint]",
+		"13:2: error: Invalid constraint [@pattern (], error parsing regexp: missing closing ): `(` [constraint]",
+		"16:2: error: Constraint [@minimum 10] is greater than [@maximum 1] [constraint]",
+		"18:2: error: Constraint [@maxLength] is not supported for type [int] [constraint]",
+		"20:2: error: Constraint [@minItems] is not supported for type [{string: int}] [constraint]",
+		"22:2: error: Constraint [@minimum 0.5] of type [int] must be an integer [constraint]",
+		"24:2: error: Constraint [@minimum] is not supported for type [t2.Role] [constraint]",
+		"26:14: error: Default value [\"abcd\"] violates constraint [@maxLength 3] [constraint]",
+		"28:13: error: Default value [[]] violates constraint [@minItems 1] [constraint]",
+		"31:2: error: Constraint [@minimum] is declared more than once [constraint]",
+		"37:15: error: Default value [0] violates constraint [@minimum 1] [constraint]",
+	}, lines)
+}
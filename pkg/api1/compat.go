@@ -0,0 +1,636 @@
+package api1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change is a difference between two versions of schema, it is breaking if
+// clients or servers built with the old version may fail with the new one.
+type Change struct {
+	Pos      Pos    `json:"pos"`
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+// String renders the change like a diagnostic, e.g.
+// `user.api:12:3: breaking: Field [name] of struct [user.User] is removed`
+func (c Change) String() string {
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	if !c.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", kind, c.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", c.Pos, kind, c.Message)
+}
+
+type Changes []Change
+
+// Breaking returns the breaking changes
+func (cs Changes) Breaking() Changes {
+	var breaking Changes
+	for _, c := range cs {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+// typeUsage tells whether a type is used in requests (as param) and/or in
+// responses (as returned value or error payload), which decides whether a
+// change of nullability is breaking
+type typeUsage struct {
+	input  bool
+	output bool
+}
+
+// CompareSchemas returns changes from old to new, both of which should have
+// been checked with routes supplied (as `api1.json` is).
+//
+// Removing or changing anything clients depend on is breaking, e.g. types,
+// fields, enum options, functions & routes. A field becoming non-null is
+// breaking if the struct is used in requests, and becoming nullable is
+// breaking if it is used in responses. Additions are compatible unless they
+// are required in requests.
+func CompareSchemas(old, new *Schema) Changes {
+	c := &comparer{
+		oldDecls: declsOf(old),
+		newDecls: declsOf(new),
+		usages:   make(map[string]*typeUsage),
+	}
+	c.collectUsages(old, c.oldDecls)
+	c.collectUsages(new, c.newDecls)
+
+	newGroups := make(map[string]*ApiGroup)
+	for i := range new.Groups {
+		newGroups[new.Groups[i].Name] = &new.Groups[i]
+	}
+	oldGroups := make(map[string]bool)
+	for i := range old.Groups {
+		g := &old.Groups[i]
+		oldGroups[g.Name] = true
+		if ng, ok := newGroups[g.Name]; ok {
+			c.compareGroups(g, ng)
+		} else {
+			c.add(g.Pos, true, "Group [%s] is removed", g.Name)
+		}
+	}
+	for i := range new.Groups {
+		if g := &new.Groups[i]; !oldGroups[g.Name] {
+			c.add(g.Pos, false, "Group [%s] is added", g.Name)
+		}
+	}
+	return c.changes
+}
+
+// NormalizeBaseline fills post-processed fields which are missing from
+// `api1.json` written by older versions, so that it can be compared with
+// CompareSchemas: unqualified references are qualified by the group declaring
+// the type (the referencing group first), and the empty response style is
+// ResponseJSON.
+func (schema *Schema) NormalizeBaseline() {
+	owners := make(map[string][]string)
+	for key := range declsOf(schema) {
+		i := strings.Index(key, ".")
+		owners[key[i+1:]] = append(owners[key[i+1:]], key[:i])
+	}
+	for i := range schema.Groups {
+		g := &schema.Groups[i]
+		qualify := func(t *TypeRef, params []string) {
+			qualifyTypeRef(t, g.Name, owners, params)
+		}
+		for _, st := range g.StructTypes {
+			for j := range st.Extends {
+				qualify(&st.Extends[j], nil)
+			}
+			for _, f := range st.Fields {
+				qualify(f.Type, st.TypeParams)
+			}
+		}
+		for _, ut := range g.UnionTypes {
+			for j := range ut.Types {
+				qualify(&ut.Types[j], nil)
+			}
+		}
+		for _, er := range g.ErrorTypes {
+			qualify(er.Payload, nil)
+		}
+		for _, iface := range g.Ifaces {
+			for j := range iface.Funs {
+				fun := &iface.Funs[j]
+				for _, param := range fun.Params {
+					qualify(param.Type, nil)
+				}
+				qualify(fun.Type, nil)
+				if fun.Stream != nil {
+					qualify(fun.Stream.Item, nil)
+				}
+				for k := range fun.Throws {
+					qualify(&fun.Throws[k], nil)
+				}
+				if fun.Route != nil && fun.Route.Response == "" {
+					fun.Route.Response = ResponseJSON
+				}
+			}
+		}
+	}
+}
+
+func qualifyTypeRef(t *TypeRef, group string, owners map[string][]string, params []string) {
+	if t == nil {
+		return
+	}
+	qualifyTypeRef(t.KeyType, group, owners, params)
+	qualifyTypeRef(t.ItemType, group, owners, params)
+	for i := range t.TypeArgs {
+		qualifyTypeRef(&t.TypeArgs[i], group, owners, params)
+	}
+	if t.Group != "" || t.KeyType != nil || t.ItemType != nil {
+		return
+	}
+	for _, param := range params {
+		if param == t.Name {
+			return
+		}
+	}
+	groups := owners[t.Name]
+	for _, owner := range groups {
+		if owner == group {
+			t.Group = group
+			return
+		}
+	}
+	if len(groups) > 0 {
+		sort.Strings(groups)
+		t.Group = groups[0]
+	}
+}
+
+type comparer struct {
+	oldDecls map[string]interface{}
+	newDecls map[string]interface{}
+	usages   map[string]*typeUsage
+	changes  Changes
+}
+
+func (c *comparer) add(pos Pos, breaking bool, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{
+		Pos:      pos,
+		Breaking: breaking,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// declsOf returns declarations of schema keyed by qualified names
+func declsOf(schema *Schema) map[string]interface{} {
+	decls := make(map[string]interface{})
+	for i := range schema.Groups {
+		g := &schema.Groups[i]
+		for j := range g.ScalarTypes {
+			decls[g.Name+"."+g.ScalarTypes[j].Name] = &g.ScalarTypes[j]
+		}
+		for j := range g.EnumTypes {
+			decls[g.Name+"."+g.EnumTypes[j].Name] = &g.EnumTypes[j]
+		}
+		for j := range g.StructTypes {
+			decls[g.Name+"."+g.StructTypes[j].Name] = &g.StructTypes[j]
+		}
+		for j := range g.UnionTypes {
+			decls[g.Name+"."+g.UnionTypes[j].Name] = &g.UnionTypes[j]
+		}
+		for j := range g.ErrorTypes {
+			decls[g.Name+"."+g.ErrorTypes[j].Name] = &g.ErrorTypes[j]
+		}
+	}
+	return decls
+}
+
+func declKind(decl interface{}) string {
+	switch decl.(type) {
+	case *ScalarType:
+		return "scalar"
+	case *EnumType:
+		return "enum"
+	case *StructType:
+		return "struct"
+	case *UnionType:
+		return "union"
+	case *ErrorType:
+		return "error"
+	}
+	return ""
+}
+
+// collectUsages marks types reachable from params as input, and types
+// reachable from returned values & errors as output
+func (c *comparer) collectUsages(schema *Schema, decls map[string]interface{}) {
+	for _, g := range schema.Groups {
+		for _, iface := range g.Ifaces {
+			for _, fun := range iface.Funs {
+				for _, param := range fun.Params {
+					c.markType(param.Type, decls, true)
+				}
+				c.markType(fun.Type, decls, false)
+				if fun.Stream != nil {
+					c.markType(fun.Stream.Item, decls, false)
+				}
+				for i := range fun.Throws {
+					c.markType(&fun.Throws[i], decls, false)
+				}
+			}
+		}
+	}
+}
+
+func (c *comparer) markType(t *TypeRef, decls map[string]interface{}, input bool) {
+	if t == nil {
+		return
+	}
+	c.markType(t.KeyType, decls, input)
+	c.markType(t.ItemType, decls, input)
+	for i := range t.TypeArgs {
+		c.markType(&t.TypeArgs[i], decls, input)
+	}
+	if t.Group == "" {
+		return
+	}
+	key := t.Group + "." + t.Name
+	usage := c.usages[key]
+	if usage == nil {
+		usage = &typeUsage{}
+		c.usages[key] = usage
+	}
+	if (input && usage.input) || (!input && usage.output) {
+		return
+	}
+	if input {
+		usage.input = true
+	} else {
+		usage.output = true
+	}
+	switch decl := decls[key].(type) {
+	case *StructType:
+		for i := range decl.Extends {
+			c.markType(&decl.Extends[i], decls, input)
+		}
+		for _, f := range decl.Fields {
+			c.markType(f.Type, decls, input)
+		}
+	case *UnionType:
+		for i := range decl.Types {
+			c.markType(&decl.Types[i], decls, input)
+		}
+	case *ErrorType:
+		c.markType(decl.Payload, decls, input)
+	}
+}
+
+func (c *comparer) usage(group, name string) typeUsage {
+	if usage := c.usages[group+"."+name]; usage != nil {
+		return *usage
+	}
+	return typeUsage{}
+}
+
+func (c *comparer) compareGroups(old, new *ApiGroup) {
+	var oldNames, newNames []string
+	for key := range c.oldDecls {
+		if strings.HasPrefix(key, old.Name+".") {
+			oldNames = append(oldNames, key)
+		}
+	}
+	for key := range c.newDecls {
+		if strings.HasPrefix(key, new.Name+".") {
+			newNames = append(newNames, key)
+		}
+	}
+	sort.Strings(oldNames)
+	sort.Strings(newNames)
+	for _, key := range oldNames {
+		oldDecl := c.oldDecls[key]
+		newDecl, ok := c.newDecls[key]
+		if !ok {
+			c.add(declPos(oldDecl), true, "Type [%s] is removed", key)
+			continue
+		}
+		if declKind(oldDecl) != declKind(newDecl) {
+			c.add(declPos(newDecl), true, "Type [%s] changes from %s to %s",
+				key, declKind(oldDecl), declKind(newDecl))
+			continue
+		}
+		switch oldDecl := oldDecl.(type) {
+		case *EnumType:
+			c.compareEnums(key, oldDecl, newDecl.(*EnumType))
+		case *StructType:
+			c.compareStructs(key, oldDecl, newDecl.(*StructType), c.usage(old.Name, oldDecl.Name))
+		case *UnionType:
+			c.compareUnions(key, oldDecl, newDecl.(*UnionType))
+		case *ErrorType:
+			c.compareErrors(key, oldDecl, newDecl.(*ErrorType))
+		}
+	}
+	for _, key := range newNames {
+		if _, ok := c.oldDecls[key]; !ok {
+			c.add(declPos(c.newDecls[key]), false, "Type [%s] is added", key)
+		}
+	}
+	c.compareIfaces(old, new)
+}
+
+func declPos(decl interface{}) Pos {
+	switch decl := decl.(type) {
+	case *ScalarType:
+		return decl.Pos
+	case *EnumType:
+		return decl.Pos
+	case *StructType:
+		return decl.Pos
+	case *UnionType:
+		return decl.Pos
+	case *ErrorType:
+		return decl.Pos
+	}
+	return Pos{}
+}
+
+func (c *comparer) compareEnums(name string, old, new *EnumType) {
+	newOptions := make(map[string]*EnumOption)
+	for i := range new.Options {
+		newOptions[new.Options[i].Name] = &new.Options[i]
+	}
+	oldOptions := make(map[string]bool)
+	for _, o := range old.Options {
+		oldOptions[o.Name] = true
+		n, ok := newOptions[o.Name]
+		if !ok {
+			c.add(new.Pos, true, "Option [%s] of enum [%s] is removed", o.Name, name)
+		} else if enumValue(o.Value) != enumValue(n.Value) {
+			c.add(n.Pos, true, "Value of option [%s] of enum [%s] changes from [%s] to [%s]",
+				o.Name, name, enumValue(o.Value), enumValue(n.Value))
+		}
+	}
+	for _, o := range new.Options {
+		if !oldOptions[o.Name] {
+			c.add(o.Pos, false, "Option [%s] of enum [%s] is added", o.Name, name)
+		}
+	}
+}
+
+func enumValue(v *IntOrString) string {
+	if v == nil {
+		return ""
+	} else if v.IntVal != nil {
+		return fmt.Sprint(*v.IntVal)
+	} else if v.StrVal != nil {
+		return fmt.Sprintf("%q", *v.StrVal)
+	}
+	return ""
+}
+
+func (c *comparer) compareStructs(name string, old, new *StructType, usage typeUsage) {
+	if typeList(old.Extends) != typeList(new.Extends) {
+		c.add(new.Pos, true, "Parent structs of [%s] change from [%s] to [%s]",
+			name, typeList(old.Extends), typeList(new.Extends))
+	}
+	if strings.Join(old.TypeParams, ", ") != strings.Join(new.TypeParams, ", ") {
+		c.add(new.Pos, true, "Type params of struct [%s] change from [%s] to [%s]",
+			name, strings.Join(old.TypeParams, ", "), strings.Join(new.TypeParams, ", "))
+	}
+	newFields := make(map[string]*StructField)
+	for i := range new.Fields {
+		newFields[new.Fields[i].Name] = &new.Fields[i]
+	}
+	oldFields := make(map[string]bool)
+	for _, f := range old.Fields {
+		oldFields[f.Name] = true
+		n, ok := newFields[f.Name]
+		if !ok {
+			c.add(new.Pos, true, "Field [%s] of struct [%s] is removed", f.Name, name)
+			continue
+		}
+		what := fmt.Sprintf("field [%s] of struct [%s]", f.Name, name)
+		c.compareValueTypes(n.Pos, what, f.Type, n.Type, f.Default != nil, n.Default != nil, usage)
+	}
+	for _, f := range new.Fields {
+		if oldFields[f.Name] {
+			continue
+		}
+		required := !f.Type.Nullable && f.Default == nil
+		if required && usage.input {
+			c.add(f.Pos, true, "Required field [%s] is added to struct [%s] used in requests", f.Name, name)
+		} else {
+			c.add(f.Pos, false, "Field [%s] is added to struct [%s]", f.Name, name)
+		}
+	}
+}
+
+// compareValueTypes compares types of a field or param, what is the
+// description of it, hasDefault tells whether it's optional in requests
+func (c *comparer) compareValueTypes(pos Pos, what string, old, new *TypeRef,
+	oldHasDefault, newHasDefault bool, usage typeUsage) {
+	if typeString(old, false) != typeString(new, false) {
+		c.add(pos, true, "Type of %s changes from [%s] to [%s]", what, old, new)
+		return
+	}
+	if old.Nullable && !new.Nullable && usage.input {
+		c.add(pos, true, "Type of %s changes from nullable [%s] to non-null [%s]", what, old, new)
+	} else if !old.Nullable && new.Nullable && usage.output {
+		c.add(pos, true, "Type of %s changes from non-null [%s] to nullable [%s]", what, old, new)
+	} else if old.Nullable != new.Nullable {
+		c.add(pos, false, "Type of %s changes from [%s] to [%s]", what, old, new)
+	} else if oldHasDefault && !newHasDefault && !new.Nullable && usage.input {
+		c.add(pos, true, "Default value of %s is removed, which becomes required", what)
+	}
+}
+
+// typeString renders t, including the nullability of itself if nullable is
+// true, nullability of items are always included
+func typeString(t *TypeRef, nullable bool) string {
+	if t == nil {
+		return ""
+	}
+	s := *t
+	s.Nullable = s.Nullable && nullable
+	return s.String()
+}
+
+func typeList(types []TypeRef) string {
+	var names []string
+	for i := range types {
+		names = append(names, types[i].String())
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *comparer) compareUnions(name string, old, new *UnionType) {
+	newTypes := make(map[string]bool)
+	for i := range new.Types {
+		newTypes[new.Types[i].String()] = true
+	}
+	oldTypes := make(map[string]bool)
+	for i := range old.Types {
+		t := old.Types[i].String()
+		oldTypes[t] = true
+		if !newTypes[t] {
+			c.add(new.Pos, true, "Type [%s] of union [%s] is removed", t, name)
+		}
+	}
+	for i := range new.Types {
+		if t := new.Types[i].String(); !oldTypes[t] {
+			c.add(new.Types[i].Pos, false, "Type [%s] is added to union [%s]", t, name)
+		}
+	}
+}
+
+func (c *comparer) compareErrors(name string, old, new *ErrorType) {
+	if old.Status != new.Status {
+		c.add(new.Pos, true, "Status of error [%s] changes from [%d] to [%d]", name, old.Status, new.Status)
+	}
+	if typeString(old.Payload, true) != typeString(new.Payload, true) {
+		c.add(new.Pos, true, "Payload of error [%s] changes from [%s] to [%s]",
+			name, typeString(old.Payload, true), typeString(new.Payload, true))
+	}
+}
+
+func (c *comparer) compareIfaces(old, new *ApiGroup) {
+	newFuns := make(map[string]*Fun)
+	var newKeys []string
+	for i := range new.Ifaces {
+		for j := range new.Ifaces[i].Funs {
+			key := new.Name + "." + new.Ifaces[i].Name + "." + new.Ifaces[i].Funs[j].Name
+			newFuns[key] = &new.Ifaces[i].Funs[j]
+			newKeys = append(newKeys, key)
+		}
+	}
+	oldFuns := make(map[string]bool)
+	for i := range old.Ifaces {
+		for j := range old.Ifaces[i].Funs {
+			fun := &old.Ifaces[i].Funs[j]
+			key := old.Name + "." + old.Ifaces[i].Name + "." + fun.Name
+			oldFuns[key] = true
+			if n, ok := newFuns[key]; ok {
+				c.compareFuns(key, fun, n)
+			} else {
+				c.add(old.Ifaces[i].Pos, true, "Function [%s]%s is removed", key, routeDesc(fun.Route))
+			}
+		}
+	}
+	for _, key := range newKeys {
+		if !oldFuns[key] {
+			c.add(newFuns[key].Pos, false, "Function [%s]%s is added", key, routeDesc(newFuns[key].Route))
+		}
+	}
+}
+
+func routeDesc(r *RouteInfo) string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", strings.ToUpper(r.Method), r.Path)
+}
+
+func (c *comparer) compareFuns(name string, old, new *Fun) {
+	c.compareRoutes(name, new.Pos, old.Route, new.Route)
+
+	newParams := make(map[string]*Param)
+	for i := range new.Params {
+		newParams[new.Params[i].Name] = &new.Params[i]
+	}
+	oldParams := make(map[string]bool)
+	input := typeUsage{input: true}
+	for _, p := range old.Params {
+		oldParams[p.Name] = true
+		n, ok := newParams[p.Name]
+		if !ok {
+			c.add(new.Pos, true, "Param [%s] of function [%s] is removed", p.Name, name)
+			continue
+		}
+		what := fmt.Sprintf("param [%s] of function [%s]", p.Name, name)
+		c.compareValueTypes(n.Pos, what, p.Type, n.Type, p.Default != nil, n.Default != nil, input)
+		if old.Route != nil && new.Route != nil {
+			if o, n := old.Route.ParamsIn[p.Name], new.Route.ParamsIn[p.Name]; o != n {
+				c.add(new.Pos, true, "Param [%s] of function [%s] moves from %s to %s", p.Name, name, o, n)
+			}
+			if o, n := old.Route.WireName(p.Name), new.Route.WireName(p.Name); o != n {
+				c.add(new.Pos, true, "Param [%s] of function [%s] is renamed from [%s] to [%s] in requests",
+					p.Name, name, o, n)
+			}
+		}
+	}
+	for _, p := range new.Params {
+		if oldParams[p.Name] {
+			continue
+		}
+		if !p.Type.Nullable && p.Default == nil {
+			c.add(p.Pos, true, "Required param [%s] is added to function [%s]", p.Name, name)
+		} else {
+			c.add(p.Pos, false, "Optional param [%s] is added to function [%s]", p.Name, name)
+		}
+	}
+
+	if old.Type == nil || new.Type == nil {
+		if streamString(old) != streamString(new) {
+			c.add(new.Pos, true, "Return type of function [%s] changes from [%s] to [%s]",
+				name, streamString(old), streamString(new))
+		}
+		return
+	}
+	output := typeUsage{output: true}
+	c.compareValueTypes(new.Pos, fmt.Sprintf("returned value of function [%s]", name),
+		old.Type, new.Type, false, false, output)
+}
+
+// streamString renders the returned type of fun, including the content type
+// of stream
+func streamString(fun *Fun) string {
+	if fun.Stream == nil {
+		if fun.Type == nil {
+			return "nothing"
+		}
+		return typeString(fun.Type, true)
+	}
+	s := "stream"
+	if fun.Stream.Item != nil {
+		s += "<" + fun.Stream.Item.String() + ">"
+	}
+	if fun.Stream.ContentType != "" {
+		s += " of " + fun.Stream.ContentType
+	}
+	return s
+}
+
+func (c *comparer) compareRoutes(name string, pos Pos, old, new *RouteInfo) {
+	if old == nil {
+		if new != nil {
+			c.add(pos, false, "Route%s of function [%s] is added", routeDesc(new), name)
+		}
+		return
+	}
+	if new == nil {
+		c.add(pos, true, "Route%s of function [%s] is removed", routeDesc(old), name)
+		return
+	}
+	if old.Method != new.Method || old.Path != new.Path {
+		c.add(pos, true, "Route of function [%s] changes from [%s %s] to [%s %s]",
+			name, strings.ToUpper(old.Method), old.Path, strings.ToUpper(new.Method), new.Path)
+	}
+	if old.Response != new.Response {
+		c.add(pos, true, "Response style of function [%s] changes from [%s] to [%s]",
+			name, old.Response, new.Response)
+	}
+	if old.FormType != new.FormType {
+		c.add(pos, true, "Body content type of function [%s] changes from [%s] to [%s]",
+			name, formType(old), formType(new))
+	}
+}
+
+func formType(r *RouteInfo) string {
+	if r.FormType == "" {
+		return "application/json"
+	}
+	return r.FormType
+}
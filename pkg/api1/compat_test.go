@@ -0,0 +1,153 @@
+package api1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSchemas(t *testing.T) {
+	parse := func(content string) *Schema {
+		parser := Parser{}
+		schema, err := parser.Parse(content)
+		assert.NoError(t, err)
+		assert.NoError(t, schema.SupplyRouteInfo())
+		return schema
+	}
+	old := parse(`
+group user
+
+enum Role {
+  Admin
+  Guest
+  Owner
+}
+
+struct User {
+  id: int
+  name: string
+  email: string?
+  role: Role
+  tags: [string]
+}
+
+struct UserQuery {
+  name: string?
+  page: int = 1
+}
+
+interface UserApi {
+  # @route GET /users/:id
+  getUser(id: int): User
+
+  # @route GET /users
+  listUsers(q: UserQuery, role: Role?): [User]
+
+  # @route DELETE /users/:id
+  deleteUser(id: int)
+
+  # @route POST /users/:id/avatar
+  setAvatar(id: int, avatar: string)
+}
+`)
+	new := parse(`
+group user
+
+enum Role {
+  Admin
+  Owner
+  Member
+}
+
+struct User {
+  id: int
+  name: string?
+  email: string
+  role: Role
+  tags: [int]
+  age: int
+}
+
+struct UserQuery {
+  name: string
+  page: int
+  size: int?
+}
+
+interface UserApi {
+  # @route GET /users/:id
+  getUser(id: int): User?
+
+  # @route GET /users
+  listUsers(q: UserQuery, role: Role, extra: string): [User]
+
+  # @route PUT /users/:id/avatar
+  setAvatar(id: int, avatar: string)
+
+  # @route GET /roles
+  listRoles(): [Role]
+}
+`)
+
+	var messages []string
+	for _, c := range CompareSchemas(old, new) {
+		messages = append(messages, Change{Breaking: c.Breaking, Message: c.Message}.String())
+	}
+	assert.Equal(t, []string{
+		"breaking: Option [Guest] of enum [user.Role] is removed",
+		"compatible: Option [Member] of enum [user.Role] is added",
+		"breaking: Type of field [name] of struct [user.User] changes from non-null [string] to nullable [string?]",
+		"compatible: Type of field [email] of struct [user.User] changes from [string?] to [string]",
+		"breaking: Type of field [tags] of struct [user.User] changes from [[string]] to [[int]]",
+		"compatible: Field [age] is added to struct [user.User]",
+		"breaking: Type of field [name] of struct [user.UserQuery] changes from nullable [string?] to non-null [string]",
+		"breaking: Default value of field [page] of struct [user.UserQuery] is removed, which becomes required",
+		"compatible: Field [size] is added to struct [user.UserQuery]",
+		"breaking: Type of returned value of function [user.UserApi.getUser] changes from non-null [user.User] to nullable [user.User?]",
+		"breaking: Type of param [role] of function [user.UserApi.listUsers] changes from nullable [user.Role?] to non-null [user.Role]",
+		"breaking: Required param [extra] is added to function [user.UserApi.listUsers]",
+		"breaking: Function [user.UserApi.deleteUser] (DELETE /users/:id) is removed",
+		"breaking: Route of function [user.UserApi.setAvatar] changes from [POST /users/:id/avatar] to [PUT /users/:id/avatar]",
+		"compatible: Function [user.UserApi.listRoles] (GET /roles) is added",
+	}, messages)
+
+	// the old schema may be loaded from `api1.json`
+	var loaded Schema
+	assert.NoError(t, json.Unmarshal([]byte(toJson(t, old)), &loaded))
+	assert.Len(t, CompareSchemas(&loaded, new), len(messages))
+	assert.Empty(t, CompareSchemas(&loaded, old))
+
+	changes := CompareSchemas(old, new)
+	assert.Len(t, changes.Breaking(), 10)
+	assert.Equal(t, "4:1: breaking: Option [Guest] of enum [user.Role] is removed", changes[0].String())
+}
+
+func TestCompareBaseline(t *testing.T) {
+	// written by an older version from the same api files, without qualified
+	// references & response styles
+	content, err := ioutil.ReadFile("testdata/baseline_api1.json")
+	assert.NoError(t, err)
+	var old Schema
+	assert.NoError(t, json.Unmarshal(content, &old))
+
+	files, err := filepath.Glob("../../e2e/testData/api/*.api")
+	assert.NoError(t, err)
+	p := &Parser{}
+	new, err := p.ParseFiles(files...)
+	assert.NoError(t, err)
+	assert.NoError(t, new.SupplyRouteInfo())
+
+	assert.NotEmpty(t, CompareSchemas(&old, new).Breaking())
+	old.NormalizeBaseline()
+	assert.Empty(t, CompareSchemas(&old, new))
+	assert.Equal(t, "comment.CreateCommentRequest", old.Groups[0].Ifaces[0].Funs[0].Params[0].Type.String())
+}
+
+func toJson(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(b)
+}
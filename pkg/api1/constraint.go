@@ -0,0 +1,243 @@
+package api1
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Constraints are validation constraints of a struct field or param, declared
+// by semantic comments, e.g. `@minimum 1`, `@maxLength 32`, `@pattern ^\w+$`.
+type Constraints struct {
+	// integer|number
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	// string
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	// array
+	MinItems *int `json:"minItems,omitempty"`
+	MaxItems *int `json:"maxItems,omitempty"`
+}
+
+// constraint kinds, which are types the constraints apply to
+const (
+	kindInteger = "integer"
+	kindNumber  = "number"
+	kindString  = "string"
+	kindArray   = "array"
+)
+
+// ConstraintKeys are semantic comment keys of constraints
+var ConstraintKeys = []string{
+	"minimum", "maximum", "minLength", "maxLength", "pattern", "minItems", "maxItems",
+}
+
+var constraintKinds = map[string][]string{
+	"minimum":   {kindInteger, kindNumber},
+	"maximum":   {kindInteger, kindNumber},
+	"minLength": {kindString},
+	"maxLength": {kindString},
+	"pattern":   {kindString},
+	"minItems":  {kindArray},
+	"maxItems":  {kindArray},
+}
+
+// GetConstraints returns constraints declared in semantic comments, or nil if
+// there is none.
+func GetConstraints(semComments map[string]interface{}) (*Constraints, error) {
+	var c Constraints
+	var found bool
+	for _, key := range ConstraintKeys {
+		val, ok := semComments[key]
+		if !ok {
+			continue
+		}
+		found = true
+		if _, ok := val.([]interface{}); ok {
+			return nil, fmt.Errorf("Constraint [@%s] is declared more than once", key)
+		}
+		var err error
+		switch key {
+		case "minimum":
+			c.Minimum, err = constraintNumber(key, val)
+		case "maximum":
+			c.Maximum, err = constraintNumber(key, val)
+		case "minLength":
+			c.MinLength, err = constraintCount(key, val)
+		case "maxLength":
+			c.MaxLength, err = constraintCount(key, val)
+		case "minItems":
+			c.MinItems, err = constraintCount(key, val)
+		case "maxItems":
+			c.MaxItems, err = constraintCount(key, val)
+		case "pattern":
+			s, _ := val.(string)
+			if s == "" {
+				return nil, fmt.Errorf("Invalid constraint [@%s %v], a regular expression is expected", key, val)
+			}
+			if _, err := regexp.Compile(s); err != nil {
+				return nil, fmt.Errorf("Invalid constraint [@%s %s], %v", key, s, err)
+			}
+			c.Pattern = s
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	if c.Minimum != nil && c.Maximum != nil && *c.Minimum > *c.Maximum {
+		return nil, fmt.Errorf("Constraint [@minimum %v] is greater than [@maximum %v]", *c.Minimum, *c.Maximum)
+	}
+	if c.MinLength != nil && c.MaxLength != nil && *c.MinLength > *c.MaxLength {
+		return nil, fmt.Errorf("Constraint [@minLength %d] is greater than [@maxLength %d]", *c.MinLength, *c.MaxLength)
+	}
+	if c.MinItems != nil && c.MaxItems != nil && *c.MinItems > *c.MaxItems {
+		return nil, fmt.Errorf("Constraint [@minItems %d] is greater than [@maxItems %d]", *c.MinItems, *c.MaxItems)
+	}
+	return &c, nil
+}
+
+func constraintNumber(key string, val interface{}) (*float64, error) {
+	switch val := val.(type) {
+	case float64:
+		return &val, nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil &&
+			!math.IsInf(f, 0) && !math.IsNaN(f) {
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid constraint [@%s %v], a number is expected", key, val)
+}
+
+func constraintCount(key string, val interface{}) (*int, error) {
+	switch val := val.(type) {
+	case float64:
+		if val >= 0 && val == math.Trunc(val) {
+			n := int(val)
+			return &n, nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && n >= 0 {
+			return &n, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid constraint [@%s %v], a non-negative integer is expected", key, val)
+}
+
+// constraintKind returns the kind of type t which constraints apply to, or an
+// empty string if constraints are not supported for t.
+func constraintKind(t *TypeRef, params map[string]bool, types map[string]interface{}) string {
+	if t.Name == "" {
+		if t.KeyType == nil {
+			return kindArray
+		}
+		return ""
+	}
+	if t.Group == "" && params[t.Name] {
+		return ""
+	}
+	switch t.Name {
+	case "int":
+		return kindInteger
+	case "float":
+		return kindNumber
+	case "string":
+		return kindString
+	}
+	// scalar types are supported if openapi type is declared
+	if sc, ok := types[t.QualName()].(ScalarType); ok {
+		switch typ, _ := sc.SemComments["openapi.type"].(string); typ {
+		case kindInteger, kindNumber, kindString:
+			return typ
+		}
+	}
+	return ""
+}
+
+// checkConstraints checks constraints declared in c fit type t, and the
+// default value l satisfies the constraints.
+func checkConstraints(c *HasComments, pos Pos, t *TypeRef, l *Literal, params map[string]bool,
+	types map[string]interface{}, report reportFunc) {
+	cons, err := GetConstraints(c.SemComments)
+	if err != nil {
+		report(pos, CodeConstraint, "%v", err)
+		return
+	}
+	if cons == nil || t == nil {
+		return
+	}
+	kind := constraintKind(t, params, types)
+	for _, key := range ConstraintKeys {
+		if _, ok := c.SemComments[key]; !ok {
+			continue
+		}
+		var supported bool
+		for _, k := range constraintKinds[key] {
+			supported = supported || k == kind
+		}
+		if !supported {
+			report(pos, CodeConstraint, "Constraint [@%s] is not supported for type [%s]", key, t)
+			return
+		}
+	}
+	if kind == kindInteger {
+		if cons.Minimum != nil && *cons.Minimum != math.Trunc(*cons.Minimum) {
+			report(pos, CodeConstraint, "Constraint [@minimum %v] of type [%s] must be an integer", *cons.Minimum, t)
+		}
+		if cons.Maximum != nil && *cons.Maximum != math.Trunc(*cons.Maximum) {
+			report(pos, CodeConstraint, "Constraint [@maximum %v] of type [%s] must be an integer", *cons.Maximum, t)
+		}
+	}
+	if l != nil {
+		if violated := cons.violatedBy(l); violated != "" {
+			report(l.Pos, CodeConstraint, "Default value [%s] violates constraint [%s]", l, violated)
+		}
+	}
+}
+
+// violatedBy returns the constraint violated by literal l, or an empty string
+// if l satisfies all the constraints.
+func (c *Constraints) violatedBy(l *Literal) string {
+	switch l.Kind {
+	case LiteralInt, LiteralFloat:
+		val, err := strconv.ParseFloat(l.Text, 64)
+		if err != nil {
+			return ""
+		}
+		if c.Minimum != nil && val < *c.Minimum {
+			return fmt.Sprintf("@minimum %v", *c.Minimum)
+		}
+		if c.Maximum != nil && val > *c.Maximum {
+			return fmt.Sprintf("@maximum %v", *c.Maximum)
+		}
+	case LiteralString:
+		n := utf8.RuneCountInString(l.Text)
+		if c.MinLength != nil && n < *c.MinLength {
+			return fmt.Sprintf("@minLength %d", *c.MinLength)
+		}
+		if c.MaxLength != nil && n > *c.MaxLength {
+			return fmt.Sprintf("@maxLength %d", *c.MaxLength)
+		}
+		if c.Pattern != "" {
+			if re, err := regexp.Compile(c.Pattern); err == nil && !re.MatchString(l.Text) {
+				return fmt.Sprintf("@pattern %s", c.Pattern)
+			}
+		}
+	case LiteralArray:
+		if c.MinItems != nil && len(l.Items) < *c.MinItems {
+			return fmt.Sprintf("@minItems %d", *c.MinItems)
+		}
+		if c.MaxItems != nil && len(l.Items) > *c.MaxItems {
+			return fmt.Sprintf("@maxItems %d", *c.MaxItems)
+		}
+	}
+	return ""
+}
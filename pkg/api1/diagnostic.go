@@ -0,0 +1,149 @@
+package api1
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// diagnostic codes
+const (
+	CodeSyntax      = "syntax"
+	CodeSemComment  = "sem-comment"
+	CodeDuplicated  = "duplicated"
+	CodeUnknownType = "unknown-type"
+	CodeEnumValue   = "enum-value"
+	CodeMapKey      = "map-key"
+	CodeExtends     = "extends"
+	CodeUnion       = "union"
+	CodeGeneric     = "generic"
+	CodeImport      = "import"
+	CodeAmbiguous   = "ambiguous"
+	CodeDefault     = "default"
+	CodeRoute       = "route"
+	CodeConstraint  = "constraint"
+	CodeErrorType   = "error-type"
+	CodeStream      = "stream"
+)
+
+// Pos is the location of a node in `*.api` files, Line & Column start from 1.
+type Pos struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	s := p.File
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d", p.Line)
+		if p.Column > 0 {
+			s += fmt.Sprintf(":%d", p.Column)
+		}
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+type HasPos struct {
+	Pos Pos `json:"-"`
+}
+
+type Diagnostic struct {
+	Pos      Pos      `json:"pos"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+func newError(pos Pos, code string, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Pos:      pos,
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Error renders the diagnostic compiler-style, e.g.
+// `user.api:42:7: error: Type [Foo] cannot be found [unknown-type]`
+func (d Diagnostic) Error() string {
+	s := fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+	if d.Code != "" {
+		s += fmt.Sprintf(" [%s]", d.Code)
+	}
+	return s
+}
+
+// Diagnostics collects all problems found in `*.api` files,
+// it is returned as error by Parser & Schema.Check.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Add appends err to diagnostics, err could be Diagnostic, Diagnostics or
+// other errors (which has no position).
+func (ds *Diagnostics) Add(err error) {
+	if err == nil {
+		return
+	}
+	var d Diagnostic
+	var a Diagnostics
+	if errors.As(err, &a) {
+		*ds = append(*ds, a...)
+	} else if errors.As(err, &d) {
+		*ds = append(*ds, d)
+	} else {
+		*ds = append(*ds, Diagnostic{
+			Severity: SeverityError,
+			Message:  err.Error(),
+		})
+	}
+}
+
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		a, b := ds[i].Pos, ds[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// Err returns sorted diagnostics as error, or nil if there is no diagnostic.
+func (ds Diagnostics) Err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+	sorted := make(Diagnostics, len(ds))
+	copy(sorted, ds)
+	sorted.Sort()
+	return sorted
+}
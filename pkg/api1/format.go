@@ -0,0 +1,367 @@
+package api1
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatIndent = "  "
+	// params of functions longer than it are printed one per line
+	formatMaxWidth = 100
+	// semantic comments of json longer than it are printed in multiple lines
+	formatMaxSemWidth = 80
+)
+
+// Format formats content of a `*.api` file. Types are not checked, so a file
+// importing others can be formatted alone, syntax errors are returned as
+// Diagnostics.
+func Format(file string, content string) (string, error) {
+	p := &Parser{}
+	schema, err := p.parse(file, content)
+	if err != nil {
+		return "", err
+	}
+	return PrintGroup(&schema.Groups[0]), nil
+}
+
+// PrintGroup renders g as the canonical `*.api` text:
+//
+//   - members are indented by 2 spaces, and declarations are kept in the
+//     order they are parsed (or by kinds if positions are unknown)
+//   - declarations & functions are separated by a blank line, except adjacent
+//     single line declarations, fields & enum options keep a blank line
+//     between them if there is any
+//   - params are printed in a line, or one per line if any of them has
+//     comments, or the line is longer than 100 columns
+//   - comments are printed as `# comment`, semantic comments are kept as
+//     written, or rendered from SemComments if the group is not parsed
+//   - post comments are kept at the end of the line, before the close brace
+//     or paren, or at the end of the file as parsed
+func PrintGroup(g *ApiGroup) string {
+	p := &printer{}
+	p.comments(0, &g.HasComments)
+	head, dangling, tail := splitPostComments(&g.HasComments)
+	p.line(0, "group "+g.Name+joinComments(head))
+	if len(g.Imports) > 0 {
+		p.blank()
+	}
+	for i := range g.Imports {
+		imp := &g.Imports[i]
+		p.comments(0, &imp.HasComments)
+		p.line(0, "import "+strconv.Quote(imp.Path)+postComments(&imp.HasComments))
+	}
+
+	var decls []printerDecl
+	for i := range g.ScalarTypes {
+		sc := &g.ScalarTypes[i]
+		decls = append(decls, printerDecl{sc.Pos, &sc.HasComments, true, func() {
+			p.line(0, "scalar "+sc.Name+postComments(&sc.HasComments))
+		}})
+	}
+	for i := range g.EnumTypes {
+		en := &g.EnumTypes[i]
+		decls = append(decls, printerDecl{en.Pos, &en.HasComments, false, func() { p.enum(en) }})
+	}
+	for i := range g.StructTypes {
+		st := &g.StructTypes[i]
+		decls = append(decls, printerDecl{st.Pos, &st.HasComments, false, func() { p.structType(st) }})
+	}
+	for i := range g.UnionTypes {
+		un := &g.UnionTypes[i]
+		decls = append(decls, printerDecl{un.Pos, &un.HasComments, true, func() {
+			var types []string
+			for j := range un.Types {
+				types = append(types, un.Types[j].String())
+			}
+			p.line(0, "union "+un.Name+" = "+strings.Join(types, " | ")+postComments(&un.HasComments))
+		}})
+	}
+	for i := range g.ErrorTypes {
+		er := &g.ErrorTypes[i]
+		decls = append(decls, printerDecl{er.Pos, &er.HasComments, true, func() {
+			s := "error " + er.Name + "(" + strconv.Itoa(er.Status) + ")"
+			if er.Payload != nil {
+				s += ": " + er.Payload.String()
+			}
+			p.line(0, s+postComments(&er.HasComments))
+		}})
+	}
+	for i := range g.Ifaces {
+		iface := &g.Ifaces[i]
+		decls = append(decls, printerDecl{iface.Pos, &iface.HasComments, false, func() { p.iface(iface) }})
+	}
+	sort.SliceStable(decls, func(i, j int) bool {
+		a, b := decls[i].pos, decls[j].pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	for i, decl := range decls {
+		if i == 0 || !decl.oneLine || !decls[i-1].oneLine ||
+			!adjacent(decls[i-1].pos, decl.pos, decl.comments) {
+			p.blank()
+		}
+		p.comments(0, decl.comments)
+		decl.print()
+	}
+
+	if rest := append(append([]string{}, dangling...), tail...); len(rest) > 0 {
+		p.blank()
+		p.commentLines(0, rest)
+	}
+	return p.buf.String()
+}
+
+type printerDecl struct {
+	pos      Pos
+	comments *HasComments
+	// declarations printed in a line, e.g. scalars
+	oneLine bool
+	print   func()
+}
+
+// adjacent reports whether the node at cur with comments c starts at the
+// next line of the single line node at prev
+func adjacent(prev Pos, cur Pos, c *HasComments) bool {
+	return prev.IsValid() && cur.IsValid() && cur.Line-len(c.Lines)-prev.Line <= 1
+}
+
+type printer struct {
+	buf strings.Builder
+}
+
+func (p *printer) line(indent int, s string) {
+	p.buf.WriteString(strings.Repeat(formatIndent, indent))
+	p.buf.WriteString(s)
+	p.buf.WriteString("\n")
+}
+
+func (p *printer) blank() {
+	p.buf.WriteString("\n")
+}
+
+func (p *printer) comments(indent int, c *HasComments) {
+	p.commentLines(indent, commentLines(c))
+}
+
+func (p *printer) commentLines(indent int, lines []string) {
+	for _, line := range lines {
+		p.line(indent, formatComment(line))
+	}
+}
+
+func formatComment(s string) string {
+	if s == "" {
+		return CommentSign
+	}
+	return CommentSign + " " + s
+}
+
+// postComments returns the comment at the end of line of single line nodes
+func postComments(c *HasComments) string {
+	return joinComments(c.PostComments)
+}
+
+// joinComments returns comments at the end of line, there can be only one
+// comment in a line, so multiple ones are joined
+func joinComments(comments []string) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	return " " + formatComment(strings.Join(comments, " "+CommentSign+" "))
+}
+
+// splitPostComments splits PostComments of c by HeadComments &
+// DanglingComments, all of them are in tail if c is not parsed
+func splitPostComments(c *HasComments) (head, dangling, tail []string) {
+	pcs := c.PostComments
+	h := c.HeadComments
+	if h > len(pcs) {
+		h = len(pcs)
+	}
+	d := c.DanglingComments
+	if d > len(pcs)-h {
+		d = len(pcs) - h
+	}
+	return pcs[:h], pcs[h : h+d], pcs[h+d:]
+}
+
+// block prints a block with members printed by printMembers
+func (p *printer) block(head string, c *HasComments, members int, printMembers func()) {
+	headComments, dangling, tail := splitPostComments(c)
+	if members == 0 && len(headComments) == 0 && len(dangling) == 0 {
+		p.line(0, head+" {}"+joinComments(tail))
+		return
+	}
+	p.line(0, head+" {"+joinComments(headComments))
+	printMembers()
+	p.commentLines(1, dangling)
+	p.line(0, "}"+joinComments(tail))
+}
+
+func (p *printer) enum(en *EnumType) {
+	p.block("enum "+en.Name, &en.HasComments, len(en.Options), func() {
+		var prev Pos
+		for i := range en.Options {
+			o := &en.Options[i]
+			if i > 0 && prev.IsValid() && !adjacent(prev, o.Pos, &o.HasComments) {
+				p.blank()
+			}
+			prev = o.Pos
+			p.comments(1, &o.HasComments)
+			s := o.Name
+			if o.Value != nil {
+				if o.Value.IntVal != nil {
+					s += " = " + strconv.FormatInt(*o.Value.IntVal, 10)
+				} else if o.Value.StrVal != nil {
+					s += " = " + strconv.Quote(*o.Value.StrVal)
+				}
+			}
+			p.line(1, s+postComments(&o.HasComments))
+		}
+	})
+}
+
+func (p *printer) structType(st *StructType) {
+	head := "struct " + st.Name
+	if len(st.TypeParams) > 0 {
+		head += "<" + strings.Join(st.TypeParams, ", ") + ">"
+	}
+	if len(st.Extends) > 0 {
+		var parents []string
+		for i := range st.Extends {
+			parents = append(parents, st.Extends[i].String())
+		}
+		head += " extends " + strings.Join(parents, ", ")
+	}
+	p.block(head, &st.HasComments, len(st.Fields), func() {
+		var prev Pos
+		for i := range st.Fields {
+			f := &st.Fields[i]
+			if i > 0 && prev.IsValid() && !adjacent(prev, f.Pos, &f.HasComments) {
+				p.blank()
+			}
+			prev = f.Pos
+			p.comments(1, &f.HasComments)
+			p.line(1, valueDecl(f.Name, f.Type, f.Default)+postComments(&f.HasComments))
+		}
+	})
+}
+
+// valueDecl renders a field or param, e.g. `page: int = 1`
+func valueDecl(name string, t *TypeRef, l *Literal) string {
+	s := name + ": " + t.String()
+	if l != nil {
+		s += " = " + l.String()
+	}
+	return s
+}
+
+func (p *printer) iface(iface *Iface) {
+	p.block("interface "+iface.Name, &iface.HasComments, len(iface.Funs), func() {
+		for i := range iface.Funs {
+			if i > 0 {
+				p.blank()
+			}
+			p.fun(&iface.Funs[i])
+		}
+	})
+}
+
+func (p *printer) fun(fun *Fun) {
+	p.comments(1, &fun.HasComments)
+	ret := ""
+	if fun.Stream != nil {
+		ret = ": stream"
+		if fun.Stream.Item != nil {
+			ret += "<" + fun.Stream.Item.String() + ">"
+		}
+	} else if fun.Type != nil {
+		ret = ": " + fun.Type.String()
+	}
+
+	head, dangling, tail := splitPostComments(&fun.HasComments)
+	oneLine := len(head) == 0 && len(dangling) == 0
+	var params []string
+	for i := range fun.Params {
+		param := &fun.Params[i]
+		params = append(params, valueDecl(param.Name, param.Type, param.Default))
+		if len(commentLines(&param.HasComments)) > 0 || len(param.PostComments) > 0 {
+			oneLine = false
+		}
+	}
+	s := fun.Name + "(" + strings.Join(params, ", ") + ")" + ret
+	if oneLine && len(formatIndent)+len(s) <= formatMaxWidth {
+		p.line(1, s+joinComments(tail))
+		return
+	}
+
+	p.line(1, fun.Name+"("+joinComments(head))
+	for i := range fun.Params {
+		param := &fun.Params[i]
+		p.comments(2, &param.HasComments)
+		s := params[i]
+		if i < len(params)-1 {
+			s += ","
+		}
+		p.line(2, s+postComments(&param.HasComments))
+	}
+	p.commentLines(2, dangling)
+	p.line(1, ")"+ret+joinComments(tail))
+}
+
+// commentLines returns Lines of c, or comments rendered from Comments &
+// SemComments if c is not parsed, e.g. built by importers
+func commentLines(c *HasComments) []string {
+	if len(c.Lines) > 0 {
+		return c.Lines
+	}
+	lines := append([]string{}, c.Comments...)
+	var keys []string
+	for key := range c.SemComments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, semCommentLines(key, c.SemComments[key])...)
+	}
+	return lines
+}
+
+// semCommentLines renders the semantic comment of key, arrays are rendered as
+// repeated keys (see HasComments.AddSemComment), values other than single
+// line strings are rendered as json
+func semCommentLines(key string, val interface{}) []string {
+	if items, ok := val.([]interface{}); ok {
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, semCommentLines(key, item)...)
+		}
+		return lines
+	}
+	head := SemSign + key
+	if s, ok := val.(string); ok && s == strings.TrimSpace(s) && !strings.HasPrefix(s, "|") {
+		if s == "" {
+			return []string{head}
+		}
+		if !strings.Contains(s, "\n") {
+			return []string{head + " " + s}
+		}
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+	if len(b) <= formatMaxSemWidth {
+		return []string{head + ":json " + string(b)}
+	}
+	if b, err = json.MarshalIndent(val, "", formatIndent); err != nil {
+		return nil
+	}
+	lines := []string{head + ":json |"}
+	for _, line := range strings.Split(string(b), "\n") {
+		lines = append(lines, formatIndent+line)
+	}
+	return lines
+}
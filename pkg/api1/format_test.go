@@ -0,0 +1,220 @@
+package api1
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unformattedApi = `#   user apis
+#@go.package users
+group user # group
+import "common.api"   #common types
+
+scalar Time
+scalar Date # date only
+
+#the role
+enum Role { # roles
+	Admin=1,
+    Guest = 2 # guest
+
+	Owner = 3
+}
+
+# @example:json |
+#   {
+#     "name": "tom"
+#   }
+# @summary:yaml |
+#  title: user
+#  tags: [a, b]
+# @description |
+#   multiple
+#   lines
+struct User<T> extends common.Base {
+	name :string
+  data: T?
+	tags : map<string, [int]>
+	# dangling
+}
+
+struct Empty {
+}
+
+union Pet = Cat|Dog
+error NotFound ( 404 ) : common.Detail
+
+interface UserApi
+{
+	# @route GET /users/:id
+  getUser(id:int,# the id
+  role: Role? = Admin): User<string>? # get user
+	# @route GET /users
+	listUsers(page: int = 1, size: int = 10, keyword: string = "", tags: [string] = ["a", "b"], sort: string?): [User<int>]
+	watch(): stream<User<int>>
+	remove(
+	  id: int
+	  # to be removed
+	)
+}
+# end of file
+`
+
+func TestFormat(t *testing.T) {
+	formatted, err := Format("user.api", unformattedApi)
+	assert.NoError(t, err)
+	assert.Equal(t, `#   user apis
+# @go.package users
+group user # group
+
+import "common.api" # common types
+
+scalar Time
+scalar Date # date only
+
+# the role
+enum Role { # roles
+  Admin = 1
+  Guest = 2 # guest
+
+  Owner = 3
+}
+
+# @example:json |
+#   {
+#     "name": "tom"
+#   }
+# @summary:yaml |
+#  title: user
+#  tags: [a, b]
+# @description |
+#   multiple
+#   lines
+struct User<T> extends common.Base {
+  name: string
+  data: T?
+  tags: {string: [int]}
+  # dangling
+}
+
+struct Empty {}
+
+union Pet = Cat | Dog
+error NotFound(404): common.Detail
+
+interface UserApi {
+  # @route GET /users/:id
+  getUser(
+    id: int, # the id
+    role: Role? = Admin
+  ): User<string>? # get user
+
+  # @route GET /users
+  listUsers(
+    page: int = 1,
+    size: int = 10,
+    keyword: string = "",
+    tags: [string] = ["a", "b"],
+    sort: string?
+  ): [User<int>]
+
+  watch(): stream<User<int>>
+
+  remove(
+    id: int
+    # to be removed
+  )
+}
+
+# end of file
+`, formatted)
+
+	_, err = Format("user.api", "group user\n\nstruct {\n")
+	assert.EqualError(t, err, "user.api:3:1: error: expected declaration, found [struct] [syntax]")
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	contents := map[string]string{"user.api": unformattedApi}
+	files, err := filepath.Glob("../../e2e/testData/api/*.api")
+	assert.NoError(t, err)
+	for _, file := range append(files, "../golang/testdata/server.api") {
+		content, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		contents[file] = string(content)
+	}
+	parse := func(file string, content string) string {
+		p := &Parser{}
+		schema, err := p.parse(file, content)
+		assert.NoError(t, err, file)
+		return toJson(t, schema)
+	}
+	for file, content := range contents {
+		formatted, err := Format(file, content)
+		assert.NoError(t, err, file)
+		assert.Equal(t, parse(file, content), parse(file, formatted), file)
+		again, err := Format(file, formatted)
+		assert.NoError(t, err, file)
+		assert.Equal(t, formatted, again, file)
+	}
+
+	server := contents["../golang/testdata/server.api"]
+	formatted, err := Format("server.api", server)
+	assert.NoError(t, err)
+	assert.Equal(t, server, formatted)
+}
+
+func TestPrintGroup(t *testing.T) {
+	g := &ApiGroup{}
+	g.Name = "user"
+	st := StructType{}
+	st.Name = "User"
+	st.Comments = []string{"a user"}
+	st.SemComments = map[string]interface{}{
+		"deprecated": "",
+		"go.tag":     []interface{}{`json:"user"`, "db"},
+		"example": map[string]interface{}{
+			"name":  "tom",
+			"tags":  []interface{}{"admin", "staff"},
+			"about": "a user with a long description, which is rendered in multiple lines",
+		},
+		"summary": " user ",
+	}
+	field := StructField{Type: &TypeRef{HasName: HasName{Name: "string"}, Nullable: true}}
+	field.Name = "name"
+	field.PostComments = []string{"name of user"}
+	st.Fields = append(st.Fields, field)
+	g.StructTypes = append(g.StructTypes, st)
+
+	text := PrintGroup(g)
+	assert.Equal(t, `group user
+
+# a user
+# @deprecated
+# @example:json |
+#   {
+#     "about": "a user with a long description, which is rendered in multiple lines",
+#     "name": "tom",
+#     "tags": [
+#       "admin",
+#       "staff"
+#     ]
+#   }
+# @go.tag json:"user"
+# @go.tag db
+# @summary:json " user "
+struct User {
+  name: string? # name of user
+}
+`, text)
+
+	p := &Parser{}
+	schema, err := p.parse("", text)
+	assert.NoError(t, err)
+	parsed := schema.Groups[0].StructTypes[0]
+	assert.Equal(t, st.Comments, parsed.Comments)
+	assert.Equal(t, st.SemComments, parsed.SemComments)
+	assert.Equal(t, field.PostComments, parsed.Fields[0].PostComments)
+}
@@ -0,0 +1,298 @@
+package api1
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/utils"
+)
+
+// maxInstanceDepth limits nested instantiations, e.g. `struct Foo<T> { foo: Foo<[T]>? }`
+// would be expanded infinitely.
+const maxInstanceDepth = 32
+
+// StructInstance is a generic struct instantiated with type args
+type StructInstance struct {
+	// group which the instance belongs to, i.e. the group of the first type
+	// arg defined in another group than the generic struct (which may use
+	// the generic's group but not vice versa), or the group of the generic
+	Group string
+	// type reference to the instance, e.g. `Page<User>`
+	Ref TypeRef
+	// instantiated struct, named as `Page_User`, or `Page_UserUser` if
+	// qualified by groups of type args to tell apart from others
+	Struct StructType
+}
+
+// Instances are the generic struct instances referenced by a schema
+type Instances []StructInstance
+
+// Find returns the instance referenced by t, or nil if t is not an instance
+func (is Instances) Find(t *TypeRef) *StructInstance {
+	if len(t.TypeArgs) == 0 {
+		return nil
+	}
+	key := instanceKey(t)
+	for i := range is {
+		if instanceKey(&is[i].Ref) == key {
+			return &is[i]
+		}
+	}
+	return nil
+}
+
+// instanceKey returns the fully qualified type ref, e.g. `common.Page<user.User>`
+func instanceKey(t *TypeRef) string {
+	r := *t
+	r.Nullable = false
+	return r.String()
+}
+
+// MonoName returns name of the monomorphized type, e.g. `Page_User` for `Page<User>`
+func (t *TypeRef) MonoName() string {
+	if len(t.TypeArgs) == 0 {
+		return t.Name
+	}
+	names := []string{t.Name}
+	for i := range t.TypeArgs {
+		names = append(names, monoArgName(&t.TypeArgs[i]))
+	}
+	return strings.Join(names, "_")
+}
+
+// QualMonoName returns MonoName with type args qualified by their groups,
+// e.g. `Page_UserUser` for `Page<user.User>`
+func (t *TypeRef) QualMonoName() string {
+	names := []string{t.Name}
+	for i := range t.TypeArgs {
+		names = append(names, qualMonoArgName(&t.TypeArgs[i], true))
+	}
+	return strings.Join(names, "_")
+}
+
+func monoArgName(t *TypeRef) string {
+	return qualMonoArgName(t, false)
+}
+
+func qualMonoArgName(t *TypeRef, qualified bool) string {
+	var name string
+	if t.Name != "" {
+		r := TypeRef{HasName: HasName{Name: utils.PascalCase(t.Name)}, TypeArgs: t.TypeArgs}
+		if qualified {
+			name = utils.PascalCase(t.Group) + r.QualMonoName()
+		} else {
+			name = r.MonoName()
+		}
+	} else if t.KeyType != nil {
+		name = qualMonoArgName(t.KeyType, qualified) + qualMonoArgName(t.ItemType, qualified) + "Map"
+	} else if t.ItemType != nil {
+		name = qualMonoArgName(t.ItemType, qualified) + "List"
+	}
+	if t.Nullable {
+		name = "Nullable" + name
+	}
+	return name
+}
+
+// Instantiate returns a copy of the generic struct, with type params replaced
+// by args.
+func (st *StructType) Instantiate(args []TypeRef) StructType {
+	m := make(map[string]*TypeRef)
+	for i, param := range st.TypeParams {
+		if i < len(args) {
+			m[param] = &args[i]
+		}
+	}
+	inst := *st
+	inst.Name = (&TypeRef{HasName: HasName{Name: st.Name}, TypeArgs: args}).MonoName()
+	inst.TypeParams = nil
+	inst.Extends = nil
+	for i := range st.Extends {
+		inst.Extends = append(inst.Extends, *substitute(&st.Extends[i], m))
+	}
+	inst.Fields = nil
+	for _, field := range st.Fields {
+		field.Type = substitute(field.Type, m)
+		inst.Fields = append(inst.Fields, field)
+	}
+	return inst
+}
+
+func substitute(t *TypeRef, m map[string]*TypeRef) *TypeRef {
+	if t == nil {
+		return nil
+	}
+	if arg, ok := m[t.Name]; ok && len(t.TypeArgs) == 0 {
+		r := *arg
+		r.Nullable = arg.Nullable || t.Nullable
+		return &r
+	}
+	r := *t
+	r.KeyType = substitute(t.KeyType, m)
+	r.ItemType = substitute(t.ItemType, m)
+	r.TypeArgs = nil
+	for i := range t.TypeArgs {
+		r.TypeArgs = append(r.TypeArgs, *substitute(&t.TypeArgs[i], m))
+	}
+	return &r
+}
+
+// Instances returns all the generic struct instances referenced (directly or
+// indirectly) by schema, sorted by name. Instances of the same name are
+// renamed by QualMonoName, and names which still collide are reported.
+func (s *Schema) Instances() (Instances, error) {
+	type generic struct {
+		group string
+		st    StructType
+	}
+	generics := make(map[string]generic)
+	declared := make(map[string]string)
+	for _, g := range s.Groups {
+		for _, st := range g.StructTypes {
+			key := qualName(g.Name, st.Name)
+			if _, ok := generics[key]; !ok && len(st.TypeParams) > 0 {
+				generics[key] = generic{group: g.Name, st: st}
+			}
+		}
+		var names []string
+		for _, sc := range g.ScalarTypes {
+			names = append(names, sc.Name)
+		}
+		for _, en := range g.EnumTypes {
+			names = append(names, en.Name)
+		}
+		for _, st := range g.StructTypes {
+			names = append(names, st.Name)
+		}
+		for _, un := range g.UnionTypes {
+			names = append(names, un.Name)
+		}
+		for _, er := range g.ErrorTypes {
+			names = append(names, er.Name)
+		}
+		for _, name := range names {
+			declared[name] = qualName(g.Name, name)
+		}
+	}
+	if len(generics) == 0 {
+		return nil, nil
+	}
+
+	var instances Instances
+	var err error
+	seen := make(map[string]bool)
+	// homeGroup returns the group which t belongs to, empty for builtins
+	var homeGroup func(t *TypeRef) string
+	homeGroup = func(t *TypeRef) string {
+		if t.Name == "" {
+			if group := homeGroup(t.ItemType); group != "" || t.KeyType == nil {
+				return group
+			}
+			return homeGroup(t.KeyType)
+		}
+		g, ok := generics[t.QualName()]
+		if !ok || len(t.TypeArgs) == 0 {
+			return t.Group
+		}
+		for i := range t.TypeArgs {
+			if group := homeGroup(&t.TypeArgs[i]); group != "" && group != g.group {
+				return group
+			}
+		}
+		return g.group
+	}
+	var visit func(t *TypeRef, depth int)
+	visit = func(t *TypeRef, depth int) {
+		if t == nil || err != nil {
+			return
+		}
+		visit(t.KeyType, depth)
+		visit(t.ItemType, depth)
+		for i := range t.TypeArgs {
+			visit(&t.TypeArgs[i], depth)
+		}
+		g, ok := generics[t.QualName()]
+		if !ok || len(t.TypeArgs) != len(g.st.TypeParams) {
+			return
+		}
+		key := instanceKey(t)
+		if seen[key] {
+			return
+		}
+		if depth >= maxInstanceDepth {
+			err = newError(t.Pos, CodeGeneric,
+				"Struct [%s] is instantiated recursively", g.st.Name)
+			return
+		}
+		seen[key] = true
+		inst := g.st.Instantiate(t.TypeArgs)
+		ref := *t
+		ref.Nullable = false
+		instances = append(instances, StructInstance{Group: homeGroup(t), Ref: ref, Struct: inst})
+		for i := range inst.Extends {
+			visit(&inst.Extends[i], depth+1)
+		}
+		for _, field := range inst.Fields {
+			visit(field.Type, depth+1)
+		}
+	}
+	for _, g := range s.Groups {
+		for _, st := range g.StructTypes {
+			if len(st.TypeParams) > 0 {
+				continue
+			}
+			for i := range st.Extends {
+				visit(&st.Extends[i], 0)
+			}
+			for _, field := range st.Fields {
+				visit(field.Type, 0)
+			}
+		}
+		for _, iface := range g.Ifaces {
+			for _, fun := range iface.Funs {
+				visit(fun.Type, 0)
+				if fun.Stream != nil {
+					visit(fun.Stream.Item, 0)
+				}
+				for _, param := range fun.Params {
+					visit(param.Type, 0)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// instances are named apart from each other & declared types, since
+	// they may be rendered into the same package or components
+	count := make(map[string]int)
+	for _, inst := range instances {
+		count[inst.Struct.Name]++
+	}
+	for i := range instances {
+		if inst := &instances[i]; count[inst.Struct.Name] > 1 {
+			inst.Struct.Name = inst.Ref.QualMonoName()
+		}
+	}
+	named := make(map[string]*StructInstance)
+	for i := range instances {
+		inst := &instances[i]
+		name := inst.Struct.Name
+		if other, ok := named[name]; ok {
+			return nil, newError(inst.Ref.Pos, CodeGeneric,
+				"Struct instances [%s] and [%s] are both named [%s]",
+				instanceKey(&other.Ref), instanceKey(&inst.Ref), name)
+		}
+		if decl, ok := declared[name]; ok {
+			return nil, newError(inst.Ref.Pos, CodeGeneric,
+				"Struct instance [%s] is named [%s], which is defined as [%s]",
+				instanceKey(&inst.Ref), name, decl)
+		}
+		named[name] = inst
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Struct.Name < instances[j].Struct.Name
+	})
+	return instances, nil
+}
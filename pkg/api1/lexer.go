@@ -0,0 +1,130 @@
+package api1
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+	tokenComment
+)
+
+const puncts = "{}()[]:,?=<>|."
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+	// comment after other tokens on the same line
+	trailing bool
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokenPunct && t.text == punct
+}
+
+func (t token) isIdent(name string) bool {
+	return t.kind == tokenIdent && t.text == name
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "EOF"
+	}
+	return t.text
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// tokenize splits content into tokens, the last token is always tokenEOF.
+func tokenize(file string, content string) ([]token, Diagnostics) {
+	var tokens []token
+	var diags Diagnostics
+	line, lineStart := 1, 0
+	lastLine := 0 // line of last non-comment token
+
+	i := 0
+	for i < len(content) {
+		c := content[i]
+		pos := Pos{File: file, Line: line, Column: i - lineStart + 1}
+		start := i
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+			continue
+		case c == CommentSign[0]:
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, token{
+				kind:     tokenComment,
+				text:     strings.TrimRight(content[start:i], "\r"),
+				pos:      pos,
+				trailing: lastLine == line,
+			})
+			continue
+		case isLetter(c):
+			for i < len(content) && (isLetter(content[i]) || isDigit(content[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: content[start:i], pos: pos})
+		case isDigit(c) || c == '-' && i+1 < len(content) && isDigit(content[i+1]):
+			i++
+			for i < len(content) && isDigit(content[i]) {
+				i++
+			}
+			if i+1 < len(content) && content[i] == '.' && isDigit(content[i+1]) {
+				i++
+				for i < len(content) && isDigit(content[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: content[start:i], pos: pos})
+		case c == '"':
+			i++
+			for i < len(content) && content[i] != '"' && content[i] != '\n' {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+				}
+				i++
+			}
+			if i >= len(content) || content[i] != '"' {
+				diags = append(diags, newError(pos, CodeSyntax, "string is not terminated"))
+				continue
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: content[start:i], pos: pos})
+		case strings.IndexByte(puncts, c) >= 0:
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: content[start:i], pos: pos})
+		default:
+			r, size := utf8.DecodeRuneInString(content[i:])
+			i += size
+			diags = append(diags, newError(pos, CodeSyntax, "invalid character [%c]", r))
+			continue
+		}
+		lastLine = line
+	}
+	tokens = append(tokens, token{
+		kind: tokenEOF,
+		pos:  Pos{File: file, Line: line, Column: len(content) - lineStart + 1},
+	})
+	return tokens, diags
+}
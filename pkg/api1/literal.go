@@ -0,0 +1,184 @@
+package api1
+
+import (
+	"strconv"
+	"strings"
+)
+
+func (l *Literal) String() string {
+	switch l.Kind {
+	case LiteralString:
+		return strconv.Quote(l.Text)
+	case LiteralNull:
+		return "null"
+	case LiteralArray:
+		var items []string
+		for i := range l.Items {
+			items = append(items, l.Items[i].String())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return l.Text
+}
+
+// String renders the resolved type in `*.api` syntax, e.g. `{string: [common.Status]}?`
+func (t *TypeRef) String() string {
+	var s string
+	if t.Name != "" {
+		s = t.QualName()
+		if len(t.TypeArgs) > 0 {
+			var args []string
+			for i := range t.TypeArgs {
+				args = append(args, t.TypeArgs[i].String())
+			}
+			s += "<" + strings.Join(args, ", ") + ">"
+		}
+	} else if t.KeyType != nil {
+		s = "{" + t.KeyType.String() + ": " + t.ItemType.String() + "}"
+	} else if t.ItemType != nil {
+		s = "[" + t.ItemType.String() + "]"
+	}
+	if t.Nullable {
+		s += "?"
+	}
+	return s
+}
+
+// checkDefault checks default value l fits type t, params are type params
+// in scope, whose default values are not supported.
+func checkDefault(l *Literal, t *TypeRef, params map[string]bool,
+	types map[string]interface{}, report reportFunc) {
+	if l == nil || t == nil {
+		return
+	}
+	if l.Kind == LiteralNull {
+		if !t.Nullable {
+			report(l.Pos, CodeDefault,
+				"Default value [null] does not match non-nullable type [%s]", t)
+		}
+		return
+	}
+	mismatch := func() {
+		report(l.Pos, CodeDefault, "Default value [%s] does not match type [%s]", l, t)
+	}
+	unsupported := func() {
+		report(l.Pos, CodeDefault, "Default value is not supported for type [%s]", t)
+	}
+	if t.Name == "" {
+		if t.KeyType != nil {
+			unsupported()
+		} else if l.Kind != LiteralArray {
+			mismatch()
+		} else {
+			for i := range l.Items {
+				checkDefault(&l.Items[i], t.ItemType, params, types, report)
+			}
+		}
+		return
+	}
+	if t.Group == "" && params[t.Name] {
+		unsupported()
+		return
+	}
+	var ok bool
+	switch t.Name {
+	case "int":
+		ok = l.Kind == LiteralInt
+	case "float":
+		ok = l.Kind == LiteralInt || l.Kind == LiteralFloat
+	case "string":
+		ok = l.Kind == LiteralString
+	case "boolean":
+		ok = l.Kind == LiteralBool
+	case "any":
+		ok = l.Kind != LiteralIdent
+	case "object":
+		unsupported()
+		return
+	default:
+		typ, found := types[t.QualName()]
+		if !found {
+			// unresolved type is reported already
+			return
+		}
+		switch typ := typ.(type) {
+		case EnumType:
+			if l.Kind != LiteralIdent {
+				mismatch()
+			} else if findOption(&typ, l.Text) == nil {
+				report(l.Pos, CodeDefault, "Enum [%s] has no option [%s]", typ.Name, l.Text)
+			}
+			return
+		case ScalarType:
+			ok = l.Kind != LiteralIdent && l.Kind != LiteralArray
+		default:
+			unsupported()
+			return
+		}
+	}
+	if !ok {
+		mismatch()
+	}
+}
+
+func findOption(en *EnumType, name string) *EnumOption {
+	for i := range en.Options {
+		if en.Options[i].Name == name {
+			return &en.Options[i]
+		}
+	}
+	return nil
+}
+
+// LiteralValue returns the JSON value of literal l of type t, enum options are
+// converted to their values.
+func (s *Schema) LiteralValue(l *Literal, t *TypeRef) interface{} {
+	if l == nil {
+		return nil
+	}
+	switch l.Kind {
+	case LiteralInt:
+		if t != nil && t.Name == "float" {
+			val, _ := strconv.ParseFloat(l.Text, 64)
+			return val
+		}
+		val, _ := strconv.ParseInt(l.Text, 10, 64)
+		return val
+	case LiteralFloat:
+		val, _ := strconv.ParseFloat(l.Text, 64)
+		return val
+	case LiteralString:
+		return l.Text
+	case LiteralBool:
+		return l.Text == "true"
+	case LiteralIdent:
+		if t != nil {
+			for _, g := range s.Groups {
+				for i := range g.EnumTypes {
+					en := &g.EnumTypes[i]
+					if qualName(g.Name, en.Name) != t.QualName() {
+						continue
+					}
+					if option := findOption(en, l.Text); option != nil && option.Value != nil {
+						if option.Value.IntVal != nil {
+							return *option.Value.IntVal
+						}
+						return *option.Value.StrVal
+					}
+				}
+			}
+		}
+		return l.Text
+	case LiteralArray:
+		items := make([]interface{}, 0, len(l.Items))
+		for i := range l.Items {
+			var itemType *TypeRef
+			if t != nil {
+				itemType = t.ItemType
+			}
+			items = append(items, s.LiteralValue(&l.Items[i], itemType))
+		}
+		return items
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,367 +16,853 @@ import (
 const CommentSign = "#"
 const SemSign = "@"
 
-const tID = "[A-Za-z][0-9A-Za-z_]*?"
-const tType = "[A-Za-z\\[][0-9A-Za-z_\\[\\]\\?]*?"
-
-var reComment = compile("^([^%s]*?)\\s*(%s.*)$", CommentSign, CommentSign)
 var reSemComment = compile("^\\s*%s([0-9A-Za-z_.]+?)(?::(json|ya?ml))?(?:|\\s*([|])|\\s+([^|].*?))\\s*$", SemSign)
-var reGroup = compile("^group\\s+(%s)$", tID)
-var reScalar = compile("^scalar\\s+(%s)$", tID)
-var reBlockStart = compile("^(enum|struct|interface)\\s+(%s)\\s*\\{$", tID)
-var reEnumOption = compile("^(%s)(?:\\s*=\\s*(?:([0-9]+?)|\"(.+?)\"))?\\s*,?$", tID)
-var reStructField = compile("^(%s)\\s*:\\s*(%s)$", tID, tType)
-var reFunInline = compile("^(%s)\\s*\\((.*?)\\)(?:\\s*:\\s*(%s))?$", tID, tType)
-var reFunStart = compile("^(%s)\\s*\\(\\s*(.*?)$", tID)
-var reFunEnd = compile("^(.*?)\\s*\\)(?:\\s*:\\s*(%s))?$", tType)
-var reParam = compile("^(%s)\\s*:\\s*(%s)(?:\\s*=\\s*(.+?))?$", tID, tType)
-var reCommaSep = compile("\\s*,\\s*")
 
+var declKeywords = map[string]bool{
+	"scalar":    true,
+	"enum":      true,
+	"struct":    true,
+	"union":     true,
+	"error":     true,
+	"interface": true,
+}
+
+// Parser is a recursive-descent parser of `*.api` files.
+//
+// Comments on their own lines are attached to the next node, comments at the
+// end of a line are attached to the last node on that line as post comments,
+// and dangling comments before `}` or `)` are post comments of the block.
 type Parser struct {
+	tokens       []token
+	cur          int
 	comments     []string
+	commentsPos  []Pos
 	postComments []string
+	diags        Diagnostics
 }
 
+// Parse parses contents and checks the result schema, all the problems found
+// are returned together as Diagnostics.
 func (p *Parser) Parse(contents ...string) (*Schema, error) {
 	schema := &Schema{}
+	var diags Diagnostics
 	for _, content := range contents {
-		subSchema, err := p.parse(content)
-		if err != nil {
-			return nil, err
+		subSchema, err := p.parse("", content)
+		diags.Add(err)
+		if subSchema != nil {
+			schema.Groups = append(schema.Groups, subSchema.Groups...)
 		}
-		schema.Groups = append(schema.Groups, subSchema.Groups...)
 	}
 	schema.MergeGroupIfaces()
-	if err := schema.Check(); err != nil {
+	diags.Add(schema.Check())
+	if err := diags.Err(); err != nil {
 		return nil, err
 	}
 	return schema, nil
 }
 
 func (p *Parser) ParseFiles(files ...string) (*Schema, error) {
+	return p.ParseFilesWith(ioutil.ReadFile, files...)
+}
+
+// ParseFilesWith is ParseFiles reading files by readFile, e.g. from a git
+// revision, files imported relatively are loaded if readFile succeeds.
+func (p *Parser) ParseFilesWith(readFile func(file string) ([]byte, error),
+	files ...string) (*Schema, error) {
 	schema := &Schema{}
+	var diags Diagnostics
+	loaded := make(map[string]bool)
 	for _, file := range files {
-		content, err := ioutil.ReadFile(file)
-		if err != nil {
-			return nil, errors.Errorf("Read file [%s] failed: %v", file, err)
+		loaded[filepath.Clean(file)] = true
+	}
+	// contents of imported files, which are read to find out they exist
+	contents := make(map[string][]byte)
+	queue := append([]string{}, files...)
+	for i := 0; i < len(queue); i++ {
+		file := queue[i]
+		content, ok := contents[file]
+		if !ok {
+			var err error
+			if content, err = readFile(file); err != nil {
+				return nil, errors.Errorf("Read file [%s] failed: %v", file, err)
+			}
 		}
-		subSchema, err := p.parse(string(content))
-		if err != nil {
-			return nil, errors.Errorf("Parse file [%s] failed: %v", file, err)
+		subSchema, err := p.parse(file, string(content))
+		diags.Add(err)
+		if subSchema == nil {
+			continue
 		}
 		schema.Groups = append(schema.Groups, subSchema.Groups...)
+		// files imported relatively are loaded as well
+		for _, g := range subSchema.Groups {
+			for _, imp := range g.Imports {
+				path := filepath.Join(filepath.Dir(file), imp.Path)
+				if loaded[path] {
+					continue
+				}
+				if content, err := readFile(path); err == nil {
+					loaded[path] = true
+					contents[path] = content
+					queue = append(queue, path)
+				}
+			}
+		}
 	}
 	schema.MergeGroupIfaces()
-	if err := schema.Check(); err != nil {
+	diags.Add(schema.Check())
+	if err := diags.Err(); err != nil {
 		return nil, err
 	}
 	return schema, nil
 }
 
-func (p *Parser) parse(content string) (*Schema, error) {
-	var err error
-	var group *ApiGroup = nil
-	var parsingBlock bool = false
-	var enumType *EnumType = nil
-	var structType *StructType = nil
-	var iface *Iface = nil
-	var fun *Fun = nil
-
-	p.flushComments()
-
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if m := reComment.FindStringSubmatch(line); m != nil {
-			line = m[1]
-			comment := m[2]
-			if line == "" {
-				p.addComment(comment)
-			} else {
-				p.addPostComment(comment)
-			}
-		}
-		if line == "" {
+// parse returns the (maybe partial) schema with all the diagnostics.
+// On syntax error, the rest of the current block is skipped, and parsing
+// recovers at the next top-level block.
+func (p *Parser) parse(file string, content string) (*Schema, error) {
+	p.tokens, p.diags = tokenize(file, content)
+	p.cur = 0
+	p.comments = nil
+	p.commentsPos = nil
+	p.postComments = nil
+
+	group, err := p.parseGroup()
+	if err != nil {
+		p.diags.Add(err)
+		return nil, p.diags.Err()
+	}
+	for p.peek().isIdent("import") {
+		imp, err := p.parseImport()
+		if err != nil {
+			p.diags.Add(err)
+			p.syncDecl()
 			continue
 		}
+		group.Imports = append(group.Imports, *imp)
+	}
+	for p.peek().kind != tokenEOF {
+		if err := p.parseDecl(group); err != nil {
+			p.diags.Add(err)
+			p.syncDecl()
+		}
+	}
+	p.next()
+	p.flushPostCommentsTo(&group.HasComments)
+	return &Schema{Groups: []ApiGroup{*group}}, p.diags.Err()
+}
 
-		if group == nil {
-			if m := reGroup.FindStringSubmatch(line); m != nil {
-				group = &ApiGroup{}
-				group.Name = m[1]
-				group.HasComments, err = p.flushComments()
-				if err != nil {
-					return nil, err
-				}
-				continue
-			}
-			return nil, errors.Errorf("first line must be group, invalid line: [%s]", line)
+func (p *Parser) parseGroup() (*ApiGroup, error) {
+	tok := p.next()
+	if tok.kind == tokenEOF {
+		return nil, newError(tok.pos, CodeSyntax, "group is not defined")
+	}
+	if !tok.isIdent("group") {
+		return nil, newError(tok.pos, CodeSyntax,
+			"first declaration must be group, found [%s]", tok)
+	}
+	group := &ApiGroup{}
+	group.Pos = tok.pos
+	group.HasComments = p.flushComments()
+	name, err := p.expectIdent("group name")
+	if err != nil {
+		return nil, err
+	}
+	group.Name = name.text
+	p.takePostComments(&group.HasComments)
+	group.HeadComments = len(group.PostComments)
+	return group, nil
+}
+
+// import "common/types.api"
+func (p *Parser) parseImport() (*Import, error) {
+	tok := p.next()
+	imp := &Import{}
+	imp.Pos = tok.pos
+	imp.HasComments = p.flushComments()
+	path := p.next()
+	if path.kind != tokenString {
+		return nil, p.unexpected(path, "import path")
+	}
+	val, err := p.unquote(path)
+	if err != nil {
+		return nil, err
+	}
+	if val == "" {
+		return nil, newError(path.pos, CodeSyntax, "import path cannot be empty")
+	}
+	imp.Path = val
+	p.takePostComments(&imp.HasComments)
+	return imp, nil
+}
+
+func (p *Parser) parseDecl(group *ApiGroup) error {
+	if !p.atDecl() {
+		return p.unexpected(p.peek(), "declaration")
+	}
+	switch p.peek().text {
+	case "scalar":
+		sc, err := p.parseScalar()
+		if err != nil {
+			return err
 		}
+		group.ScalarTypes = append(group.ScalarTypes, *sc)
+	case "enum":
+		en, err := p.parseEnum()
+		if err != nil {
+			return err
+		}
+		group.EnumTypes = append(group.EnumTypes, *en)
+	case "struct":
+		st, err := p.parseStruct()
+		if err != nil {
+			return err
+		}
+		group.StructTypes = append(group.StructTypes, *st)
+	case "union":
+		un, err := p.parseUnion()
+		if err != nil {
+			return err
+		}
+		group.UnionTypes = append(group.UnionTypes, *un)
+	case "error":
+		er, err := p.parseError()
+		if err != nil {
+			return err
+		}
+		group.ErrorTypes = append(group.ErrorTypes, *er)
+	case "interface":
+		iface, err := p.parseIface()
+		if err != nil {
+			return err
+		}
+		group.Ifaces = append(group.Ifaces, *iface)
+	}
+	return nil
+}
 
-		if !parsingBlock {
-			if m := reScalar.FindStringSubmatch(line); m != nil {
-				scalarType := &ScalarType{}
-				scalarType.Name = m[1]
-				scalarType.HasComments, err = p.flushComments()
-				if err != nil {
-					return nil, err
-				}
-				group.ScalarTypes = append(group.ScalarTypes, *scalarType)
-				continue
-			}
-			if m := reBlockStart.FindStringSubmatch(line); m != nil {
-				switch m[1] {
-				case "enum":
-					enumType = &EnumType{}
-					enumType.Name = m[2]
-					enumType.HasComments, err = p.flushComments()
-					if err != nil {
-						return nil, err
-					}
-				case "struct":
-					structType = &StructType{}
-					structType.Name = m[2]
-					structType.HasComments, err = p.flushComments()
-					if err != nil {
-						return nil, err
-					}
-				case "interface":
-					iface = &Iface{}
-					iface.Name = m[2]
-					iface.HasComments, err = p.flushComments()
-					if err != nil {
-						return nil, err
-					}
-				}
-				parsingBlock = true
-				continue
-			}
-			return nil, errors.Errorf("invalid line [%s] for parsing.", line)
-		}
-
-		if line == "}" {
-			if enumType != nil {
-				p.flushPostCommentsTo(&enumType.HasComments)
-				group.EnumTypes = append(group.EnumTypes, *enumType)
-				enumType = nil
-			} else if structType != nil {
-				p.flushPostCommentsTo(&structType.HasComments)
-				group.StructTypes = append(group.StructTypes, *structType)
-				structType = nil
-			} else {
-				p.flushPostCommentsTo(&iface.HasComments)
-				group.Ifaces = append(group.Ifaces, *iface)
-				iface = nil
-			}
-			parsingBlock = false
-			continue
+func (p *Parser) parseScalar() (*ScalarType, error) {
+	tok := p.next()
+	sc := &ScalarType{}
+	sc.Pos = tok.pos
+	sc.HasComments = p.flushComments()
+	name, err := p.expectIdent("scalar name")
+	if err != nil {
+		return nil, err
+	}
+	sc.Name = name.text
+	p.takePostComments(&sc.HasComments)
+	return sc, nil
+}
+
+func (p *Parser) parseEnum() (*EnumType, error) {
+	tok := p.next()
+	en := &EnumType{}
+	en.Pos = tok.pos
+	en.HasComments = p.flushComments()
+	name, err := p.expectIdent("enum name")
+	if err != nil {
+		return nil, err
+	}
+	en.Name = name.text
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.takePostComments(&en.HasComments)
+	p.parseBlock(en.Pos, &en.HasComments, func() error {
+		option, err := p.parseEnumOption()
+		if err != nil {
+			return err
 		}
-		if enumType != nil {
-			option, err := p.parseEnumOption(line)
+		en.Options = append(en.Options, *option)
+		return nil
+	})
+	return en, nil
+}
+
+func (p *Parser) parseEnumOption() (*EnumOption, error) {
+	name, err := p.expectIdent("enum option")
+	if err != nil {
+		return nil, err
+	}
+	option := &EnumOption{}
+	option.Name = name.text
+	option.Pos = name.pos
+	option.HasComments = p.flushComments()
+	if p.accept("=") {
+		tok := p.next()
+		switch tok.kind {
+		case tokenNumber:
+			val, err := strconv.ParseInt(tok.text, 10, 64)
 			if err != nil {
-				return nil, err
+				return nil, newError(tok.pos, CodeSyntax,
+					"invalid int value [%s] for enum option", tok)
 			}
-			enumType.Options = append(enumType.Options, *option)
-			continue
-		}
-		if structType != nil {
-			field, err := p.parseStructField(line)
+			option.Value = &IntOrString{IntVal: &val}
+		case tokenString:
+			val, err := p.unquote(tok)
 			if err != nil {
 				return nil, err
 			}
-			structType.Fields = append(structType.Fields, *field)
-			continue
+			option.Value = &IntOrString{StrVal: &val}
+		default:
+			return nil, p.unexpected(tok, "enum option value")
 		}
+	}
+	p.accept(",")
+	p.takePostComments(&option.HasComments)
+	return option, nil
+}
 
-		// assert: iface != nil
-		if fun == nil {
-			if m := reFunInline.FindStringSubmatch(line); m != nil {
-				fun := &Fun{}
-				fun.Name = m[1]
-				fun.HasComments, err = p.flushComments()
-				if err != nil {
-					return nil, err
-				}
-				params, err := p.parseParams(m[2])
-				if err != nil {
-					return nil, err
-				}
-				fun.Params = params
-				fun.Type = p.parseType(m[3])
-				iface.Funs = append(iface.Funs, *fun)
-				continue
+func (p *Parser) parseStruct() (*StructType, error) {
+	tok := p.next()
+	st := &StructType{}
+	st.Pos = tok.pos
+	st.HasComments = p.flushComments()
+	name, err := p.expectIdent("struct name")
+	if err != nil {
+		return nil, err
+	}
+	st.Name = name.text
+	if p.accept("<") {
+		for {
+			param, err := p.expectIdent("type param")
+			if err != nil {
+				return nil, err
 			}
-			if m := reFunStart.FindStringSubmatch(line); m != nil {
-				c, err := p.flushComments()
-				if err != nil {
-					return nil, err
-				}
-				fun = &Fun{}
-				fun.Name = m[1]
-				params, err := p.parseParams(m[2])
-				if err != nil {
-					return nil, err
-				}
-				if len(params) > 0 {
-					fun.Params = params
-					fun.Comments = c.Comments
-					fun.SemComments = c.SemComments
-					params[len(params)-1].PostComments = c.PostComments
-				} else {
-					fun.HasComments = c
-				}
-				continue
+			st.TypeParams = append(st.TypeParams, param.text)
+			if !p.accept(",") {
+				break
 			}
-			return nil, errors.Errorf("invalid line [%s] for parsing.", line)
 		}
-		if m := reFunEnd.FindStringSubmatch(line); m != nil {
-			params, err := p.parseParams(m[1])
+		if _, err := p.expect(">"); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().isIdent("extends") {
+		p.next()
+		for {
+			parent, err := p.parseType()
 			if err != nil {
 				return nil, err
 			}
-			if len(params) > 0 {
-				postComments := params[len(params)-1].PostComments
-				params[len(params)-1].PostComments = nil
-				fun.PostComments = append(fun.PostComments, postComments...)
-				fun.Params = append(fun.Params, params...)
-			} else {
-				p.flushPostCommentsTo(&fun.HasComments)
+			st.Extends = append(st.Extends, *parent)
+			if !p.accept(",") {
+				break
 			}
-			fun.Type = p.parseType(m[2])
-			iface.Funs = append(iface.Funs, *fun)
-			fun = nil
-			continue
 		}
-		params, err := p.parseParams(line)
+	}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.takePostComments(&st.HasComments)
+	p.parseBlock(st.Pos, &st.HasComments, func() error {
+		field, err := p.parseStructField()
 		if err != nil {
+			return err
+		}
+		st.Fields = append(st.Fields, *field)
+		return nil
+	})
+	return st, nil
+}
+
+func (p *Parser) parseStructField() (*StructField, error) {
+	name, err := p.expectIdent("struct field")
+	if err != nil {
+		return nil, err
+	}
+	field := &StructField{}
+	field.Name = name.text
+	field.Pos = name.pos
+	field.HasComments = p.flushComments()
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if field.Type, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if p.accept("=") {
+		if field.Default, err = p.parseLiteral(); err != nil {
 			return nil, err
 		}
-		fun.Params = append(fun.Params, params...)
 	}
+	p.accept(",")
+	p.takePostComments(&field.HasComments)
+	return field, nil
+}
 
-	if parsingBlock {
-		return nil, errors.New("object in parsing is not finished yet, need close brace.")
+func (p *Parser) parseUnion() (*UnionType, error) {
+	tok := p.next()
+	un := &UnionType{}
+	un.Pos = tok.pos
+	un.HasComments = p.flushComments()
+	name, err := p.expectIdent("union name")
+	if err != nil {
+		return nil, err
 	}
-	p.flushPostCommentsTo(&group.HasComments)
-	return &Schema{Groups: []ApiGroup{*group}}, nil
+	un.Name = name.text
+	if _, err := p.expect("="); err != nil {
+		return nil, err
+	}
+	for {
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		un.Types = append(un.Types, *t)
+		if !p.accept("|") {
+			break
+		}
+	}
+	p.takePostComments(&un.HasComments)
+	return un, nil
 }
 
-func (p *Parser) parseEnumOption(line string) (*EnumOption, error) {
-	var err error
-	if m := reEnumOption.FindStringSubmatch(line); m != nil {
-		option := &EnumOption{}
-		option.Name = m[1]
-		if len(m[2]) > 0 {
-			val, err := strconv.ParseInt(m[2], 10, 64)
-			if err != nil {
-				return nil, errors.Wrapf(err,
-					"invalid int value [%s] for enum option", m[2])
+// error NotFound(404): NotFoundDetail
+func (p *Parser) parseError() (*ErrorType, error) {
+	tok := p.next()
+	er := &ErrorType{}
+	er.Pos = tok.pos
+	er.HasComments = p.flushComments()
+	name, err := p.expectIdent("error name")
+	if err != nil {
+		return nil, err
+	}
+	er.Name = name.text
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	status := p.next()
+	if status.kind != tokenNumber {
+		return nil, p.unexpected(status, "error status")
+	}
+	if er.Status, err = strconv.Atoi(status.text); err != nil {
+		return nil, newError(status.pos, CodeSyntax, "invalid error status [%s]", status)
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if p.accept(":") {
+		if er.Payload, err = p.parseType(); err != nil {
+			return nil, err
+		}
+	}
+	p.takePostComments(&er.HasComments)
+	return er, nil
+}
+
+func (p *Parser) parseIface() (*Iface, error) {
+	tok := p.next()
+	iface := &Iface{}
+	iface.Pos = tok.pos
+	iface.HasComments = p.flushComments()
+	name, err := p.expectIdent("interface name")
+	if err != nil {
+		return nil, err
+	}
+	iface.Name = name.text
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.takePostComments(&iface.HasComments)
+	p.parseBlock(iface.Pos, &iface.HasComments, func() error {
+		fun, err := p.parseFun()
+		if err != nil {
+			return err
+		}
+		iface.Funs = append(iface.Funs, *fun)
+		return nil
+	})
+	return iface, nil
+}
+
+func (p *Parser) parseFun() (*Fun, error) {
+	name, err := p.expectIdent("function name")
+	if err != nil {
+		return nil, err
+	}
+	fun := &Fun{}
+	fun.Name = name.text
+	fun.Pos = name.pos
+	fun.HasComments = p.flushComments()
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	p.takePostComments(&fun.HasComments)
+	fun.HeadComments = len(fun.PostComments)
+	for !p.peek().is(")") {
+		param, err := p.parseParam()
+		if err != nil {
+			return nil, err
+		}
+		fun.Params = append(fun.Params, *param)
+	}
+	p.next()
+	p.flushPostCommentsTo(&fun.HasComments)
+	if p.accept(":") {
+		if fun.Type, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if t := fun.Type; t.Group == "" && t.Name == streamName {
+			if t.Nullable || len(t.TypeArgs) > 1 {
+				return nil, newError(t.Pos, CodeSyntax, "invalid stream type, expected stream or stream<T>")
 			}
-			option.Value = &IntOrString{IntVal: &val}
+			fun.Stream = &Stream{HasPos: t.HasPos}
+			if len(t.TypeArgs) == 1 {
+				fun.Stream.Item = &t.TypeArgs[0]
+			}
+			fun.Type = nil
 		}
-		if len(m[3]) > 0 {
-			val := m[3]
-			option.Value = &IntOrString{StrVal: &val}
+	}
+	p.accept(",")
+	p.takePostComments(&fun.HasComments)
+	return fun, nil
+}
+
+func (p *Parser) parseParam() (*Param, error) {
+	name, err := p.expectIdent("param name")
+	if err != nil {
+		return nil, err
+	}
+	param := &Param{}
+	param.Name = name.text
+	param.Pos = name.pos
+	param.HasComments = p.flushComments()
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if param.Type, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if p.accept("=") {
+		if param.Default, err = p.parseLiteral(); err != nil {
+			return nil, err
 		}
-		option.HasComments, err = p.flushComments()
+	}
+	p.accept(",")
+	p.takePostComments(&param.HasComments)
+	return param, nil
+}
+
+// parseLiteral parses number, string, true/false, null, enum option or
+// array of them, e.g. `[1, 2]`
+func (p *Parser) parseLiteral() (*Literal, error) {
+	tok := p.next()
+	l := &Literal{}
+	l.Pos = tok.pos
+	switch {
+	case tok.kind == tokenNumber:
+		l.Text = tok.text
+		if strings.Contains(tok.text, ".") {
+			l.Kind = LiteralFloat
+		} else {
+			l.Kind = LiteralInt
+			if _, err := strconv.ParseInt(tok.text, 10, 64); err != nil {
+				return nil, newError(tok.pos, CodeSyntax, "invalid int value [%s]", tok)
+			}
+		}
+	case tok.kind == tokenString:
+		val, err := p.unquote(tok)
 		if err != nil {
 			return nil, err
 		}
-		return option, nil
+		l.Kind, l.Text = LiteralString, val
+	case tok.isIdent("true") || tok.isIdent("false"):
+		l.Kind, l.Text = LiteralBool, tok.text
+	case tok.isIdent("null"):
+		l.Kind = LiteralNull
+	case tok.kind == tokenIdent:
+		l.Kind, l.Text = LiteralIdent, tok.text
+	case tok.is("["):
+		l.Kind = LiteralArray
+		for !p.accept("]") {
+			item, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			l.Items = append(l.Items, *item)
+			if !p.accept(",") {
+				if _, err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	default:
+		return nil, p.unexpected(tok, "value")
 	}
-	return nil, fmt.Errorf("invalid line [%s] for enum option", line)
+	return l, nil
 }
 
-func (p *Parser) parseStructField(line string) (*StructField, error) {
-	var err error
-	if m := reStructField.FindStringSubmatch(line); m != nil {
-		field := &StructField{}
-		field.Name = m[1]
-		field.HasComments, err = p.flushComments()
+func (p *Parser) parseType() (*TypeRef, error) {
+	tok := p.next()
+	t := &TypeRef{}
+	t.Pos = tok.pos
+	switch {
+	case tok.isIdent("map") && p.peek().is("<"):
+		// map<K, V>
+		p.next()
+		keyType, itemType, err := p.parseMapTypes(",", ">")
 		if err != nil {
 			return nil, err
 		}
-		field.Type = p.parseType(m[2])
-		return field, nil
+		t.KeyType, t.ItemType = keyType, itemType
+	case tok.kind == tokenIdent:
+		t.Name = tok.text
+		if p.accept(".") {
+			// qualified name, e.g. `common.Status`
+			name, err := p.expectIdent("type name")
+			if err != nil {
+				return nil, err
+			}
+			t.Group, t.Name = tok.text, name.text
+		}
+		if p.accept("<") {
+			for {
+				arg, err := p.parseType()
+				if err != nil {
+					return nil, err
+				}
+				t.TypeArgs = append(t.TypeArgs, *arg)
+				if !p.accept(",") {
+					break
+				}
+			}
+			if _, err := p.expect(">"); err != nil {
+				return nil, err
+			}
+		}
+	case tok.is("{"):
+		// {K: V}
+		keyType, itemType, err := p.parseMapTypes(":", "}")
+		if err != nil {
+			return nil, err
+		}
+		t.KeyType, t.ItemType = keyType, itemType
+	case tok.is("["):
+		itemType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		t.ItemType = itemType
+	default:
+		return nil, p.unexpected(tok, "type")
+	}
+	t.Nullable = p.accept("?")
+	return t, nil
+}
+
+func (p *Parser) parseMapTypes(sep string, end string) (*TypeRef, *TypeRef, error) {
+	keyType, err := p.parseType()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.expect(sep); err != nil {
+		return nil, nil, err
+	}
+	itemType, err := p.parseType()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.expect(end); err != nil {
+		return nil, nil, err
 	}
-	return nil, fmt.Errorf("invalid line [%s] for struct field", line)
+	return keyType, itemType, nil
 }
 
-func (p *Parser) parseParams(s string) ([]Param, error) {
-	var params []Param
-	if s == "" {
-		return params, nil
+// parseBlock parses members of a block till the close brace, c is the
+// HasComments of the block.
+func (p *Parser) parseBlock(blockPos Pos, c *HasComments, parseMember func() error) {
+	c.HeadComments = len(c.PostComments)
+	for {
+		tok := p.peek()
+		if tok.is("}") {
+			p.next()
+			p.flushPostCommentsTo(c)
+			p.takePostComments(c)
+			return
+		}
+		if tok.kind == tokenEOF || p.atDeclInBlock() {
+			p.diags.Add(newError(blockPos, CodeSyntax,
+				"object in parsing is not finished yet, need close brace"))
+			return
+		}
+		if err := parseMember(); err != nil {
+			p.diags.Add(err)
+			p.syncBlock()
+			return
+		}
 	}
-	parts := reCommaSep.Split(s, -1)
-	var m []string
-	for _, part := range parts {
-		if part == "" {
+}
+
+// peek returns the next non-comment token without consuming it
+func (p *Parser) peek() token {
+	return p.peekN(0)
+}
+
+func (p *Parser) peekN(n int) token {
+	for i := p.cur; i < len(p.tokens); i++ {
+		if p.tokens[i].kind == tokenComment {
 			continue
 		}
-		if m = reParam.FindStringSubmatch(part); m == nil {
-			return nil, fmt.Errorf("invalid string [%s] for params", s)
+		if n == 0 {
+			return p.tokens[i]
 		}
-		param := Param{}
-		param.Name = m[1]
-		param.Type = p.parseType(m[2])
-		params = append(params, param)
+		n--
 	}
-	if len(params) > 0 {
-		c, err := p.flushComments()
-		if err != nil {
-			return nil, err
+	return p.tokens[len(p.tokens)-1]
+}
+
+// next consumes the next non-comment token, comments before it are pending
+// for the next node.
+func (p *Parser) next() token {
+	for p.tokens[p.cur].kind == tokenComment {
+		tok := p.tokens[p.cur]
+		if tok.trailing {
+			p.addPostComment(tok.text)
+		} else {
+			p.addComment(tok.text, tok.pos)
 		}
-		params[0].Comments = c.Comments
-		params[0].SemComments = c.SemComments
-		params[len(params)-1].PostComments = c.PostComments
+		p.cur++
+	}
+	tok := p.tokens[p.cur]
+	if tok.kind != tokenEOF {
+		p.cur++
 	}
-	return params, nil
+	return tok
 }
 
-func (p *Parser) parseType(s string) *TypeRef {
-	if len(s) == 0 {
-		return nil
+func (p *Parser) accept(punct string) bool {
+	if p.peek().is(punct) {
+		p.next()
+		return true
 	}
-	nullable := false
-	if len(s) > 1 && s[len(s)-1:] == "?" {
-		s = s[:len(s)-1]
-		nullable = true
+	return false
+}
+
+func (p *Parser) expect(punct string) (token, error) {
+	tok := p.next()
+	if !tok.is(punct) {
+		return tok, p.unexpected(tok, "["+punct+"]")
 	}
-	if len(s) > 2 && s[:1] == "[" && s[len(s)-1:] == "]" {
-		itemTypeStr := strings.TrimSpace(s[1 : len(s)-1])
-		arrayType := TypeRef{}
-		arrayType.ItemType = p.parseType(itemTypeStr)
-		arrayType.Nullable = nullable
-		return &arrayType
+	return tok, nil
+}
+
+func (p *Parser) expectIdent(what string) (token, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return tok, p.unexpected(tok, what)
 	}
-	singleType := TypeRef{}
-	singleType.Name = s
-	singleType.Nullable = nullable
-	return &singleType
+	return tok, nil
 }
 
-func (p *Parser) addComment(s string) {
-	if strings.HasPrefix(s, CommentSign+" ") {
-		s = strings.TrimPrefix(s, CommentSign+" ")
-	} else {
-		s = strings.TrimPrefix(s, CommentSign)
+func (p *Parser) unexpected(tok token, expected string) error {
+	return newError(tok.pos, CodeSyntax, "expected %s, found [%s]", expected, tok)
+}
+
+func (p *Parser) unquote(tok token) (string, error) {
+	s, err := strconv.Unquote(tok.text)
+	if err != nil {
+		return "", newError(tok.pos, CodeSyntax, "invalid string %s", tok)
 	}
-	p.comments = append(p.comments, s)
+	return s, nil
 }
 
-func (p *Parser) addPostComment(s string) {
+// atDecl reports whether the next tokens start a top-level declaration
+func (p *Parser) atDecl() bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && declKeywords[tok.text] &&
+		p.peekN(1).kind == tokenIdent
+}
+
+// atDeclInBlock is atDecl for tokens in blocks, where keywords may be names,
+// e.g. enum options `info` & `error`, so the declaration is also told by the
+// token after the name
+func (p *Parser) atDeclInBlock() bool {
+	if !p.atDecl() {
+		return false
+	}
+	tok, name, after := p.peek(), p.peekN(1), p.peekN(2)
+	switch tok.text {
+	case "scalar":
+		// nothing follows the name, which is on the same line
+		return name.pos.Line == tok.pos.Line && !declKeywords[name.text] &&
+			(after.kind == tokenEOF || (after.kind == tokenIdent && declKeywords[after.text]))
+	case "struct":
+		return after.is("{") || after.is("<") || after.isIdent("extends")
+	case "union":
+		return after.is("=")
+	case "error":
+		return after.is("(")
+	}
+	return after.is("{")
+}
+
+// syncDecl skips tokens till the next top-level declaration
+func (p *Parser) syncDecl() {
+	for p.peek().kind != tokenEOF && !p.atDecl() {
+		p.next()
+	}
+}
+
+// syncBlock skips tokens till the end of current block
+func (p *Parser) syncBlock() {
+	depth := 0
+	for {
+		tok := p.peek()
+		if tok.kind == tokenEOF || p.atDeclInBlock() {
+			return
+		}
+		p.next()
+		if tok.is("{") {
+			depth++
+		} else if tok.is("}") {
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// takePostComments attaches comments at the end of current line to c
+func (p *Parser) takePostComments(c *HasComments) {
+	for p.tokens[p.cur].kind == tokenComment && p.tokens[p.cur].trailing {
+		c.PostComments = append(c.PostComments, trimCommentSign(p.tokens[p.cur].text))
+		p.cur++
+	}
+}
+
+func trimCommentSign(s string) string {
 	if strings.HasPrefix(s, CommentSign+" ") {
-		s = strings.TrimPrefix(s, CommentSign+" ")
-	} else {
-		s = strings.TrimPrefix(s, CommentSign)
+		return strings.TrimPrefix(s, CommentSign+" ")
 	}
-	p.postComments = append(p.postComments, s)
+	return strings.TrimPrefix(s, CommentSign)
 }
 
-func (p *Parser) flushComments() (HasComments, error) {
+func (p *Parser) addComment(s string, pos Pos) {
+	p.comments = append(p.comments, trimCommentSign(s))
+	p.commentsPos = append(p.commentsPos, pos)
+}
+
+func (p *Parser) addPostComment(s string) {
+	p.postComments = append(p.postComments, trimCommentSign(s))
+}
+
+// flushComments returns pending comments, invalid semantic comments are
+// reported to diagnostics.
+func (p *Parser) flushComments() HasComments {
 	c := HasComments{}
+	comments, commentsPos, postComments := p.comments, p.commentsPos, p.postComments
+	p.comments = nil
+	p.commentsPos = nil
+	p.postComments = nil
+
 	var semKey string
+	var semPos Pos
 	var semVal string
 	var semFmt string
 	var mlMode bool
@@ -393,16 +880,18 @@ func (p *Parser) flushComments() (HasComments, error) {
 			val = semVal
 		}
 		if err != nil {
-			return errors.Wrapf(err, "invalid %s string: [%s]", semFmt, semVal)
+			err = newError(semPos, CodeSemComment,
+				"invalid %s string: [%s]: %v", semFmt, semVal, err)
+		} else {
+			c.AddSemComment(semKey, val)
 		}
-		c.AddSemComment(semKey, val)
 		semKey = ""
 		semVal = ""
 		semFmt = ""
 		mlMode = false
-		return nil
+		return err
 	}
-	for _, line := range p.comments {
+	for i, line := range comments {
 		if mlMode {
 			var matched bool
 			if strings.HasPrefix(line, "  ") {
@@ -419,38 +908,34 @@ func (p *Parser) flushComments() (HasComments, error) {
 				semVal += line
 				continue
 			}
-			if err := semCommit(); err != nil {
-				return HasComments{}, err
-			}
+			p.diags.Add(semCommit())
 		}
 		if m := reSemComment.FindStringSubmatch(line); m != nil {
 			semKey = m[1]
+			semPos = commentsPos[i]
 			semFmt = m[2]
 			mlMode = len(m[3]) > 0
 			if mlMode {
 				continue
 			}
 			semVal = m[4]
-			if err := semCommit(); err != nil {
-				return HasComments{}, err
-			}
+			p.diags.Add(semCommit())
 			continue
 		}
 		c.Comments = append(c.Comments, line)
 	}
-	if err := semCommit(); err != nil {
-		return HasComments{}, err
-	}
-	c.PostComments = p.postComments
-	p.comments = nil
-	p.postComments = nil
-	return c, nil
+	p.diags.Add(semCommit())
+	c.PostComments = postComments
+	c.Lines = comments
+	return c
 }
 
 func (p *Parser) flushPostCommentsTo(c *HasComments) {
+	c.DanglingComments += len(p.comments)
 	c.PostComments = append(c.PostComments, p.comments...)
 	c.PostComments = append(c.PostComments, p.postComments...)
 	p.comments = nil
+	p.commentsPos = nil
 	p.postComments = nil
 }
 
@@ -325,6 +325,176 @@ func TestParseEnum(t *testing.T) {
 	}
 }
 
+func TestParseEnumKeywords(t *testing.T) {
+	parser := Parser{}
+	schema, err := parser.Parse(`group levels
+
+enum Level {
+  info
+  error
+  fatal
+}
+
+enum Kind {
+  scalar
+  struct
+  union
+  enum
+  interface
+}
+
+scalar Time
+`)
+	assert.NoError(t, err)
+	g := schema.Groups[0]
+	var options []string
+	for _, en := range g.EnumTypes {
+		for _, o := range en.Options {
+			options = append(options, o.Name)
+		}
+	}
+	assert.Equal(t, []string{"info", "error", "fatal", "scalar", "struct", "union", "enum", "interface"}, options)
+	assert.Equal(t, "Time", g.ScalarTypes[0].Name)
+}
+
+func TestParseDiagnostic(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct T1 {
+  f1: int
+  f2 string
+}
+`
+	_, err := parser.parse("t1.api", t1)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, Pos{File: "t1.api", Line: 5, Column: 6}, ds[0].Pos)
+	assert.Equal(t, CodeSyntax, ds[0].Code)
+	assert.Equal(t, "t1.api:5:6: error: expected [:], found [string] [syntax]", ds[0].Error())
+
+	t2 := `group t2
+
+# @a:json {"a":
+struct T2 {
+}
+`
+	_, err = parser.parse("t2.api", t2)
+	assert.ErrorAs(t, err, &ds)
+	assert.Equal(t, 1, len(ds))
+	assert.Equal(t, Pos{File: "t2.api", Line: 3, Column: 1}, ds[0].Pos)
+	assert.Equal(t, CodeSemComment, ds[0].Code)
+
+	t3 := `group t3
+
+interface T3 {
+	f1(p1: int,
+	   p2: [ string ]): int
+}
+`
+	schema, err := parser.parse("t3.api", t3)
+	assert.NoError(t, err)
+	fun := schema.Groups[0].Ifaces[0].Funs[0]
+	assert.Equal(t, Pos{File: "t3.api", Line: 4, Column: 2}, fun.Pos)
+	assert.Equal(t, Pos{File: "t3.api", Line: 5, Column: 5}, fun.Params[1].Pos)
+	assert.Equal(t, Pos{File: "t3.api", Line: 5, Column: 9}, fun.Params[1].Type.Pos)
+	assert.Equal(t, Pos{File: "t3.api", Line: 5, Column: 11}, fun.Params[1].Type.ItemType.Pos)
+}
+
+func TestParseLayout(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct A { a1: int }
+
+struct B
+{
+	b1: int, b2: [ string ]?
+	b3: A b4: [[A?]] # b4
+}
+
+enum E { E1 = 1, E2 = 2 }
+
+interface I { f1(a: A): B f2(
+	# b
+	b: B): [ A ]
+}
+`
+	schema, err := parser.Parse(t1)
+	assert.NoError(t, err)
+
+	g := schema.Groups[0]
+	assert.Equal(t, "a1", g.StructTypes[0].Fields[0].Name)
+	b := g.StructTypes[1]
+	assert.Equal(t, 4, len(b.Fields))
+	assert.Equal(t, "string", b.Fields[1].Type.ItemType.Name)
+	assert.True(t, b.Fields[1].Type.Nullable)
+	assert.Equal(t, "A", b.Fields[2].Type.Name)
+	assert.Equal(t, "A", b.Fields[3].Type.ItemType.ItemType.Name)
+	assert.True(t, b.Fields[3].Type.ItemType.ItemType.Nullable)
+	assert.Equal(t, []string{"b4"}, b.Fields[3].PostComments)
+	assert.Nil(t, b.Fields[2].PostComments)
+
+	assert.Equal(t, 2, len(g.EnumTypes[0].Options))
+	assert.Equal(t, int64(2), *g.EnumTypes[0].Options[1].Value.IntVal)
+
+	funs := g.Ifaces[0].Funs
+	assert.Equal(t, 2, len(funs))
+	assert.Equal(t, "B", funs[0].Type.Name)
+	assert.Equal(t, []string{"b"}, funs[1].Params[0].Comments)
+	assert.Equal(t, "A", funs[1].Type.ItemType.Name)
+}
+
+func TestParseRecover(t *testing.T) {
+	parser := Parser{}
+
+	t1 := `group t1
+
+struct A {
+  a1: int
+  a2 string
+  a3: string
+}
+
+struct B {
+  b1: A
+
+interface I {
+  f1(a: A): B
+  f2 a: A
+  f3(b: B)
+}
+
+enum E {
+  E1 = x
+}
+`
+	schema, err := parser.parse("t1.api", t1)
+	var ds Diagnostics
+	assert.ErrorAs(t, err, &ds)
+
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	assert.Equal(t, []string{
+		"t1.api:5:6: error: expected [:], found [string] [syntax]",
+		"t1.api:9:1: error: object in parsing is not finished yet, need close brace [syntax]",
+		"t1.api:14:6: error: expected [(], found [a] [syntax]",
+		"t1.api:19:8: error: expected enum option value, found [x] [syntax]",
+	}, lines)
+
+	// broken blocks are kept with what parsed
+	g := schema.Groups[0]
+	assert.Equal(t, 1, len(g.StructTypes[0].Fields))
+	assert.Equal(t, 1, len(g.StructTypes[1].Fields))
+	assert.Equal(t, 1, len(g.Ifaces[0].Funs))
+	assert.Equal(t, 1, len(g.EnumTypes))
+}
+
 func dump(o interface{}) string {
 	b, _ := json.MarshalIndent(o, "", "  ")
 	return string(b)
@@ -0,0 +1,149 @@
+package api1
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// QualName returns the qualified name of type, e.g. `common.Status`, or the
+// plain name for builtin (or unresolved) types.
+func (t *TypeRef) QualName() string {
+	return qualName(t.Group, t.Name)
+}
+
+func qualName(group string, name string) string {
+	if group == "" {
+		return name
+	}
+	return group + "." + name
+}
+
+// FindErrorType returns the error referenced by t (qualified by Schema.Check),
+// e.g. one in Fun.Throws
+func (s *Schema) FindErrorType(t *TypeRef) (*ErrorType, bool) {
+	for i := range s.Groups {
+		g := &s.Groups[i]
+		if g.Name != t.Group {
+			continue
+		}
+		for j := range g.ErrorTypes {
+			if g.ErrorTypes[j].Name == t.Name {
+				return &g.ErrorTypes[j], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func hasGroup(schema *Schema, name string) bool {
+	for _, g := range schema.Groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// imports returns whether group g imports the named group
+func imports(g *ApiGroup, name string) bool {
+	for _, imp := range g.Imports {
+		if imp.Group == name {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleGroups returns names of groups whose types can be referenced in g,
+// which are g & the imported groups, or all the groups if g imports nothing.
+func visibleGroups(schema *Schema, g *ApiGroup) []string {
+	m := map[string]bool{g.Name: true}
+	if len(g.Imports) == 0 {
+		for _, other := range schema.Groups {
+			m[other.Name] = true
+		}
+	} else {
+		for _, imp := range g.Imports {
+			if imp.Group != "" {
+				m[imp.Group] = true
+			}
+		}
+	}
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasImports returns whether any group imports others, in which case groups
+// are separated namespaces.
+func (s *Schema) HasImports() bool {
+	for _, g := range s.Groups {
+		if len(g.Imports) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveImports resolves import paths to the groups defined in imported files.
+func resolveImports(schema *Schema, report reportFunc) {
+	for i := range schema.Groups {
+		g := &schema.Groups[i]
+		var imported []named
+		for j := range g.Imports {
+			imp := &g.Imports[j]
+			matched := matchImport(schema, g.Pos.File, imp.Path)
+			var files []string
+			for file := range matched {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+			switch len(files) {
+			case 0:
+				report(imp.Pos, CodeImport, "Import [%s] cannot be resolved", imp.Path)
+			case 1:
+				imp.Group = matched[files[0]]
+				imported = append(imported, named{HasName{imp.Group}, imp.HasPos})
+			default:
+				report(imp.Pos, CodeImport, "Import [%s] is ambiguous, found [%s]",
+					imp.Path, strings.Join(files, ", "))
+			}
+		}
+		for _, d := range duplicated(imported) {
+			report(d.Pos, CodeDuplicated, "Group [%s] is imported more than once", d.Name)
+		}
+	}
+}
+
+// matchImport returns files (with the group defined) matching the import path,
+// which is relative to the importing file, or to any parent directory of the
+// imported file, e.g. `common/types.api` matches `api/common/types.api`.
+func matchImport(schema *Schema, from string, path string) map[string]string {
+	matched := make(map[string]string)
+	if from != "" {
+		rel := filepath.Join(filepath.Dir(from), path)
+		for _, g := range schema.Groups {
+			if g.Pos.File != "" && filepath.Clean(g.Pos.File) == rel {
+				matched[filepath.Clean(g.Pos.File)] = g.Name
+			}
+		}
+		if len(matched) > 0 {
+			return matched
+		}
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, g := range schema.Groups {
+		if g.Pos.File == "" {
+			continue
+		}
+		file := filepath.ToSlash(filepath.Clean(g.Pos.File))
+		if file == path || strings.HasSuffix(file, "/"+path) {
+			matched[filepath.Clean(g.Pos.File)] = g.Name
+		}
+	}
+	return matched
+}
@@ -2,6 +2,7 @@ package api1
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/jinzhenj/api1/pkg/utils"
@@ -63,11 +64,39 @@ const (
 	PositionQuery  Position = "query"
 	PositionHeader Position = "header"
 	PositionCookie Position = "cookie"
+	// field of the form body
+	PositionForm Position = "form"
 )
 
+// content types of form bodies & streams
+const (
+	MimeFormData       = "multipart/form-data"
+	MimeFormUrlencoded = "application/x-www-form-urlencoded"
+	MimeEventStream    = "text/event-stream"
+	MimeOctetStream    = "application/octet-stream"
+	mimeJSON           = "application/json"
+)
+
+// name of the stream type, e.g. `stream<Event>`
+const streamName = "stream"
+
+// GetFormType returns the content type of form body specified by `@accept`
+// of fun, empty if the body is not a form
+func GetFormType(fun *Fun) string {
+	accept, _ := fun.SemComments["accept"].(string)
+	switch accept = strings.ToLower(strings.TrimSpace(accept)); accept {
+	case MimeFormData, MimeFormUrlencoded:
+		return accept
+	}
+	return ""
+}
+
 type RouteParam struct {
 	Param
 	In Position `json:"in"`
+	// WireName is the name of param in the request, e.g. `X-Request-Id` of
+	// header, which defaults to the param name
+	WireName string `json:"wireName"`
 }
 
 type TypeKind string
@@ -76,27 +105,53 @@ const (
 	TypeKindScalar TypeKind = "scalar"
 	TypeKindEnum   TypeKind = "enum"
 	TypeKindStruct TypeKind = "struct"
+	TypeKindUnion  TypeKind = "union"
 )
 
 type RouteParser struct {
-	typeInBody map[string]bool
+	// positions of types specified by `@route.in`, or body for structs,
+	// keyed by both qualified names & plain names
+	typeIn map[string]Position
+	// structs with `@form`, which are the whole form bodies, keyed as typeIn
+	formTypes map[string]bool
 }
 
 func (p *RouteParser) LoadSchema(schema *Schema) {
-	p.typeInBody = make(map[string]bool)
+	p.typeIn = make(map[string]Position)
+	p.formTypes = make(map[string]bool)
 	if schema == nil {
 		return
 	}
+	add := func(group string, name string, in Position) {
+		p.typeIn[qualName(group, name)] = in
+		if _, ok := p.typeIn[name]; !ok {
+			p.typeIn[name] = in
+		}
+	}
 	for _, g := range schema.Groups {
 		for _, sc := range g.ScalarTypes {
-			position, ok := sc.SemComments["route.in"].(string)
-			p.typeInBody[sc.Name] = (ok && position == "body")
+			position, _ := sc.SemComments["route.in"].(string)
+			switch Position(position) {
+			case PositionBody, PositionHeader, PositionCookie:
+				add(g.Name, sc.Name, Position(position))
+			default:
+				add(g.Name, sc.Name, "")
+			}
 		}
 		for _, en := range g.EnumTypes {
-			p.typeInBody[en.Name] = false
+			add(g.Name, en.Name, "")
 		}
 		for _, st := range g.StructTypes {
-			p.typeInBody[st.Name] = true
+			add(g.Name, st.Name, PositionBody)
+			if _, ok := st.SemComments["form"]; ok {
+				p.formTypes[qualName(g.Name, st.Name)] = true
+				if _, ok := p.formTypes[st.Name]; !ok {
+					p.formTypes[st.Name] = true
+				}
+			}
+		}
+		for _, un := range g.UnionTypes {
+			add(g.Name, un.Name, PositionBody)
 		}
 	}
 }
@@ -115,8 +170,52 @@ func (p *RouteParser) IsBodyParam(t *TypeRef) bool {
 		t.Name == "string" || t.Name == "boolean" {
 		return false
 	}
-	inBody, ok := p.typeInBody[t.Name]
-	return ok && inBody
+	return p.typePosition(t) == PositionBody
+}
+
+// typePosition returns the position of named type t, empty if unspecified
+func (p *RouteParser) typePosition(t *TypeRef) Position {
+	if p == nil || t.Name == "" {
+		return ""
+	}
+	return p.typeIn[t.QualName()]
+}
+
+// paramIn returns the position & the wire name specified by `@in` (or
+// `@route.in`) of param, e.g. `@in header X-Request-Id`, or by `@route.in`
+// of the scalar type, empty position if not specified
+func (p *RouteParser) paramIn(iface *Iface, fun *Fun, param *Param) (Position, string, error) {
+	v, ok := param.SemComments["in"]
+	if !ok {
+		v, ok = param.SemComments["route.in"]
+	}
+	if !ok {
+		if param.Type != nil {
+			if in := p.typePosition(param.Type); in == PositionHeader || in == PositionCookie {
+				return in, param.Name, nil
+			}
+		}
+		return "", param.Name, nil
+	}
+	fields := strings.Fields(fmt.Sprint(v))
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", "", newError(param.Pos, CodeRoute,
+			"Function [%s.%s] param [%s] has invalid position [%v]",
+			iface.Name, fun.Name, param.Name, v)
+	}
+	position := Position(fields[0])
+	switch position {
+	case PositionHeader, PositionCookie, PositionQuery:
+	default:
+		return "", "", newError(param.Pos, CodeRoute,
+			"Function [%s.%s] param [%s] position [%s] should be one of [%s, %s, %s]",
+			iface.Name, fun.Name, param.Name, position, PositionHeader, PositionCookie, PositionQuery)
+	}
+	wireName := param.Name
+	if len(fields) == 2 {
+		wireName = fields[1]
+	}
+	return position, wireName, nil
 }
 
 func (p *RouteParser) ParseParams(iface *Iface, fun *Fun, pathParams []string) ([]RouteParam, error) {
@@ -126,29 +225,58 @@ func (p *RouteParser) ParseParams(iface *Iface, fun *Fun, pathParams []string) (
 		paramInPath[pathParam] = true
 	}
 
+	formType := GetFormType(fun)
 	var params []RouteParam
 	var bodyParams []string
+	var formParams []string
 	for _, param := range fun.Params {
-		position := PositionQuery
-		if paramInPath[param.Name] {
+		position, wireName, err := p.paramIn(iface, fun, &param)
+		if err != nil {
+			return nil, err
+		}
+		if position != "" {
+			if paramInPath[param.Name] {
+				return nil, newError(param.Pos, CodeRoute,
+					"Function [%s.%s] path param [%s] cannot be in %s",
+					iface.Name, fun.Name, param.Name, position)
+			}
+			if p.IsBodyParam(param.Type) {
+				return nil, newError(param.Pos, CodeRoute,
+					"Function [%s.%s] param [%s] of body type cannot be in %s",
+					iface.Name, fun.Name, param.Name, position)
+			}
+		} else if paramInPath[param.Name] {
 			position = PositionPath
 			delete(paramInPath, param.Name)
+		} else if formType != "" && !p.formTypes[param.Type.QualName()] {
+			// structs without `@form` are json fields of the form
+			position = PositionForm
+			formParams = append(formParams, param.Name)
 		} else if p.IsBodyParam(param.Type) {
 			position = PositionBody
 			bodyParams = append(bodyParams, param.Name)
+		} else {
+			position = PositionQuery
 		}
 
 		// TODO: check path param is simple type
 
 		if position == PositionPath && param.Type.Nullable {
-			return nil, errors.Errorf(
+			return nil, newError(param.Pos, CodeRoute,
 				"Function [%s.%s] has nullable path param [%s]",
 				iface.Name, fun.Name, param.Name)
 		}
+		// path params are always present if the route matches
+		if position == PositionPath && param.Default != nil {
+			return nil, newError(param.Pos, CodeRoute,
+				"Function [%s.%s] path param [%s] cannot have default value",
+				iface.Name, fun.Name, param.Name)
+		}
 
 		params = append(params, RouteParam{
-			Param: param,
-			In:    position,
+			Param:    param,
+			In:       position,
+			WireName: wireName,
 		})
 	}
 
@@ -157,26 +285,82 @@ func (p *RouteParser) ParseParams(iface *Iface, fun *Fun, pathParams []string) (
 		for key := range paramInPath {
 			keys = append(keys, key)
 		}
-		return nil, errors.Errorf(
+		sort.Strings(keys)
+		return nil, newError(fun.Pos, CodeRoute,
 			"Function [%s.%s] has undefined path params [%s]",
 			iface.Name, fun.Name, strings.Join(keys, ", "))
 	}
 	if len(bodyParams) > 1 {
-		return nil, errors.Errorf(
+		return nil, newError(fun.Pos, CodeRoute,
 			"Function [%s.%s] has more than one bodyParams [%s]",
 			iface.Name, fun.Name, strings.Join(bodyParams, ", "))
 	}
+	if len(bodyParams) > 0 && len(formParams) > 0 {
+		return nil, newError(fun.Pos, CodeRoute,
+			"Function [%s.%s] has both bodyParam [%s] and form params [%s]",
+			iface.Name, fun.Name, bodyParams[0], strings.Join(formParams, ", "))
+	}
 
 	return params, nil
 }
 
+// ResponseStyle is how the returned value of route is responded
+type ResponseStyle string
+
+const (
+	// the value as json
+	ResponseJSON ResponseStyle = "json"
+	// the value wrapped in `{"code": 0, "data": ..., "message": "ok"}`
+	ResponseEnvelope ResponseStyle = "envelope"
+)
+
+// GetResponseStyle returns style specified by `@response` of fun, or of
+// group if fun doesn't specify, ResponseJSON by default
+func GetResponseStyle(g *ApiGroup, fun *Fun) (ResponseStyle, error) {
+	for _, c := range []*HasComments{&fun.HasComments, &g.HasComments} {
+		v, ok := c.SemComments["response"]
+		if !ok {
+			continue
+		}
+		switch style := ResponseStyle(fmt.Sprint(v)); style {
+		case ResponseJSON, ResponseEnvelope:
+			return style, nil
+		default:
+			pos := g.Pos
+			if c == &fun.HasComments {
+				pos = fun.Pos
+			}
+			return "", newError(pos, CodeRoute, "Response style [%s] should be one of [%s, %s]",
+				style, ResponseJSON, ResponseEnvelope)
+		}
+	}
+	return ResponseJSON, nil
+}
+
 type RouteInfo struct {
 	Method   string              `json:"method"`
 	Path     string              `json:"path"`
 	ParamsIn map[string]Position `json:"paramsIn"`
+	// ResponseEnvelope if the returned value is wrapped
+	Response ResponseStyle `json:"response"`
+	// names of params in the request, which differ from the param names,
+	// e.g. `X-Request-Id` of header
+	WireNames map[string]string `json:"wireNames,omitempty"`
+	// content type of the form body, e.g. `multipart/form-data`, empty if
+	// the body is not a form
+	FormType string `json:"formType,omitempty"`
+}
+
+// WireName returns the name of param in the request
+func (r *RouteInfo) WireName(param string) string {
+	if name, ok := r.WireNames[param]; ok {
+		return name
+	}
+	return param
 }
 
 func (s *Schema) SupplyRouteInfo() error {
+	var diags Diagnostics
 	rParser := &RouteParser{}
 	rParser.LoadSchema(s)
 
@@ -186,20 +370,35 @@ func (s *Schema) SupplyRouteInfo() error {
 				if route, ok := iface.Funs[i].SemComments["route"].(string); ok {
 					method, path, pathParams, err := ParseRoute(route, PathStyleColon)
 					if err != nil {
-						return err
+						diags.Add(newError(iface.Funs[i].Pos, CodeRoute, "%v", err))
+						continue
 					}
 					params, err := rParser.ParseParams(&iface, &iface.Funs[i], pathParams)
 					if err != nil {
-						return err
+						diags.Add(err)
+						continue
+					}
+					style, err := GetResponseStyle(&g, &iface.Funs[i])
+					if err != nil {
+						diags.Add(err)
+						continue
 					}
 					paramsIn := make(map[string]Position)
+					var wireNames map[string]string
 					for _, param := range params {
 						paramsIn[param.Name] = param.In
+						if param.WireName != param.Name {
+							if wireNames == nil {
+								wireNames = make(map[string]string)
+							}
+							wireNames[param.Name] = param.WireName
+						}
 					}
-					iface.Funs[i].Route = &RouteInfo{method, path, paramsIn}
+					iface.Funs[i].Route = &RouteInfo{method, path, paramsIn, style, wireNames,
+						GetFormType(&iface.Funs[i])}
 				}
 			}
 		}
 	}
-	return nil
+	return diags.Err()
 }
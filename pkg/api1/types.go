@@ -8,18 +8,59 @@ type HasComments struct {
 	Comments     []string               `json:"comments,omitempty"`
 	PostComments []string               `json:"postComments,omitempty"`
 	SemComments  map[string]interface{} `json:"semComments,omitempty"`
+	// layout of comments in the source, which is kept for formatting:
+	// Lines are the comments before the node as written (without comment
+	// signs), including semantic comments, HeadComments is the number of
+	// PostComments at the end of the first line of blocks, functions & groups,
+	// which are followed by DanglingComments on their own lines before the
+	// close brace or paren (or at the end of file), the rest are at the end
+	// of the node
+	Lines            []string `json:"-"`
+	HeadComments     int      `json:"-"`
+	DanglingComments int      `json:"-"`
 }
 
-// Name & ItemType cannot be both set
+// Name & ItemType cannot be both set,
+// KeyType is set only for map types (ItemType is the value type),
+// TypeArgs is set only for instantiated generic structs, e.g. `Page<User>`,
+// Group is set for qualified names, e.g. `common.Status`, and is filled by
+// Schema.Check for all the resolved (non-builtin) types
 type TypeRef struct {
 	HasName
-	ItemType *TypeRef `json:"itemType,omitempty"`
-	Nullable bool     `json:"nullable"`
+	HasPos
+	Group    string    `json:"group,omitempty"`
+	TypeArgs []TypeRef `json:"typeArgs,omitempty"`
+	KeyType  *TypeRef  `json:"keyType,omitempty"`
+	ItemType *TypeRef  `json:"itemType,omitempty"`
+	Nullable bool      `json:"nullable"`
+}
+
+type LiteralKind string
+
+const (
+	LiteralInt    LiteralKind = "int"
+	LiteralFloat  LiteralKind = "float"
+	LiteralString LiteralKind = "string"
+	LiteralBool   LiteralKind = "bool"
+	LiteralNull   LiteralKind = "null"
+	// enum option, e.g. `Admin` in `role: Role = Admin`
+	LiteralIdent LiteralKind = "ident"
+	LiteralArray LiteralKind = "array"
+)
+
+// Literal is a constant value, e.g. default value of field & param,
+// Text is the number, unquoted string, `true`/`false` or the option name.
+type Literal struct {
+	HasPos
+	Kind  LiteralKind `json:"kind"`
+	Text  string      `json:"text,omitempty"`
+	Items []Literal   `json:"items,omitempty"`
 }
 
 type ScalarType struct {
 	HasName
 	HasComments
+	HasPos
 }
 
 type IntOrString struct {
@@ -30,54 +71,116 @@ type IntOrString struct {
 type EnumOption struct {
 	HasName
 	HasComments
+	HasPos
 	Value *IntOrString `json:"value,omitempty"`
 }
 
 type EnumType struct {
 	HasName
 	HasComments
+	HasPos
 	Options []EnumOption `json:"options"`
 }
 
 type StructField struct {
 	HasName
 	HasComments
-	Type *TypeRef `json:"type"`
+	HasPos
+	Type    *TypeRef `json:"type"`
+	Default *Literal `json:"default,omitempty"`
 }
 
 type StructType struct {
 	HasName
 	HasComments
-	Fields []StructField `json:"fields"`
+	HasPos
+	// type params of generic struct, e.g. `T` in `struct Page<T>`
+	TypeParams []string `json:"typeParams,omitempty"`
+	// parent structs, whose fields are inherited
+	Extends []TypeRef     `json:"extends,omitempty"`
+	Fields  []StructField `json:"fields"`
+}
+
+// UnionType is one of the struct Types, which can be told apart by the
+// `@discriminator` field
+type UnionType struct {
+	HasName
+	HasComments
+	HasPos
+	Types []TypeRef `json:"types"`
 }
 
 type Param struct {
 	HasName
 	HasComments
-	Type *TypeRef `json:"type"`
+	HasPos
+	Type    *TypeRef `json:"type"`
+	Default *Literal `json:"default,omitempty"`
 }
 
 type Fun struct {
 	HasName
 	HasComments
+	HasPos
 	Params []Param  `json:"params"`
 	Type   *TypeRef `json:"type"`
+	// Stream is returned instead of Type, e.g. `stream<Event>`
+	Stream *Stream `json:"stream,omitempty"`
 	// post-processed field, not parsed
 	Route *RouteInfo `json:"route,omitempty"`
+	// post-processed field, errors declared by `@throws`, which are resolved
+	// and qualified by Schema.Check
+	Throws []TypeRef `json:"throws,omitempty"`
+}
+
+// Stream is a stream of events (of json) or bytes, e.g. `stream<Event>` or
+// `stream`
+type Stream struct {
+	HasPos
+	// Item is the type of events, nil for the stream of bytes
+	Item *TypeRef `json:"item,omitempty"`
+	// post-processed field, content type specified by `@produces`, which
+	// defaults to text/event-stream for events, and application/octet-stream
+	// for bytes
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// ErrorType is an error responded with the HTTP Status, and the Payload
+// struct (optional) as data, e.g. `error NotFound(404): NotFoundDetail`
+type ErrorType struct {
+	HasName
+	HasComments
+	HasPos
+	Status  int      `json:"status"`
+	Payload *TypeRef `json:"payload,omitempty"`
 }
 
 type Iface struct {
 	HasName
 	HasComments
+	HasPos
 	Funs []Fun `json:"funs"`
 }
 
+// Import makes types of another group visible, e.g. `import "common/types.api"`
+type Import struct {
+	HasComments
+	HasPos
+	Path string `json:"path"`
+	// post-processed field, resolved by Schema.Check
+	Group string `json:"group,omitempty"`
+}
+
 type ApiGroup struct {
 	HasName
 	HasComments
+	HasPos
+	Imports     []Import     `json:"imports,omitempty"`
 	ScalarTypes []ScalarType `json:"scalarTypes"`
 	EnumTypes   []EnumType   `json:"enumTypes"`
 	StructTypes []StructType `json:"structTypes"`
+	UnionTypes  []UnionType  `json:"unionTypes,omitempty"`
+	ErrorTypes  []ErrorType  `json:"errorTypes,omitempty"`
 	Ifaces      []Iface      `json:"ifaces"`
 }
 
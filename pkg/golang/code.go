@@ -3,6 +3,7 @@ package golang
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jinzhenj/api1/pkg/utils"
@@ -104,6 +105,13 @@ func (t *GoType) Code() string {
 	var code string
 	if len(t.Name) > 0 {
 		code = t.Name
+		if len(t.TypeArgs) > 0 {
+			var args []string
+			for _, arg := range t.TypeArgs {
+				args = append(args, arg.Code())
+			}
+			code += sprintf("[%s]", strings.Join(args, ", "))
+		}
 	} else if t.KeyType == nil {
 		code = "[]" + t.ItemType.Code()
 	} else {
@@ -132,17 +140,196 @@ func CodeTags(tags map[string]string) string {
 	var a []string
 	for _, k := range keys {
 		v := tags[k]
-		a = append(a, sprintf("%s:\"%s\"", k, v))
+		a = append(a, sprintf("%s:%s", k, strconv.Quote(v)))
+	}
+	code := strings.Join(a, " ")
+	if strings.Contains(code, "`") {
+		// e.g. a pattern containing backquotes
+		return strconv.Quote(code)
 	}
-	return sprintf("`%s`", strings.Join(a, " "))
+	return sprintf("`%s`", code)
 }
 
 func (s *GoStructType) Code() string {
 	code := ""
 	code += CodeComments(s.Comments)
-	code += sprintf("type %s struct {\n", s.Name)
+	if len(s.TypeParams) > 0 {
+		code += sprintf("type %s[%s any] struct {\n", s.Name, strings.Join(s.TypeParams, ", "))
+	} else {
+		code += sprintf("type %s struct {\n", s.Name)
+	}
+	for _, embed := range s.Embeds {
+		code += indent(embed.Code() + "\n")
+	}
 	code += indent(CodeStructFields(s.Fields))
 	code += "}\n"
+	if len(s.Defaults) > 0 || len(s.DefaultStructs) > 0 {
+		receiver := s.Name
+		if len(s.TypeParams) > 0 {
+			receiver += sprintf("[%s]", strings.Join(s.TypeParams, ", "))
+		}
+		code += "\n"
+		code += "// SetDefaults sets fields to their default values\n"
+		code += sprintf("func (s *%s) SetDefaults() {\n", receiver)
+		for _, name := range s.DefaultStructs {
+			code += indent(sprintf("s.%s.SetDefaults()\n", name))
+		}
+		for _, d := range s.Defaults {
+			code += indent(d.Code())
+		}
+		code += "}\n"
+	}
+	return code
+}
+
+func (d *GoDefault) Code() string {
+	return sprintf("%s = %s\n", d.Target, d.Value)
+}
+
+func (u *GoUnionType) Code() string {
+	valueType := u.Name + "Value"
+	marker := "is" + u.Name
+
+	code := ""
+	code += CodeComments(u.Comments)
+	code += sprintf("type %s struct {\n", u.Name)
+	code += indent(sprintf("Value %s\n", valueType))
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("// %s is one of %s\n", valueType, strings.Join(u.Members, ", "))
+	code += sprintf("type %s interface {\n", valueType)
+	code += indent(sprintf("%s()\n", marker))
+	code += "}\n"
+	for _, m := range u.Members {
+		code += "\n"
+		code += sprintf("func (%s) %s() {}\n", m, marker)
+	}
+
+	// marshal
+	code += "\n"
+	code += sprintf("func (u %s) MarshalJSON() ([]byte, error) {\n", u.Name)
+	if u.Discriminator != "" {
+		block := "switch v := u.Value.(type) {\n"
+		block += "case nil:\n"
+		block += indent("return []byte(\"null\"), nil\n")
+		for _, m := range u.Members {
+			block += sprintf("case %s:\n", m)
+			block += indent(sprintf("v.%s = \"%s\"\n", u.DiscriminatorField, m))
+			block += indent("return json.Marshal(v)\n")
+			block += sprintf("case *%s:\n", m)
+			block += indent("_v := *v\n")
+			block += indent(sprintf("_v.%s = \"%s\"\n", u.DiscriminatorField, m))
+			block += indent("return json.Marshal(_v)\n")
+		}
+		block += "}\n"
+		code += indent(block)
+	} else {
+		block := "if u.Value == nil {\n"
+		block += indent("return []byte(\"null\"), nil\n")
+		block += "}\n"
+		code += indent(block)
+	}
+	code += indent("return json.Marshal(u.Value)\n")
+	code += "}\n"
+
+	// unmarshal
+	code += "\n"
+	code += sprintf("func (u *%s) UnmarshalJSON(data []byte) error {\n", u.Name)
+	block := "if string(data) == \"null\" {\n"
+	block += indent("u.Value = nil\n")
+	block += indent("return nil\n")
+	block += "}\n"
+	if u.Discriminator != "" {
+		block += "var _d struct {\n"
+		block += indent(sprintf("%s string `json:\"%s\"`\n", u.DiscriminatorField, u.Discriminator))
+		block += "}\n"
+		block += "if err := json.Unmarshal(data, &_d); err != nil {\n"
+		block += indent("return err\n")
+		block += "}\n"
+		block += sprintf("switch _d.%s {\n", u.DiscriminatorField)
+		for _, m := range u.Members {
+			block += sprintf("case \"%s\":\n", m)
+			c := sprintf("var v %s\n", m)
+			c += "if err := json.Unmarshal(data, &v); err != nil {\n"
+			c += indent("return err\n")
+			c += "}\n"
+			c += "u.Value = v\n"
+			block += indent(c)
+		}
+		block += "default:\n"
+		block += indent(sprintf("return fmt.Errorf(\"invalid %s [%%s] for %s\", _d.%s)\n",
+			u.Discriminator, u.Name, u.DiscriminatorField))
+		block += "}\n"
+		block += "return nil\n"
+	} else {
+		// try members one by one, the first one matched wins
+		for _, m := range u.Members {
+			block += "{\n"
+			c := sprintf("var v %s\n", m)
+			c += "dec := json.NewDecoder(bytes.NewReader(data))\n"
+			c += "dec.DisallowUnknownFields()\n"
+			c += "if err := dec.Decode(&v); err == nil {\n"
+			c += indent("u.Value = v\n")
+			c += indent("return nil\n")
+			c += "}\n"
+			block += indent(c)
+			block += "}\n"
+		}
+		block += sprintf("return fmt.Errorf(\"invalid value for %s: %%s\", data)\n", u.Name)
+	}
+	code += indent(block)
+	code += "}\n"
+	return code
+}
+
+func (e *GoErrorType) Code() string {
+	code := ""
+	code += CodeComments(e.Comments)
+	code += sprintf("type %s struct {\n", e.TypeName)
+	code += indent("Message string\n")
+	if e.DataType != nil {
+		code += indent(sprintf("Data    %s\n", e.DataType.Code()))
+	}
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("func New%s(message string", e.TypeName)
+	if e.DataType != nil {
+		code += sprintf(", data %s) *%s {\n", e.DataType.Code(), e.TypeName)
+		code += indent(sprintf("return &%s{Message: message, Data: data}\n", e.TypeName))
+	} else {
+		code += sprintf(") *%s {\n", e.TypeName)
+		code += indent(sprintf("return &%s{Message: message}\n", e.TypeName))
+	}
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("func (e *%s) Error() string {\n", e.TypeName)
+	code += indent("if e.Message == \"\" {\n")
+	code += indent(indent(sprintf("return %q\n", e.Name)))
+	code += indent("}\n")
+	code += indent("return e.Message\n")
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("func (e *%s) StatusCode() int {\n", e.TypeName)
+	code += indent(sprintf("return %d\n", e.Status))
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("func (e *%s) ErrorName() string {\n", e.TypeName)
+	code += indent(sprintf("return %q\n", e.Name))
+	code += "}\n"
+
+	code += "\n"
+	code += sprintf("func (e *%s) ErrorData() interface{} {\n", e.TypeName)
+	if e.DataType != nil {
+		code += indent("return e.Data\n")
+	} else {
+		code += indent("return nil\n")
+	}
+	code += "}\n"
 	return code
 }
 
@@ -206,62 +393,196 @@ func (iface *GoInterface) Code() string {
 }
 
 func (route *RouteStatement) Code() string {
-	code := sprintf("_r.Router.%s(\"%s\", ", strings.ToUpper(route.Method), route.Path)
-
-	for _, middleware := range route.Middlewares {
-		code += middleware + ", "
+	server := route.Server
+	if server == nil {
+		server = servers[ServerGin]
 	}
+	return server.RouteCode(route)
+}
 
-	code += "_wrap(func(_c *gin.Context) error {\n"
-
+// codeParams declares `_path` & `_query` structs of path & query params,
+// which are bound by bindPath & bindQuery, e.g. `_c.ShouldBindUri(&_path)`
+func (route *RouteStatement) codeParams(bindPath string, bindQuery string) string {
+	code := ""
 	if len(route.PathParams) > 0 {
 		block := "\n"
 		block += "var _path struct {\n"
 		block += indent(CodeStructFields(route.PathParams))
 		block += "}\n"
-		block += "if _err := _c.ShouldBindUri(&_path); _err != nil {\n"
+		block += sprintf("if _err := %s; _err != nil {\n", bindPath)
 		block += indent("return _err\n")
 		block += "}\n"
-		code += indent(block)
+		code += block
 	}
 
-	if len(route.QueryParams) > 0 {
-		block := "\n"
-		block += "var _query struct {\n"
-		block += indent(CodeStructFields(route.QueryParams))
-		block += "}\n"
-		block += "if _err := _c.ShouldBindQuery(&_query); _err != nil {\n"
-		block += indent("return _err\n")
-		block += "}\n"
-		code += indent(block)
+	code += route.codeValues("_query", route.QueryParams, "query", bindQuery)
+	return code
+}
+
+// codeHeaders declares `_header` & `_cookie` structs of header & cookie
+// params, which are bound by bindHeader & bindCookie, e.g.
+// `_c.ShouldBindHeader(&_header)`
+func (route *RouteStatement) codeHeaders(bindHeader string, bindCookie string) string {
+	code := route.codeValues("_header", route.HeaderParams, "header", bindHeader)
+	code += route.codeValues("_cookie", route.CookieParams, "cookie", bindCookie)
+	return code
+}
+
+// codeValues declares holder struct of optional params in position in, e.g.
+// query params, which is bound by bind
+func (route *RouteStatement) codeValues(holder string, fields []GoStructField, in string, bind string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	block := "\n"
+	block += sprintf("var %s struct {\n", holder)
+	block += indent(CodeStructFields(fields))
+	block += "}\n"
+	// absent params are left untouched by binding, so defaults are set in
+	// advance to be validated as well
+	for _, d := range route.ParamDefaults {
+		if d.In == in {
+			block += d.Code()
+		}
 	}
+	block += sprintf("if _err := %s; _err != nil {\n", bind)
+	block += indent("return _err\n")
+	block += "}\n"
+	return block
+}
 
-	if route.BodyParam != nil {
-		param := route.BodyParam
-		block := "\n"
-		block += sprintf("var %s %s\n", param.Name, param.Type.Code())
-		block += sprintf("if _err := _c.ShouldBind(&%s); _err != nil {\n", param.Name)
-		block += "  return _err\n"
-		block += "}\n"
-		code += indent(block)
+// codeForm declares `_form` struct of form params, which is bound by bind
+func (route *RouteStatement) codeForm(bind string) string {
+	return route.codeValues("_form", route.FormParams, "form", bind)
+}
+
+// codeBody declares the body param, which is bound by bind, e.g.
+// `_c.ShouldBind(&user)`, and the default value is used if contentLength
+// is 0
+func (route *RouteStatement) codeBody(bind string, contentLength string) string {
+	param := route.BodyParam
+	if param == nil {
+		return ""
+	}
+	block := "\n"
+	block += sprintf("var %s %s\n", param.Name, param.Type.Code())
+	if route.BodySetDefaults {
+		block += sprintf("%s.SetDefaults()\n", param.Name)
+	}
+	if route.BodyDefault != "" {
+		block += sprintf("if %s == 0 {\n", contentLength)
+		block += indent(sprintf("%s = %s\n", param.Name, route.BodyDefault))
+		block += sprintf("} else if _err := %s; _err != nil {\n", bind)
+	} else {
+		block += sprintf("if _err := %s; _err != nil {\n", bind)
 	}
+	block += "  return _err\n"
+	block += "}\n"
+	return block
+}
 
+// codeCall calls the route function with ctxArgs appended, then responds
+// by write, e.g. `return _writeJSON(_c, %s)`, the returned value is wrapped
+// if Envelope is set. Streamed responses are written to writer, e.g. `_w`
+func (route *RouteStatement) codeCall(ctxArgs []string, writer string, write string) string {
 	block := "\n"
+	if route.StreamType != "" {
+		block += sprintf("_stream := &_streamWriter{w: %s, contentType: %q}\n", writer, route.StreamType)
+		send := "_stream"
+		if route.StreamItem != nil {
+			send = sprintf("func(_v %s) error { return _stream.send(_v) }", route.StreamItem.Code())
+		}
+		args := append(append(route.ParamExprs, send), ctxArgs...)
+		block += sprintf("_err := _o.%s(%s)\n", route.Name, strings.Join(args, ", "))
+		block += "return _stream.end(_err)\n"
+		return block
+	}
 	if route.HasRet {
 		block += "_ret, "
 	}
 	block += sprintf("_err := _o.%s(%s)\n", route.Name,
-		strings.Join(append(route.ParamExprs, "_c"), ", "))
+		strings.Join(append(route.ParamExprs, ctxArgs...), ", "))
 	block += "if _err != nil {\n"
 	block += "  return _err\n"
 	block += "}\n"
-	if route.HasRet {
-		block += "_c.Set(\"ret\", _ret)\n"
+	switch {
+	case route.HasRet && route.Envelope:
+		block += sprintf(write, "_envelope(_ret)")
+	case route.HasRet:
+		block += sprintf(write, "_ret")
+	case route.Envelope:
+		block += sprintf(write, "_envelope(nil)")
+	default:
+		block += "return nil\n"
 	}
-	block += "return nil\n"
+	return block
+}
 
-	code += indent(block)
-	code += "}))\n"
+func (stmt *ClientStatement) Code() string {
+	code := sprintf("_path := %s\n", stmt.Path)
+	query := "nil"
+	if len(stmt.QueryParams) > 0 {
+		query = "_query"
+		code += "_query := url.Values{}\n"
+		for _, q := range stmt.QueryParams {
+			code += sprintf("_addQuery(_query, %q, %s)\n", q.Name, q.Param)
+		}
+	}
+	body := "nil"
+	if stmt.Body != "" {
+		body = stmt.Body
+	}
+	if stmt.FormType != "" {
+		code += sprintf("_form := _newForm(%q)\n", stmt.FormType)
+		for _, f := range stmt.Form {
+			code += sprintf("_addForm(_form, %q, %s)\n", f.Name, f.Param)
+		}
+		if stmt.Body != "" {
+			code += sprintf("_addFormStruct(_form, %s)\n", stmt.Body)
+		}
+		body = "_form"
+	}
+	call := sprintf("_c.Client.Do(_ctx, %q, _path, %s, %s", strings.ToUpper(stmt.Method), query, body)
+	header := "nil"
+	if len(stmt.Headers) > 0 || len(stmt.Cookies) > 0 {
+		header = "_header"
+		code += "_header := http.Header{}\n"
+		for _, h := range stmt.Headers {
+			code += sprintf("_addHeader(_header, %q, %s)\n", h.Name, h.Param)
+		}
+		for _, c := range stmt.Cookies {
+			code += sprintf("_addCookie(_header, %q, %s)\n", c.Name, c.Param)
+		}
+		call = sprintf("_c.Client.DoWithHeader(_ctx, %q, _path, %s, _header, %s",
+			strings.ToUpper(stmt.Method), query, body)
+	}
+	if stmt.StreamType != "" {
+		args := sprintf("_ctx, %q, _path, %s, %s, %s", strings.ToUpper(stmt.Method), query, header, body)
+		if stmt.StreamItem == nil {
+			code += sprintf("return _c.Client.Stream(%s, _writer)\n", args)
+			return code
+		}
+		code += sprintf("return _c.Client.Events(%s, func(_data []byte) error {\n", args)
+		code += indent(sprintf("var _v %s\n", stmt.StreamItem.Code()))
+		code += indent("if _err := json.Unmarshal(_data, &_v); _err != nil {\n")
+		code += indent("  return _err\n")
+		code += indent("}\n")
+		code += indent("return _onEvent(_v)\n")
+		code += "})\n"
+		return code
+	}
+	if stmt.RetType == nil {
+		code += sprintf("return %s, nil)\n", call)
+		return code
+	}
+	code += sprintf("var _ret %s\n", stmt.RetType.Code())
+	if stmt.Envelope {
+		// data of the envelope is decoded into _ret
+		code += sprintf("_err := %s, &ResponseEnvelope{Data: &_ret})\n", call)
+	} else {
+		code += sprintf("_err := %s, &_ret)\n", call)
+	}
+	code += "return _ret, _err\n"
 	return code
 }
 
@@ -3,23 +3,55 @@ package golang
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jinzhenj/api1/pkg/api1"
 	"github.com/jinzhenj/api1/pkg/utils"
+	"github.com/pkg/errors"
 )
 
 const (
 	defaultOutputDir = "pkg/api"
 	defaultPackage   = "api"
-	ginImportPath    = "github.com/gin-gonic/gin"
 )
 
 type Render struct {
-	rParser   *api1.RouteParser
-	imports   map[string]bool
-	outputDir string
-	scalars   map[string]scalarInfo
+	// Generics renders generic structs as Go generics (requires go1.18),
+	// otherwise each instance is rendered as a monomorphized struct, e.g. `Page_User`
+	Generics bool
+	// PackagePerGroup renders each group into its own package under the
+	// output dir, it's enabled automatically if any group imports others
+	PackagePerGroup bool
+	// ImportPath is the Go import path of the output dir, it's read from
+	// go.mod in working dir if not set
+	ImportPath string
+	// Server is the web framework which routes are registered to, e.g.
+	// `nethttp`, it's read from `@go.server` of groups if not set, and
+	// defaults to gin
+	Server string
+	// OutputDir is the dir of generated files, defaults to `pkg/api`
+	OutputDir string
+	// Package is the package name of generated files, defaults to the last
+	// element of OutputDir
+	Package string
+
+	server  Server
+	rParser *api1.RouteParser
+	imports map[string]bool
+	// keyed by both qualified names & plain names
+	scalars map[string]scalarInfo
+	structs map[string]api1.StructType
+	// monomorphized instances of generic structs, unless Generics
+	instances api1.Instances
+
+	// rendering packages per group
+	perGroup   bool
+	importPath string
+	group      string
+	// group being rendered, for semantic comments of the group
+	apiGroup  *api1.ApiGroup
+	groupDeps map[string]map[string]bool
 }
 
 type scalarInfo struct {
@@ -79,13 +111,86 @@ func getScalarInfo(semComments map[string]interface{}) *scalarInfo {
 	}
 }
 
-func (r *Render) addScalar(sc *api1.ScalarType) {
+func (r *Render) addScalar(group string, sc *api1.ScalarType) {
 	if r.scalars == nil {
 		r.scalars = make(map[string]scalarInfo)
 	}
 	if s := getScalarInfo(sc.SemComments); s != nil && !s.def {
-		r.scalars[sc.Name] = *s
+		r.scalars[group+"."+sc.Name] = *s
+		if _, ok := r.scalars[sc.Name]; !ok {
+			r.scalars[sc.Name] = *s
+		}
+	}
+}
+
+func (r *Render) getImportPath() (string, error) {
+	if r.ImportPath != "" {
+		return strings.TrimRight(r.ImportPath, "/"), nil
+	}
+	module, err := utils.GoModulePath(".")
+	if err != nil {
+		return "", errors.Errorf("Go import path of [%s] cannot be determined, "+
+			"ImportPath should be set: %v", r.getOutputDir(), err)
+	}
+	return module + "/" + r.getOutputDir(), nil
+}
+
+// qualify returns the name of type defined in group, which is prefixed by
+// package name if the group is rendered into another package
+func (r *Render) qualify(group string, name string) string {
+	if !r.perGroup || group == "" || group == r.group {
+		return name
+	}
+	r.addImport(r.importPath + "/" + group)
+	if r.groupDeps[r.group] == nil {
+		r.groupDeps[r.group] = make(map[string]bool)
+	}
+	r.groupDeps[r.group][group] = true
+	return group + "." + name
+}
+
+// checkImportCycle returns error if group packages import each other
+func (r *Render) checkImportCycle() error {
+	var groups []string
+	for group := range r.groupDeps {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	state := make(map[string]int)
+	var visit func(group string, path []string) []string
+	visit = func(group string, path []string) []string {
+		path = append(path, group)
+		if state[group] == 1 {
+			for i, g := range path {
+				if g == group {
+					return path[i:]
+				}
+			}
+		}
+		if state[group] == 2 {
+			return nil
+		}
+		state[group] = 1
+		var deps []string
+		for dep := range r.groupDeps[group] {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if cycle := visit(dep, path); cycle != nil {
+				return cycle
+			}
+		}
+		state[group] = 2
+		return nil
 	}
+	for _, group := range groups {
+		if cycle := visit(group, nil); cycle != nil {
+			return errors.Errorf("Go packages of groups import each other circularly [%s]",
+				strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
 }
 
 func (r *Render) renderScalar(sc *api1.ScalarType) *GoTypeDef {
@@ -101,8 +206,48 @@ func (r *Render) renderScalar(sc *api1.ScalarType) *GoTypeDef {
 	return nil
 }
 
+// getServer returns Server if set, or the server specified by `@go.server`
+// of groups, which must agree with each other
+func (r *Render) getServer(schema *api1.Schema) (Server, error) {
+	name := r.Server
+	if name == "" {
+		for _, g := range schema.Groups {
+			s, ok := g.SemComments["go.server"].(string)
+			if !ok {
+				continue
+			}
+			if name != "" && s != name {
+				return nil, errors.Errorf("Groups specify different Go servers [%s] and [%s]", name, s)
+			}
+			name = s
+		}
+	}
+	if name == "" {
+		name = ServerGin
+	}
+	return GetServer(name)
+}
+
+// responseStyle returns the response style of fun in the group being rendered
+func (r *Render) responseStyle(fun *api1.Fun) (api1.ResponseStyle, error) {
+	g := r.apiGroup
+	if g == nil {
+		g = &api1.ApiGroup{}
+	}
+	return api1.GetResponseStyle(g, fun)
+}
+
+// routeServer returns the server routes are registered to, gin if the
+// schema is not rendered yet
+func (r *Render) routeServer() Server {
+	if r.server == nil {
+		r.server = servers[ServerGin]
+	}
+	return r.server
+}
+
 func (r *Render) getOutputDir() string {
-	trimed := strings.TrimLeft(r.outputDir, "/")
+	trimed := strings.TrimLeft(r.OutputDir, "/")
 	trimed = strings.TrimRight(trimed, "/")
 	if len(trimed) == 0 {
 		return defaultOutputDir
@@ -111,6 +256,9 @@ func (r *Render) getOutputDir() string {
 }
 
 func (r *Render) getPackage() string {
+	if r.Package != "" {
+		return r.Package
+	}
 	outputDir := r.getOutputDir()
 	parts := strings.Split(outputDir, "/")
 	if len(parts) == 0 {
@@ -124,23 +272,66 @@ func (r *Render) Render(schema *api1.Schema) ([]GoFile, error) {
 	r.rParser.LoadSchema(schema)
 	r.popImports()
 
+	server, err := r.getServer(schema)
+	if err != nil {
+		return nil, err
+	}
+	r.server = server
+
 	outputDir := r.getOutputDir()
 	packageName := r.getPackage()
 
-	// load scalars
+	r.perGroup = r.PackagePerGroup || schema.HasImports()
+	r.groupDeps = make(map[string]map[string]bool)
+	r.group = ""
+	if r.perGroup {
+		importPath, err := r.getImportPath()
+		if err != nil {
+			return nil, err
+		}
+		r.importPath = importPath
+	}
+
+	// load scalars & structs
 	r.scalars = nil
+	r.structs = make(map[string]api1.StructType)
 	for _, g := range schema.Groups {
 		for _, sc := range g.ScalarTypes {
-			r.addScalar(&sc)
+			r.addScalar(g.Name, &sc)
+		}
+		for _, st := range g.StructTypes {
+			r.structs[g.Name+"."+st.Name] = st
+			if _, ok := r.structs[st.Name]; !ok {
+				r.structs[st.Name] = st
+			}
+		}
+	}
+
+	// load generic struct instances
+	r.instances = nil
+	if !r.Generics {
+		var err error
+		if r.instances, err = schema.Instances(); err != nil {
+			return nil, err
 		}
 	}
 
 	// generate files
 	var files []GoFile
-	for _, g := range schema.Groups {
+	helpers := make(map[string]bool)
+	// client helpers of package dirs which have clients
+	clients := make(map[string]bool)
+	var clientFiles []GoFile
+	for i, g := range schema.Groups {
+		r.group = g.Name
+		r.apiGroup = &schema.Groups[i]
+		dir, pkg := outputDir, packageName
+		if r.perGroup {
+			dir, pkg = fmt.Sprintf("%s/%s", outputDir, g.Name), g.Name
+		}
 		file := GoFile{
-			Name:    fmt.Sprintf("%s/%s.go", outputDir, g.Name),
-			Package: packageName,
+			Name:    fmt.Sprintf("%s/%s.go", dir, g.Name),
+			Package: pkg,
 		}
 		for _, sc := range g.ScalarTypes {
 			if typeDef := r.renderScalar(&sc); typeDef != nil {
@@ -151,8 +342,30 @@ func (r *Render) Render(schema *api1.Schema) ([]GoFile, error) {
 			file.CodeGens = append(file.CodeGens, r.renderEnum(&en))
 		}
 		for _, st := range g.StructTypes {
+			if len(st.TypeParams) > 0 && !r.Generics {
+				continue
+			}
 			file.CodeGens = append(file.CodeGens, r.renderStruct(&st))
 		}
+		for _, inst := range r.instances {
+			// in the group of type args, whose package may import the generic's
+			if inst.Group == g.Name {
+				file.CodeGens = append(file.CodeGens, r.renderStruct(&inst.Struct))
+			}
+		}
+		for _, un := range g.UnionTypes {
+			for _, t := range un.Types {
+				// marker methods can only be defined on types of the same package
+				if r.perGroup && t.Group != "" && t.Group != g.Name {
+					return nil, errors.Errorf("Union [%s] member [%s] must be defined in group [%s]",
+						un.Name, t.QualName(), g.Name)
+				}
+			}
+			file.CodeGens = append(file.CodeGens, r.renderUnion(&un))
+		}
+		for _, er := range g.ErrorTypes {
+			file.CodeGens = append(file.CodeGens, r.renderErrorType(&er))
+		}
 		for _, iface := range g.Ifaces {
 			file.CodeGens = append(file.CodeGens, r.renderIface(&iface))
 		}
@@ -160,8 +373,8 @@ func (r *Render) Render(schema *api1.Schema) ([]GoFile, error) {
 		files = append(files, file)
 
 		file2 := GoFile{
-			Name:    fmt.Sprintf("%s/%s_route.go", outputDir, g.Name),
-			Package: packageName,
+			Name:    fmt.Sprintf("%s/%s_route.go", dir, g.Name),
+			Package: pkg,
 		}
 		for _, iface := range g.Ifaces {
 			fun, err := r.renderRoutes(&iface)
@@ -172,8 +385,39 @@ func (r *Render) Render(schema *api1.Schema) ([]GoFile, error) {
 		}
 		file2.Imports = r.popImports()
 		files = append(files, file2)
+
+		file3 := GoFile{
+			Name:    fmt.Sprintf("%s/%s_client.go", dir, g.Name),
+			Package: pkg,
+		}
+		for _, iface := range g.Ifaces {
+			codeGens, err := r.renderClient(&iface)
+			if err != nil {
+				return nil, err
+			}
+			file3.CodeGens = append(file3.CodeGens, codeGens...)
+		}
+		file3.Imports = r.popImports()
+		if len(file3.CodeGens) > 0 {
+			files = append(files, file3)
+			if !clients[dir] {
+				clientFiles = append(clientFiles, r.renderClientFile(dir, pkg))
+				clients[dir] = true
+			}
+		}
+
+		if r.perGroup && !helpers[dir] {
+			files = append(files, r.renderHelperFile(dir, pkg))
+			helpers[dir] = true
+		}
+	}
+	if err := r.checkImportCycle(); err != nil {
+		return nil, err
+	}
+	if !r.perGroup {
+		files = append(files, r.renderHelperFile(outputDir, packageName))
 	}
-	files = append(files, r.renderHelperFile())
+	files = append(files, clientFiles...)
 	return files, nil
 }
 
@@ -206,16 +450,140 @@ func (r *Render) renderEnum(en *api1.EnumType) *GoEnumCodeBlock {
 
 func (r *Render) renderStruct(st *api1.StructType) *GoStructType {
 	s := GoStructType{
-		Comments: st.Comments,
-		Name:     st.Name,
+		Comments:   st.Comments,
+		Name:       st.Name,
+		TypeParams: st.TypeParams,
+	}
+	for _, parent := range st.Extends {
+		embed := r.renderType(&parent, nil)
+		s.Embeds = append(s.Embeds, *embed)
+		if r.hasDefaults(&parent, make(map[string]bool)) {
+			// field name of embedded type is the unqualified type name
+			name := embed.Name
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			s.DefaultStructs = append(s.DefaultStructs, name)
+		}
 	}
 	_, hasForm := st.SemComments["form"]
 	for _, sf := range st.Fields {
-		s.Fields = append(s.Fields, r.renderStructField(&sf, hasForm))
+		f := r.renderStructField(&sf, hasForm)
+		s.Fields = append(s.Fields, f)
+		if sf.Default != nil && sf.Default.Kind != api1.LiteralNull {
+			s.Defaults = append(s.Defaults, GoDefault{
+				Target: "s." + f.Name,
+				Value:  r.renderLiteral(sf.Default, sf.Type, f.Type),
+			})
+		} else if sf.Default == nil && r.hasDefaults(sf.Type, make(map[string]bool)) {
+			s.DefaultStructs = append(s.DefaultStructs, f.Name)
+		}
 	}
 	return &s
 }
 
+// hasDefaults returns whether struct t has fields (including inherited ones
+// and non-nullable struct fields) with default values. Structs of nullable
+// fields, arrays & maps are created by binding, whose defaults are not set.
+func (r *Render) hasDefaults(t *api1.TypeRef, visited map[string]bool) bool {
+	if t == nil || t.Name == "" || t.Nullable || visited[t.QualName()] {
+		return false
+	}
+	visited[t.QualName()] = true
+	st, ok := r.structs[t.QualName()]
+	if !ok {
+		return false
+	}
+	for _, field := range st.Fields {
+		if field.Default != nil && field.Default.Kind != api1.LiteralNull {
+			return true
+		}
+		if field.Default == nil && r.hasDefaults(field.Type, visited) {
+			return true
+		}
+	}
+	for _, parent := range st.Extends {
+		if r.hasDefaults(&parent, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderLiteral renders default value l of type t as Go expression of typ
+func (r *Render) renderLiteral(l *api1.Literal, t *api1.TypeRef, typ *GoType) string {
+	if l.Kind == api1.LiteralNull {
+		return "nil"
+	}
+	if typ.IsPointer {
+		elem := *typ
+		elem.IsPointer = false
+		code := sprintf("func() %s {\n", typ.Code())
+		code += indent(sprintf("var _v %s = %s\n", elem.Code(), r.renderLiteral(l, t, &elem)))
+		code += indent("return &_v\n")
+		code += "}()"
+		return code
+	}
+	switch l.Kind {
+	case api1.LiteralString:
+		return strconv.Quote(l.Text)
+	case api1.LiteralIdent:
+		// enum option
+		return r.qualify(t.Group, t.Name+utils.PascalCase(l.Text))
+	case api1.LiteralArray:
+		var items []string
+		for i := range l.Items {
+			items = append(items, r.renderLiteral(&l.Items[i], t.ItemType, typ.ItemType))
+		}
+		return sprintf("%s{%s}", typ.Code(), strings.Join(items, ", "))
+	}
+	return l.Text
+}
+
+func (r *Render) renderUnion(un *api1.UnionType) *GoUnionType {
+	u := GoUnionType{
+		Comments: un.Comments,
+		Name:     un.Name,
+	}
+	for _, t := range un.Types {
+		u.Members = append(u.Members, t.Name)
+	}
+	r.addImport("encoding/json")
+	r.addImport("fmt")
+	if discriminator, ok := un.SemComments["discriminator"].(string); ok {
+		u.Discriminator = discriminator
+		u.DiscriminatorField = utils.PascalCase(discriminator)
+	} else {
+		r.addImport("bytes")
+	}
+	return &u
+}
+
+// errorTypeName returns the Go type name of error, e.g. `NotFoundError` of
+// `NotFound`
+func errorTypeName(name string) string {
+	name = utils.PascalCase(name)
+	if strings.HasSuffix(name, "Error") {
+		return name
+	}
+	return name + "Error"
+}
+
+func (r *Render) renderErrorType(er *api1.ErrorType) *GoErrorType {
+	name := errorTypeName(er.Name)
+	comments := []string{sprintf("%s is responded with status %d", name, er.Status)}
+	if len(er.Comments) > 0 {
+		comments = append(append(comments, ""), er.Comments...)
+	}
+	return &GoErrorType{
+		Comments: comments,
+		Name:     er.Name,
+		TypeName: name,
+		Status:   er.Status,
+		DataType: r.renderType(er.Payload, nil),
+	}
+}
+
 func (r *Render) renderStructField(sf *api1.StructField, hasForm bool) GoStructField {
 	f := GoStructField{
 		Comments: sf.Comments,
@@ -234,9 +602,56 @@ func (r *Render) renderStructField(sf *api1.StructField, hasForm bool) GoStructF
 	if hasForm {
 		f.Tags["form"] = tagValue
 	}
+	if tagValue != "-" {
+		if binding := bindingTag(sf.SemComments, sf.Type.Nullable); binding != "" {
+			f.Tags["binding"] = binding
+		}
+	}
 	return f
 }
 
+// bindingTag returns the validator tag of constraints declared in semComments,
+// optional values are validated only if present.
+func bindingTag(semComments map[string]interface{}, optional bool) string {
+	c, _ := api1.GetConstraints(semComments)
+	if c == nil {
+		return ""
+	}
+	var rules []string
+	if optional {
+		rules = append(rules, "omitempty")
+	}
+	number := func(f *float64) string {
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	// min & max are applied to numbers, or length of strings & arrays
+	if c.Minimum != nil {
+		rules = append(rules, "min="+number(c.Minimum))
+	}
+	if c.Maximum != nil {
+		rules = append(rules, "max="+number(c.Maximum))
+	}
+	for _, n := range []*int{c.MinLength, c.MinItems} {
+		if n != nil {
+			rules = append(rules, sprintf("min=%d", *n))
+		}
+	}
+	for _, n := range []*int{c.MaxLength, c.MaxItems} {
+		if n != nil {
+			rules = append(rules, sprintf("max=%d", *n))
+		}
+	}
+	if c.Pattern != "" {
+		// commas & pipes are separators of validator tags
+		pattern := strings.NewReplacer(",", "0x2C", "|", "0x7C").Replace(c.Pattern)
+		rules = append(rules, "pattern="+pattern)
+	}
+	if len(rules) == 0 || (optional && len(rules) == 1) {
+		return ""
+	}
+	return strings.Join(rules, ",")
+}
+
 func (r *Render) renderType(t *api1.TypeRef, semComments map[string]interface{}) *GoType {
 	if t == nil {
 		return nil
@@ -264,15 +679,23 @@ func (r *Render) renderType(t *api1.TypeRef, semComments map[string]interface{})
 			typ.Name = "int64"
 		} else if t.Name == "boolean" {
 			typ.Name = "bool"
-		} else if s, ok := r.scalars[t.Name]; ok {
+		} else if s, ok := r.scalars[t.QualName()]; ok {
 			typ.Name = s.typ
 			if s.pkg != "" {
 				r.addImport(s.pkg)
 			}
+		} else if inst := r.instances.Find(t); inst != nil {
+			typ.Name = r.qualify(inst.Group, inst.Struct.Name)
 		} else {
-			typ.Name = t.Name
+			typ.Name = r.qualify(t.Group, t.Name)
+			for _, arg := range t.TypeArgs {
+				typ.TypeArgs = append(typ.TypeArgs, *r.renderType(&arg, nil))
+			}
 		}
 	} else {
+		if t.KeyType != nil {
+			typ.KeyType = r.renderType(t.KeyType, nil)
+		}
 		typ.ItemType = r.renderType(t.ItemType, nil)
 	}
 	return &typ
@@ -301,22 +724,42 @@ func (r *Render) renderFun(fun *api1.Fun) *GoFunction {
 	for _, param := range fun.Params {
 		f.Params = append(f.Params, r.renderParam(&param))
 	}
+	if fun.Stream != nil {
+		f.Params = append(f.Params, r.renderStreamParam(fun.Stream))
+	}
 	if _, ok := fun.SemComments["route"].(string); ok {
-		f.Params = append(f.Params, GoParam{
-			Name: "c",
-			Type: &GoType{
-				Name:      "gin.Context",
-				IsPointer: true,
-			},
+		f.Params = append(f.Params, r.routeServer().ContextParams()...)
+		f.RetTypes = append(f.RetTypes, GoType{
+			Name: "error",
 		})
+		for _, imp := range r.routeServer().Imports() {
+			r.addImport(imp)
+		}
+	} else if fun.Stream != nil {
 		f.RetTypes = append(f.RetTypes, GoType{
 			Name: "error",
 		})
-		r.addImport(ginImportPath)
 	}
 	return &f
 }
 
+// renderStreamParam renders the param which the streamed response is written
+// to, `send func(T) error` sends an event of server-sent events, and bytes
+// are written to `writer io.Writer`
+func (r *Render) renderStreamParam(stream *api1.Stream) GoParam {
+	if stream.Item == nil {
+		r.addImport("io")
+		return GoParam{
+			Name: "writer",
+			Type: &GoType{Name: "io.Writer"},
+		}
+	}
+	return GoParam{
+		Name: "send",
+		Type: &GoType{Name: sprintf("func(%s) error", r.renderType(stream.Item, nil).Code())},
+	}
+}
+
 func (r *Render) renderParam(param *api1.Param) GoParam {
 	p := GoParam{
 		Comments: param.Comments,
@@ -365,18 +808,34 @@ func (r *Render) renderRouteStmt(iface *api1.Iface, fun *api1.Fun) (GoStatement,
 		return nil, nil
 	}
 
-	r.addImport(ginImportPath)
+	for _, imp := range r.routeServer().Imports() {
+		r.addImport(imp)
+	}
 	m, path, pathParams, err := api1.ParseRoute(route, api1.PathStyleColon)
 	if err != nil {
 		return nil, err
 	}
 
+	style, err := r.responseStyle(fun)
+	if err != nil {
+		return nil, err
+	}
+
 	stmt := RouteStatement{
 		Comments: fun.Comments,
 		Name:     utils.PascalCase(fun.Name),
 		Method:   m,
 		Path:     path,
 		HasRet:   fun.Type != nil,
+		Envelope: style == api1.ResponseEnvelope,
+		FormType: api1.GetFormType(fun),
+		Server:   r.routeServer(),
+	}
+	if fun.Stream != nil {
+		stmt.StreamType = fun.Stream.ContentType
+		if fun.Stream.Item != nil {
+			stmt.StreamItem = r.renderType(fun.Stream.Item, nil)
+		}
 	}
 
 	if middleware, ok := fun.SemComments["go.middleware"]; ok {
@@ -404,6 +863,10 @@ func (r *Render) renderRouteStmt(iface *api1.Iface, fun *api1.Fun) (GoStatement,
 				Name:     param.Name,
 				Type:     r.renderType(param.Type, param.SemComments),
 			}
+			stmt.BodySetDefaults = r.hasDefaults(param.Type, make(map[string]bool))
+			if param.Default != nil && param.Default.Kind != api1.LiteralNull {
+				stmt.BodyDefault = r.renderLiteral(param.Default, param.Type, stmt.BodyParam.Type)
+			}
 			paramExpr = param.Name
 		case api1.PositionPath:
 			stmt.PathParams = append(stmt.PathParams, GoStructField{
@@ -412,15 +875,16 @@ func (r *Render) renderRouteStmt(iface *api1.Iface, fun *api1.Fun) (GoStatement,
 				Type:     r.renderType(param.Type, param.SemComments),
 				Tags:     map[string]string{"uri": param.Name},
 			})
+			r.addBindingTag(&stmt.PathParams[len(stmt.PathParams)-1], &param, false)
 			paramExpr = fmt.Sprintf("_path.%s", utils.PascalCase(param.Name))
 		case api1.PositionQuery:
-			stmt.QueryParams = append(stmt.QueryParams, GoStructField{
-				Comments: param.Comments,
-				Name:     utils.PascalCase(param.Name),
-				Type:     r.renderType(param.Type, param.SemComments),
-				Tags:     map[string]string{"form": param.Name},
-			})
-			paramExpr = fmt.Sprintf("_query.%s", utils.PascalCase(param.Name))
+			paramExpr = r.addValueParam(&stmt, &stmt.QueryParams, &param, "_query", "form")
+		case api1.PositionHeader:
+			paramExpr = r.addValueParam(&stmt, &stmt.HeaderParams, &param, "_header", "header")
+		case api1.PositionCookie:
+			paramExpr = r.addValueParam(&stmt, &stmt.CookieParams, &param, "_cookie", "cookie")
+		case api1.PositionForm:
+			paramExpr = r.addValueParam(&stmt, &stmt.FormParams, &param, "_form", "form")
 		}
 		stmt.ParamExprs = append(stmt.ParamExprs, paramExpr)
 	}
@@ -428,47 +892,509 @@ func (r *Render) renderRouteStmt(iface *api1.Iface, fun *api1.Fun) (GoStatement,
 	return &stmt, nil
 }
 
-func (r *Render) renderHelperFile() GoFile {
-	code := `type ApiRoutes struct {
-	Router *gin.RouterGroup
+// renderClient renders the client struct of iface, with a method sending
+// request of each route, returns nil if iface has no route.
+func (r *Render) renderClient(iface *api1.Iface) ([]CodeGen, error) {
+	name := utils.PascalCase(iface.Name) + "Client"
+	var methods []CodeGen
+	for _, fun := range iface.Funs {
+		method, err := r.renderClientMethod(name, iface, &fun)
+		if err != nil {
+			return nil, err
+		}
+		if method != nil {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+	r.addImport("context")
+
+	comments := []string{sprintf("%s sends requests to the routes of %s", name, iface.Name)}
+	if len(iface.Comments) > 0 {
+		comments = append(append(comments, ""), iface.Comments...)
+	}
+	st := GoStructType{
+		Comments: comments,
+		Name:     name,
+		Fields: []GoStructField{{
+			Name: "Client",
+			Type: &GoType{Name: "ApiClient", IsPointer: true},
+		}},
+	}
+	constructor := GoFunction{
+		Name: "New" + name,
+		Params: []GoParam{{
+			Name: "client",
+			Type: &GoType{Name: "ApiClient", IsPointer: true},
+		}},
+		RetTypes: []GoType{{Name: name, IsPointer: true}},
+		Statements: []GoStatement{
+			&RawCode{code: sprintf("return &%s{Client: client}\n", name)},
+		},
+	}
+	return append([]CodeGen{&st, &constructor}, methods...), nil
+}
+
+func (r *Render) renderClientMethod(client string, iface *api1.Iface, fun *api1.Fun) (*GoFunction, error) {
+	route, ok := fun.SemComments["route"].(string)
+	if !ok {
+		return nil, nil
+	}
+	m, path, pathParams, err := api1.ParseRoute(route, api1.PathStyleColon)
+	if err != nil {
+		return nil, err
+	}
+	routeParams, err := r.rParser.ParseParams(iface, fun, pathParams)
+	if err != nil {
+		return nil, err
+	}
+
+	f := GoFunction{
+		Comments: fun.Comments,
+		Name:     utils.PascalCase(fun.Name),
+		Receiver: &GoParam{
+			Name: "_c",
+			Type: &GoType{Name: client, IsPointer: true},
+		},
+		Params: []GoParam{{
+			Name: "_ctx",
+			Type: &GoType{Name: "context.Context"},
+		}},
+	}
+	style, err := r.responseStyle(fun)
+	if err != nil {
+		return nil, err
+	}
+	stmt := ClientStatement{
+		Method:   m,
+		Path:     renderClientPath(path),
+		RetType:  r.renderType(fun.Type, fun.SemComments),
+		Envelope: style == api1.ResponseEnvelope,
+		FormType: api1.GetFormType(fun),
+	}
+	if stmt.RetType != nil {
+		f.RetTypes = append(f.RetTypes, *stmt.RetType)
+	}
+	f.RetTypes = append(f.RetTypes, GoType{Name: "error"})
+	for _, param := range routeParams {
+		f.Params = append(f.Params, r.renderParam(&param.Param))
+		switch param.In {
+		case api1.PositionBody:
+			stmt.Body = param.Name
+		case api1.PositionQuery:
+			stmt.QueryParams = append(stmt.QueryParams, ClientQueryParam{Name: param.WireName, Param: param.Name})
+			r.addImport("net/url")
+		case api1.PositionHeader:
+			stmt.Headers = append(stmt.Headers, ClientQueryParam{Name: param.WireName, Param: param.Name})
+			r.addImport("net/http")
+		case api1.PositionCookie:
+			stmt.Cookies = append(stmt.Cookies, ClientQueryParam{Name: param.WireName, Param: param.Name})
+			r.addImport("net/http")
+		case api1.PositionForm:
+			stmt.Form = append(stmt.Form, ClientQueryParam{Name: param.WireName, Param: param.Name})
+		}
+	}
+	if fun.Stream != nil {
+		// events are handled by the callback, bytes are copied to the writer
+		stmt.StreamType = fun.Stream.ContentType
+		if fun.Stream.Item != nil {
+			stmt.StreamItem = r.renderType(fun.Stream.Item, nil)
+			r.addImport("encoding/json")
+			f.Params = append(f.Params, GoParam{
+				Name: "_onEvent",
+				Type: &GoType{Name: sprintf("func(%s) error", stmt.StreamItem.Code())},
+			})
+		} else {
+			r.addImport("io")
+			f.Params = append(f.Params, GoParam{
+				Name: "_writer",
+				Type: &GoType{Name: "io.Writer"},
+			})
+		}
+	}
+	f.Statements = append(f.Statements, &stmt)
+	return &f, nil
+}
+
+// renderClientPath renders route path (in colon style) as a string expression,
+// e.g. `"/users/" + _pathParam(id, false)`
+func renderClientPath(path string) string {
+	var exprs []string
+	var static string
+	for i, part := range strings.Split(path, "/") {
+		if i > 0 {
+			static += "/"
+		}
+		if len(part) > 1 && (part[0] == ':' || part[0] == '*') {
+			if static != "" {
+				exprs = append(exprs, strconv.Quote(static))
+				static = ""
+			}
+			exprs = append(exprs, sprintf("_pathParam(%s, %t)", part[1:], part[0] == '*'))
+		} else {
+			static += part
+		}
+	}
+	if static != "" || len(exprs) == 0 {
+		exprs = append(exprs, strconv.Quote(static))
+	}
+	return strings.Join(exprs, " + ")
+}
+
+func (r *Render) renderClientFile(dir string, pkg string) GoFile {
+	code := `// ApiClient sends requests to the api server, and decodes json responses
+type ApiClient struct {
+	// BaseURL is prepended to route paths, e.g. "http://localhost:8080/api"
+	BaseURL string
+	// HTTPClient sends requests, http.DefaultClient is used if nil
+	HTTPClient *http.Client
+	// Header is added to each request, e.g. authorization
+	Header http.Header
+}
+
+// ApiError is returned if the response status is not 2xx
+type ApiError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// FormBody is sent as the form of ContentType, i.e. multipart/form-data or
+// application/x-www-form-urlencoded
+type FormBody struct {
+	ContentType string
+	Values      url.Values
+	// Files are *multipart.FileHeader or io.Reader, sent only by multipart
+	Files map[string][]interface{}
+}
+
+// encode returns the encoded form & its content type
+func (f *FormBody) encode() (io.Reader, string, error) {
+	if f.ContentType != "multipart/form-data" {
+		return strings.NewReader(f.Values.Encode()), f.ContentType, nil
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for key, values := range f.Values {
+		for _, value := range values {
+			if err := w.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for key, files := range f.Files {
+		for _, file := range files {
+			if err := _writeFile(w, key, file); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+func _writeFile(w *multipart.Writer, key string, file interface{}) error {
+	filename := key
+	var reader io.Reader
+	switch f := file.(type) {
+	case *multipart.FileHeader:
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		filename, reader = f.Filename, src
+	case io.Reader:
+		reader = f
+	}
+	dst, err := w.CreateFormFile(key, filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, reader)
+	return err
+}
+
+// Do sends request with json body (or FormBody) if not nil, and decodes json
+// response into ret (if not nil)
+func (c *ApiClient) Do(ctx context.Context, method string, path string, query url.Values,
+	body interface{}, ret interface{}) error {
+	return c.DoWithHeader(ctx, method, path, query, nil, body, ret)
+}
+
+// DoWithHeader is Do with header added to the request
+func (c *ApiClient) DoWithHeader(ctx context.Context, method string, path string, query url.Values,
+	header http.Header, body interface{}, ret interface{}) error {
+	resp, err := c.send(ctx, method, path, query, header, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if ret == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, ret)
+}
+
+// Stream sends request like DoWithHeader, and copies the streamed response
+// to w
+func (c *ApiClient) Stream(ctx context.Context, method string, path string, query url.Values,
+	header http.Header, body interface{}, w io.Writer) error {
+	resp, err := c.send(ctx, method, path, query, header, body, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Events sends request like DoWithHeader, and calls handle with data of each
+// server-sent event until the stream ends or handle returns error. The
+// ` + "`error`" + ` event is returned as ApiError
+func (c *ApiClient) Events(ctx context.Context, method string, path string, query url.Values,
+	header http.Header, body interface{}, handle func(data []byte) error) error {
+	resp, err := c.send(ctx, method, path, query, header, body, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	var event string
+	var data [][]byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) > 0 {
+			// field lines, comments (starting with ":") are ignored
+			field, value := line, []byte(nil)
+			if i := bytes.IndexByte(line, ':'); i >= 0 {
+				field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+			}
+			switch string(field) {
+			case "event":
+				event = string(value)
+			case "data":
+				data = append(data, append([]byte(nil), value...))
+			}
+			continue
+		}
+		// a blank line dispatches the event
+		payload := bytes.Join(data, []byte("\n"))
+		name := event
+		event, data = "", nil
+		if len(payload) == 0 {
+			continue
+		}
+		if name == "error" {
+			return &ApiError{StatusCode: resp.StatusCode, Body: payload}
+		}
+		if err := handle(payload); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// send sends request, and returns the response if the status is 2xx
+func (c *ApiClient) send(ctx context.Context, method string, path string, query url.Values,
+	header http.Header, body interface{}, accept string) (*http.Response, error) {
+	u := strings.TrimRight(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	var contentType string
+	if form, ok := body.(*FormBody); ok {
+		var err error
+		if reader, contentType, err = form.encode(); err != nil {
+			return nil, err
+		}
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range []http.Header{c.Header, header} {
+		for key, values := range h {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ApiError{StatusCode: resp.StatusCode, Body: data}
+	}
+	return resp, nil
 }
 
-type Enum interface {
-	IsValid() bool
+func _format(v interface{}) string {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(v)
 }
 
-// TODO: enum array?
-func EnumValidation(fl validator.FieldLevel) bool {
-	if en, ok := fl.Field().Interface().(Enum); ok {
-		return en.IsValid()
+func _pathParam(v interface{}, wildcard bool) string {
+	s := _format(v)
+	if !wildcard {
+		return url.PathEscape(s)
+	}
+	parts := strings.Split(strings.TrimPrefix(s, "/"), "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+func _addQuery(query url.Values, key string, v interface{}) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			_addQuery(query, key, rv.Index(i).Interface())
+		}
+		return
 	}
-	return true
+	query.Add(key, _format(rv.Interface()))
 }
 
-func init() {
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("enum", EnumValidation)
+func _addHeader(header http.Header, key string, v interface{}) {
+	values := url.Values{}
+	_addQuery(values, key, v)
+	for _, value := range values[key] {
+		header.Add(key, value)
 	}
 }
 
-func _wrap(f func(*gin.Context) error) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if err := f(c); err != nil {
-			c.Error(err)
-			c.Abort()
+func _addCookie(header http.Header, name string, v interface{}) {
+	values := url.Values{}
+	_addQuery(values, name, v)
+	for _, value := range values[name] {
+		header.Add("Cookie", (&http.Cookie{Name: name, Value: value}).String())
+	}
+}
+
+func _newForm(contentType string) *FormBody {
+	return &FormBody{
+		ContentType: contentType,
+		Values:      url.Values{},
+		Files:       make(map[string][]interface{}),
+	}
+}
+
+// _addForm adds files, json of structs & maps, or values of v to form
+func _addForm(form *FormBody, key string, v interface{}) {
+	switch f := v.(type) {
+	case *multipart.FileHeader:
+		if f != nil {
+			form.Files[key] = append(form.Files[key], f)
+		}
+		return
+	case []*multipart.FileHeader:
+		for _, fh := range f {
+			_addForm(form, key, fh)
+		}
+		return
+	case io.Reader:
+		form.Files[key] = append(form.Files[key], f)
+		return
+	case encoding.TextMarshaler:
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map {
+			if data, err := json.Marshal(v); err == nil {
+				form.Values.Add(key, string(data))
+			}
+			return
 		}
 	}
+	_addQuery(form.Values, key, v)
+}
+
+// _addFormStruct adds fields of struct v (and embedded structs) to form,
+// which are named by the ` + "`form`" + ` tags
+func _addFormStruct(form *FormBody, v interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if field.Anonymous {
+			_addFormStruct(form, rv.Field(i).Interface())
+			continue
+		}
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" || field.PkgPath != "" {
+			continue
+		}
+		_addForm(form, name, rv.Field(i).Interface())
+	}
 }
 `
-	outputDir := r.getOutputDir()
-	packageName := r.getPackage()
 	return GoFile{
-		Name:    sprintf("%s/zz_helper.go", outputDir),
-		Package: packageName,
+		Name:    sprintf("%s/zz_client.go", dir),
+		Package: pkg,
 		Imports: []string{
-			"github.com/gin-gonic/gin",
-			"github.com/gin-gonic/gin/binding",
-			"github.com/go-playground/validator/v10",
+			"bufio",
+			"bytes",
+			"context",
+			"encoding",
+			"encoding/json",
+			"fmt",
+			"io",
+			"io/ioutil",
+			"mime/multipart",
+			"net/http",
+			"net/url",
+			"reflect",
+			"strings",
 		},
 		CodeGens: []CodeGen{
 			&RawCode{
@@ -477,3 +1403,42 @@ func _wrap(f func(*gin.Context) error) func(*gin.Context) {
 		},
 	}
 }
+
+func (r *Render) addBindingTag(field *GoStructField, param *api1.RouteParam, optional bool) {
+	if binding := bindingTag(param.SemComments, optional || param.Type.Nullable); binding != "" {
+		field.Tags["binding"] = binding
+	}
+}
+
+// addValueParam adds optional param (e.g. query param) to fields of holder,
+// which is tagged by the wire name, returns the expression of param
+func (r *Render) addValueParam(stmt *RouteStatement, fields *[]GoStructField, param *api1.RouteParam,
+	holder string, tag string) string {
+	*fields = append(*fields, GoStructField{
+		Comments: param.Comments,
+		Name:     utils.PascalCase(param.Name),
+		Type:     r.renderType(param.Type, param.SemComments),
+		Tags:     map[string]string{tag: param.WireName},
+	})
+	field := &(*fields)[len(*fields)-1]
+	r.addBindingTag(field, param, false)
+	r.addParamDefault(stmt, param, holder, *field)
+	return fmt.Sprintf("%s.%s", holder, field.Name)
+}
+
+func (r *Render) addParamDefault(stmt *RouteStatement, param *api1.RouteParam, holder string, field GoStructField) {
+	if param.Default == nil || param.Default.Kind == api1.LiteralNull {
+		return
+	}
+	stmt.ParamDefaults = append(stmt.ParamDefaults, RouteDefault{
+		GoDefault: GoDefault{
+			Target: holder + "." + field.Name,
+			Value:  r.renderLiteral(param.Default, param.Type, field.Type),
+		},
+		In: string(param.In),
+	})
+}
+
+func (r *Render) renderHelperFile(dir string, pkg string) GoFile {
+	return r.routeServer().HelperFile(dir, pkg)
+}
@@ -1,6 +1,9 @@
 package golang
 
 import (
+	"flag"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/jinzhenj/api1/pkg/api1"
@@ -132,3 +135,696 @@ func TestRenderEnum(t *testing.T) {
 	assert.Equal(t, "E2", r2.Options[1].TypeName)
 	assert.Equal(t, int64(2), *r2.Options[1].Value.IntVal)
 }
+
+func TestRenderMap(t *testing.T) {
+	parser := api1.Parser{}
+	r := Render{}
+
+	t1 := `
+	  group t1
+
+		enum E1 {
+			O1
+		}
+
+		struct S1 {
+			f1: {string: int}
+			f2: {E1: [S1]}?
+			f3: map<int, {string: float}>
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r1 := r.renderStruct(&schema.Groups[0].StructTypes[0])
+	assert.Equal(t, "map[string]int64", r1.Fields[0].Type.Code())
+	assert.Equal(t, "*map[E1][]S1", r1.Fields[1].Type.Code())
+	assert.Equal(t, "map[int64]map[string]float64", r1.Fields[2].Type.Code())
+}
+
+func TestRenderExtends(t *testing.T) {
+	parser := api1.Parser{}
+	r := Render{}
+
+	t1 := `
+	  group t1
+
+		struct Base {
+			id: int
+		}
+
+		struct Audit {
+			createdAt: string
+		}
+
+		struct User extends Base, Audit {
+			name: string
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r1 := r.renderStruct(&schema.Groups[0].StructTypes[2])
+	exp1 := "type User struct {\n" +
+		"  Base\n" +
+		"  Audit\n" +
+		"  Name string `json:\"name\"`\n" +
+		"}\n"
+	assert.Equal(t, exp1, r1.Code())
+}
+
+func TestRenderUnion(t *testing.T) {
+	parser := api1.Parser{}
+	r := Render{}
+
+	t1 := `
+	  group t1
+
+		struct A {
+			kind: string
+		}
+
+		struct B {
+			kind: string
+		}
+
+		# @discriminator kind
+		union U1 = A | B
+
+		union U2 = A | B
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r1 := r.renderUnion(&schema.Groups[0].UnionTypes[0])
+	assert.Equal(t, []string{"A", "B"}, r1.Members)
+	assert.Equal(t, "kind", r1.Discriminator)
+	assert.Equal(t, "Kind", r1.DiscriminatorField)
+	code := r1.Code()
+	assert.Contains(t, code, "type U1 struct {\n  Value U1Value\n}\n")
+	assert.Contains(t, code, "func (A) isU1() {}\n")
+	assert.Contains(t, code, "  case A:\n    v.Kind = \"A\"\n    return json.Marshal(v)\n")
+	assert.Contains(t, code, "  switch _d.Kind {\n  case \"A\":\n    var v A\n")
+
+	r2 := r.renderUnion(&schema.Groups[0].UnionTypes[1])
+	assert.Equal(t, "", r2.Discriminator)
+	assert.Contains(t, r2.Code(), "    dec.DisallowUnknownFields()\n")
+	assert.Equal(t, []string{"bytes", "encoding/json", "fmt"}, r.popImports())
+}
+
+func TestRenderGeneric(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group t1
+
+		struct Page<T> {
+			items: [T]
+			total: int
+		}
+
+		struct User {
+			name: string
+		}
+
+		struct UserPage extends Page<User> {
+			next: int?
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	code := files[0].Code()
+	assert.NotContains(t, code, "type Page struct")
+	assert.Contains(t, code, "type UserPage struct {\n"+
+		"  Page_User\n"+
+		"  Next *int64 `json:\"next\"`\n"+
+		"}\n")
+	assert.Contains(t, code, "type Page_User struct {\n"+
+		"  Items []User `json:\"items\"`\n"+
+		"  Total int64 `json:\"total\"`\n"+
+		"}\n")
+
+	r = Render{Generics: true}
+	r1 := r.renderStruct(&schema.Groups[0].StructTypes[0])
+	exp1 := "type Page[T any] struct {\n" +
+		"  Items []T `json:\"items\"`\n" +
+		"  Total int64 `json:\"total\"`\n" +
+		"}\n"
+	assert.Equal(t, exp1, r1.Code())
+
+	r2 := r.renderStruct(&schema.Groups[0].StructTypes[2])
+	exp2 := "type UserPage struct {\n" +
+		"  Page[User]\n" +
+		"  Next *int64 `json:\"next\"`\n" +
+		"}\n"
+	assert.Equal(t, exp2, r2.Code())
+}
+
+func TestRenderPackagePerGroup(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group common
+
+		enum Status {
+			Active
+		}
+	`
+	t2 := `
+	  group user
+
+		struct User {
+			status: common.Status
+		}
+	`
+
+	schema, err := parser.Parse(t1, t2)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r := Render{PackagePerGroup: true, ImportPath: "example.com/app/pkg/api"}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{
+		"pkg/api/common/common.go",
+		"pkg/api/common/common_route.go",
+		"pkg/api/common/zz_helper.go",
+		"pkg/api/user/user.go",
+		"pkg/api/user/user_route.go",
+		"pkg/api/user/zz_helper.go",
+	}, names)
+	assert.Equal(t, "user", files[3].Package)
+	assert.Equal(t, []string{"example.com/app/pkg/api/common"}, files[3].Imports)
+	assert.Contains(t, files[3].Code(), "Status common.Status `json:\"status\"`")
+
+	t3 := `
+	  group common
+
+		struct Owner {
+			user: user.User
+		}
+	`
+	schema, err = parser.Parse(t1, t2, t3)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	_, err = r.Render(schema)
+	assert.EqualError(t, err,
+		"Go packages of groups import each other circularly [common -> user -> common]")
+}
+
+func TestRenderGenericGroups(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group common
+
+		struct Meta {
+			total: int
+		}
+
+		struct Page<T> {
+			items: [T]
+			meta: Meta
+		}
+	`
+	t2 := `
+	  group pay
+
+		struct Order {
+			id: int
+		}
+
+		interface PayApi {
+			# @route GET /orders
+			listOrders(): common.Page<Order>
+		}
+	`
+
+	schema, err := parser.Parse(t1, t2)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{PackagePerGroup: true, ImportPath: "example.com/app/pkg/api"}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	// the instance is in the package of its type arg, which imports common
+	assert.Equal(t, "pkg/api/common/common.go", files[0].Name)
+	assert.NotContains(t, files[0].Code(), "Page_Order")
+	assert.Equal(t, "pkg/api/pay/pay.go", files[3].Name)
+	assert.Equal(t, []string{"example.com/app/pkg/api/common", "github.com/gin-gonic/gin"}, files[3].Imports)
+	assert.Contains(t, files[3].Code(), "type Page_Order struct {\n"+
+		"  Items []Order `json:\"items\"`\n"+
+		"  Meta common.Meta `json:\"meta\"`\n"+
+		"}\n")
+	assert.Contains(t, files[3].Code(), "  ListOrders(c *gin.Context) (Page_Order, error)\n")
+}
+
+func TestRenderDefault(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group t1
+
+		enum Role {
+			Admin
+			Normal
+		}
+
+		struct Base {
+			version: int = 1
+		}
+
+		struct User extends Base {
+			name: string = "anonymous"
+			role: Role = Normal
+			score: float? = 1.5
+		}
+
+		struct Team {
+			leader: User
+			members: [User]
+			backup: User?
+		}
+
+		interface I {
+			# @route GET /users/:id
+			getUser(id: int, page: int = 1, tags: [string] = ["a"]): User
+
+			# @route POST /users
+			createUser(user: User)
+
+			# @route POST /teams
+			createTeam(team: Team)
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	r := Render{}
+	if _, err := r.Render(schema); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	r1 := r.renderStruct(&schema.Groups[0].StructTypes[1])
+	exp1 := "type User struct {\n" +
+		"  Base\n" +
+		"  Name string `json:\"name\"`\n" +
+		"  Role Role `json:\"role\"`\n" +
+		"  Score *float64 `json:\"score\"`\n" +
+		"}\n" +
+		"\n" +
+		"// SetDefaults sets fields to their default values\n" +
+		"func (s *User) SetDefaults() {\n" +
+		"  s.Base.SetDefaults()\n" +
+		"  s.Name = \"anonymous\"\n" +
+		"  s.Role = RoleNormal\n" +
+		"  s.Score = func() *float64 {\n" +
+		"    var _v float64 = 1.5\n" +
+		"    return &_v\n" +
+		"  }()\n" +
+		"}\n"
+	assert.Equal(t, exp1, r1.Code())
+
+	iface := &schema.Groups[0].Ifaces[0]
+	r2, err := r.renderRouteStmt(iface, &iface.Funs[0])
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	code := r2.Code()
+	assert.Contains(t, code, "  _query.Page = 1\n"+
+		"  if _err := _bindError(_c.ShouldBindQuery(&_query)); _err != nil {\n")
+	assert.Contains(t, code, "  var tags []string\n"+
+		"  if _c.Request.ContentLength == 0 {\n"+
+		"    tags = []string{\"a\"}\n"+
+		"  } else if _err := _bindError(_c.ShouldBind(&tags)); _err != nil {\n")
+
+	r3, err := r.renderRouteStmt(iface, &iface.Funs[1])
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Contains(t, r3.Code(), "  var user User\n"+
+		"  user.SetDefaults()\n"+
+		"  if _err := _bindError(_c.ShouldBind(&user)); _err != nil {\n")
+
+	// defaults of non-nullable struct fields are set, while elements are
+	// created by binding
+	r4 := r.renderStruct(&schema.Groups[0].StructTypes[2])
+	assert.Contains(t, r4.Code(), "func (s *Team) SetDefaults() {\n"+
+		"  s.Leader.SetDefaults()\n"+
+		"}\n")
+	r5, err := r.renderRouteStmt(iface, &iface.Funs[2])
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Contains(t, r5.Code(), "  team.SetDefaults()\n")
+}
+
+func TestRenderConstraints(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group t1
+
+		struct User {
+			# @minLength 1
+			# @maxLength 32
+			# @pattern ^[a-z]+(,[a-z]+)*$
+			name: string
+			# @minimum 0
+			# @maximum 150
+			age: int?
+			# @maxItems 3
+			tags: [string]
+			# @pattern ^"\w+"$
+			quoted: string
+			# @pattern ^` + "`" + `\w+$
+			backquoted: string
+		}
+
+		interface I {
+			# @route GET /users/:id
+			getUser(
+				# @minimum 1
+				id: int,
+				# @minimum 1
+				page: int = 1,
+				# @minimum:json 0.5
+				score: float?
+			): User
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	r1 := r.renderStruct(&schema.Groups[0].StructTypes[0])
+	exp1 := "type User struct {\n" +
+		"  Name string `binding:\"min=1,max=32,pattern=^[a-z]+(0x2C[a-z]+)*$\" json:\"name\"`\n" +
+		"  Age *int64 `binding:\"omitempty,min=0,max=150\" json:\"age\"`\n" +
+		"  Tags []string `binding:\"max=3\" json:\"tags\"`\n" +
+		"  Quoted string `binding:\"pattern=^\\\"\\\\w+\\\"$\" json:\"quoted\"`\n" +
+		"  Backquoted string \"binding:\\\"pattern=^`\\\\\\\\w+$\\\" json:\\\"backquoted\\\"\"\n" +
+		"}\n"
+	assert.Equal(t, exp1, r1.Code())
+
+	iface := &schema.Groups[0].Ifaces[0]
+	r2, err := r.renderRouteStmt(iface, &iface.Funs[0])
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	code := r2.Code()
+	assert.Contains(t, code, "    Id int64 `binding:\"min=1\" uri:\"id\"`\n")
+	assert.Contains(t, code, "    Page int64 `binding:\"min=1\" form:\"page\"`\n"+
+		"    Score *float64 `binding:\"omitempty,min=0.5\" form:\"score\"`\n")
+}
+
+func TestRenderClient(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group user
+
+		struct User {
+			name: string
+		}
+
+		interface UserApi {
+			# @route GET /users/:id
+			getUser(id: int, fields: string?): User
+
+			# @route PUT /users/:id
+			updateUser(id: int, user: User)
+
+			# @route GET /files/*path
+			getFile(path: string): string
+
+			# @route DELETE /users/:id
+			deleteUser(
+				id: int,
+				# @in header X-Request-Id
+				requestId: string?,
+				# @in cookie
+				session: string
+			)
+
+			# @route GET /users/:id/events
+			watchUser(id: int): stream<User>
+
+			# @route GET /users/:id/avatar
+			# @produces image/png
+			getAvatar(id: int): stream
+
+			internal(): int
+		}
+
+		interface Internal {
+			ping(): string
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{
+		"pkg/api/user.go",
+		"pkg/api/user_route.go",
+		"pkg/api/user_client.go",
+		"pkg/api/zz_helper.go",
+		"pkg/api/zz_client.go",
+	}, names)
+	assert.Equal(t, []string{"context", "encoding/json", "io", "net/http", "net/url"}, files[2].Imports)
+
+	code := files[2].Code()
+	assert.NotContains(t, code, "InternalClient")
+	assert.NotContains(t, code, "func (_c *UserApiClient) Internal(")
+	assert.Contains(t, code, "type UserApiClient struct {\n"+
+		"  Client *ApiClient\n"+
+		"}\n")
+	assert.Contains(t, code, "func (_c *UserApiClient) GetUser(_ctx context.Context, id int64, fields *string) (User, error) {\n"+
+		"\n"+
+		"  _path := \"/users/\" + _pathParam(id, false)\n"+
+		"  _query := url.Values{}\n"+
+		"  _addQuery(_query, \"fields\", fields)\n"+
+		"  var _ret User\n"+
+		"  _err := _c.Client.Do(_ctx, \"GET\", _path, _query, nil, &_ret)\n"+
+		"  return _ret, _err\n"+
+		"}\n")
+	assert.Contains(t, code, "func (_c *UserApiClient) UpdateUser(_ctx context.Context, id int64, user User) error {\n"+
+		"\n"+
+		"  _path := \"/users/\" + _pathParam(id, false)\n"+
+		"  return _c.Client.Do(_ctx, \"PUT\", _path, nil, user, nil)\n"+
+		"}\n")
+	assert.Contains(t, code, "  _path := \"/files/\" + _pathParam(path, true)\n")
+	assert.Contains(t, code, "func (_c *UserApiClient) DeleteUser(_ctx context.Context, id int64, requestId *string, session string) error {\n"+
+		"\n"+
+		"  _path := \"/users/\" + _pathParam(id, false)\n"+
+		"  _header := http.Header{}\n"+
+		"  _addHeader(_header, \"X-Request-Id\", requestId)\n"+
+		"  _addCookie(_header, \"session\", session)\n"+
+		"  return _c.Client.DoWithHeader(_ctx, \"DELETE\", _path, nil, _header, nil, nil)\n"+
+		"}\n")
+	assert.Contains(t, code, "func (_c *UserApiClient) WatchUser(_ctx context.Context, id int64, _onEvent func(User) error) error {\n"+
+		"\n"+
+		"  _path := \"/users/\" + _pathParam(id, false) + \"/events\"\n"+
+		"  return _c.Client.Events(_ctx, \"GET\", _path, nil, nil, nil, func(_data []byte) error {\n"+
+		"    var _v User\n"+
+		"    if _err := json.Unmarshal(_data, &_v); _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    return _onEvent(_v)\n"+
+		"  })\n"+
+		"}\n")
+	assert.Contains(t, code, "func (_c *UserApiClient) GetAvatar(_ctx context.Context, id int64, _writer io.Writer) error {\n"+
+		"\n"+
+		"  _path := \"/users/\" + _pathParam(id, false) + \"/avatar\"\n"+
+		"  return _c.Client.Stream(_ctx, \"GET\", _path, nil, nil, nil, _writer)\n"+
+		"}\n")
+	assert.Contains(t, files[0].Code(), "  WatchUser(id int64, send func(User) error, c *gin.Context) error\n")
+	assert.Contains(t, files[0].Code(), "  GetAvatar(id int64, writer io.Writer, c *gin.Context) error\n")
+}
+
+func TestRenderNetHTTP(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  # @go.server nethttp
+	  group user
+
+		struct User {
+			name: string
+		}
+
+		interface UserApi {
+			# @route GET /users/:id/*path
+			# @go.middleware auth
+			getUser(id: int, path: string, page: int = 1): User
+
+			# @route PUT /users
+			updateUser(user: User)
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Equal(t, []string{"net/http"}, files[0].Imports)
+	assert.Contains(t, files[0].Code(),
+		"  GetUser(id int64, path string, page int64, w http.ResponseWriter, r *http.Request) (User, error)\n")
+
+	assert.Equal(t, []string{"net/http"}, files[1].Imports)
+	assert.Equal(t, "func (_r *ApiRoutes) RegisterUserApi(_o UserApi) {\n"+
+		"\n"+
+		"  _r._handle(\"GET\", \"/users/{id}/{path...}\", func(_w http.ResponseWriter, _req *http.Request) error {\n"+
+		"    \n"+
+		"    var _path struct {\n"+
+		"      Id int64 `uri:\"id\"`\n"+
+		"      Path string `uri:\"path\"`\n"+
+		"    }\n"+
+		"    if _err := _bindValues(&_path, \"uri\", _req.PathValue); _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    \n"+
+		"    var _query struct {\n"+
+		"      Page int64 `form:\"page\"`\n"+
+		"    }\n"+
+		"    _query.Page = 1\n"+
+		"    if _err := _bindValues(&_query, \"form\", _req.URL.Query().Get); _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    \n"+
+		"    _ret, _err := _o.GetUser(_path.Id, _path.Path, _query.Page, _w, _req)\n"+
+		"    if _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    return _writeJSON(_w, _ret)\n"+
+		"  }, auth)\n"+
+		"\n"+
+		"  _r._handle(\"PUT\", \"/users\", func(_w http.ResponseWriter, _req *http.Request) error {\n"+
+		"    \n"+
+		"    var user User\n"+
+		"    if _err := _bindJSON(_req, &user); _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    \n"+
+		"    _err := _o.UpdateUser(user, _w, _req)\n"+
+		"    if _err != nil {\n"+
+		"      return _err\n"+
+		"    }\n"+
+		"    return nil\n"+
+		"  })\n"+
+		"}\n", files[1].CodeGens[0].Code())
+	assert.NotContains(t, files[3].Code(), "github.com/gin-gonic")
+	assert.Contains(t, files[3].Code(), "Mux *http.ServeMux\n")
+
+	// option overrides semantic comments
+	r = Render{Server: ServerGin}
+	files, err = r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Equal(t, []string{"github.com/gin-gonic/gin"}, files[1].Imports)
+
+	r = Render{Server: "fiber"}
+	_, err = r.Render(schema)
+	assert.EqualError(t, err, "unknown Go server [fiber], expecting one of [chi, echo, gin, nethttp]")
+
+	t2 := `
+	  # @go.server gin
+	  group other
+	`
+	schema, err = parser.Parse(t1, t2)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r = Render{}
+	_, err = r.Render(schema)
+	assert.EqualError(t, err, "Groups specify different Go servers [nethttp] and [gin]")
+}
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestRenderServers compares the rendered routes & helpers of each server with
+// the golden files `testdata/server_<name>.golden`, which are rewritten by
+// `go test -update`
+func TestRenderServers(t *testing.T) {
+	parser := api1.Parser{}
+	schema, err := parser.ParseFiles("testdata/server.api")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for _, name := range ServerNames() {
+		r := Render{Server: name}
+		files, err := r.Render(schema)
+		if err != nil {
+			t.Fatalf("Render error: %v", err)
+		}
+		var code string
+		for _, file := range files {
+			if strings.HasSuffix(file.Name, "_client.go") {
+				continue
+			}
+			code += sprintf("// ---- %s ----\n", file.Name)
+			code += file.Code()
+		}
+
+		golden := sprintf("testdata/server_%s.golden", name)
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(code), 0644); err != nil {
+				t.Fatalf("Write error: %v", err)
+			}
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		assert.Equal(t, string(expected), code, name)
+	}
+}
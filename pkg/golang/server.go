@@ -0,0 +1,463 @@
+package golang
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// web frameworks which routes can be registered to
+const (
+	ServerGin     = "gin"
+	ServerNetHTTP = "nethttp"
+	ServerEcho    = "echo"
+	ServerChi     = "chi"
+)
+
+// Server generates code registering routes to a web framework
+type Server interface {
+	// ContextParams are appended to params of route functions, which give
+	// access to the request, e.g. `c *gin.Context`
+	ContextParams() []GoParam
+	// Imports are packages used by ContextParams & RouteCode
+	Imports() []string
+	// RouteCode registers the route by `_r`, the `*ApiRoutes`
+	RouteCode(route *RouteStatement) string
+	// HelperFile defines `ApiRoutes` and the helpers used by RouteCode
+	HelperFile(dir string, pkg string) GoFile
+}
+
+var servers = map[string]Server{
+	ServerGin:     ginServer{},
+	ServerNetHTTP: netHTTPServer{},
+	ServerEcho:    echoServer{},
+	ServerChi:     chiServer{},
+}
+
+// ServerNames returns names of the supported servers
+func ServerNames() []string {
+	var names []string
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetServer returns the server of name
+func GetServer(name string) (Server, error) {
+	server, ok := servers[name]
+	if !ok {
+		return nil, errors.Errorf("unknown Go server [%s], expecting one of [%s]",
+			name, strings.Join(ServerNames(), ", "))
+	}
+	return server, nil
+}
+
+// withParamTags returns a copy of route, whose path & query params are tagged
+// by pathTag & queryTag instead of `uri` & `form`, and the wildcard path
+// param is renamed to wildcard, e.g. `*` of echo
+func (route *RouteStatement) withParamTags(pathTag string, queryTag string, wildcard string) *RouteStatement {
+	wildcards := make(map[string]bool)
+	if name := wildcardParam(route.Path); name != "" {
+		wildcards[name] = true
+	}
+	retag := func(fields []GoStructField, from string, to string) []GoStructField {
+		var res []GoStructField
+		for _, field := range fields {
+			tags := make(map[string]string)
+			for key, val := range field.Tags {
+				if key == from {
+					if wildcards[val] && from == "uri" && wildcard != "" {
+						val = wildcard
+					}
+					key = to
+				}
+				tags[key] = val
+			}
+			field.Tags = tags
+			res = append(res, field)
+		}
+		return res
+	}
+	r := *route
+	r.PathParams = retag(route.PathParams, "uri", pathTag)
+	r.QueryParams = retag(route.QueryParams, "form", queryTag)
+	return &r
+}
+
+// wildcardParam returns name of the wildcard path param, e.g. `path` of
+// `/files/:id/*path`, or empty if there is none
+func wildcardParam(path string) string {
+	for _, part := range strings.Split(path, "/") {
+		if len(part) > 1 && part[0] == '*' {
+			return part[1:]
+		}
+	}
+	return ""
+}
+
+// validationCode registers validations of enums & patterns to validator v,
+// which are referred by the `binding` tags
+const validationCode = `type Enum interface {
+	IsValid() bool
+}
+
+// TODO: enum array?
+func EnumValidation(fl validator.FieldLevel) bool {
+	if en, ok := fl.Field().Interface().(Enum); ok {
+		return en.IsValid()
+	}
+	return true
+}
+
+var patterns sync.Map
+
+// PatternValidation validates strings match the regular expression in param
+func PatternValidation(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return true
+	}
+	re, ok := patterns.Load(fl.Param())
+	if !ok {
+		re, _ = patterns.LoadOrStore(fl.Param(), regexp.MustCompile(fl.Param()))
+	}
+	return re.(*regexp.Regexp).MatchString(fl.Field().String())
+}
+
+func registerValidations(v *validator.Validate) {
+	v.RegisterValidation("enum", EnumValidation)
+	v.RegisterValidation("pattern", PatternValidation)
+}
+`
+
+// validateCode validates structs by the `binding` tags, for servers which do
+// not validate by themselves
+const validateCode = `var _validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New()
+	// tags are the same as gin's
+	v.SetTagName("binding")
+	registerValidations(v)
+	return v
+}
+
+// _validateValue validates structs, and structs in arrays
+func _validateValue(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return _validate.Struct(v.Interface())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := _validateValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+`
+
+// setValuesCode sets fields of structs to string values, e.g. cookies, for
+// servers which cannot bind them
+const setValuesCode = `// _setValues sets fields of the struct pointed by dst to non-empty values
+// got by names in tag, e.g. ` + "`uri:\"id\"`" + `
+func _setValues(dst interface{}, tag string, get func(string) string) error {
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get(tag)
+		if name == "" {
+			continue
+		}
+		s := get(name)
+		if s == "" {
+			continue
+		}
+		if err := _setValue(v.Field(i), s); err != nil {
+			return fmt.Errorf("invalid %s [%s]: %v", name, s, err)
+		}
+	}
+	return nil
+}
+
+func _setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := _setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+`
+
+// formCode sets fields of structs to values & files of forms, for servers
+// which cannot bind files, it requires setValuesCode
+const formCode = `// _parseForm parses the multipart or url-encoded form of r
+func _parseForm(r *http.Request) (*multipart.Form, error) {
+	err := r.ParseMultipartForm(32 << 20)
+	if err == http.ErrNotMultipart {
+		return &multipart.Form{Value: r.PostForm}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.MultipartForm, nil
+}
+
+// _setForm sets fields of the struct pointed by dst (and embedded structs) to
+// files & values of form named in the ` + "`form`" + ` tags, structs & maps are
+// decoded from json
+func _setForm(dst interface{}, form *multipart.Form) error {
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous && v.Field(i).Kind() == reflect.Struct {
+			if err := _setForm(v.Field(i).Addr().Interface(), form); err != nil {
+				return err
+			}
+			continue
+		}
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		switch f := v.Field(i).Addr().Interface().(type) {
+		case **multipart.FileHeader:
+			if files := form.File[name]; len(files) > 0 {
+				*f = files[0]
+			}
+			continue
+		case *[]*multipart.FileHeader:
+			if files := form.File[name]; len(files) > 0 {
+				*f = files
+			}
+			continue
+		}
+		values := form.Value[name]
+		if len(values) == 0 {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Slice {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		var err error
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			fv.Set(reflect.MakeSlice(fv.Type(), len(values), len(values)))
+			for j := 0; j < len(values) && err == nil; j++ {
+				err = _setFormValue(fv.Index(j), values[j])
+			}
+		} else {
+			err = _setFormValue(fv, values[0])
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s %v: %v", name, values, err)
+		}
+	}
+	return nil
+}
+
+// _setFormValue sets v to s, which is json of structs, maps & arrays
+func _setFormValue(v reflect.Value, s string) error {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, ok := reflect.New(t).Interface().(encoding.TextUnmarshaler); !ok {
+		switch t.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Interface, reflect.Slice:
+			return json.Unmarshal([]byte(s), v.Addr().Interface())
+		}
+	}
+	return _setValue(v, s)
+}
+`
+
+// responseCode responds errors as ErrorResponse with the status mapped by
+// MapError (the status & payload of declared errors by default), and wraps
+// returned values in ResponseEnvelope for routes with `@response envelope`
+const responseCode = `// ErrorResponse is responded if the route returns error
+type ErrorResponse struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+	// Error & Data are the name & payload of declared errors
+	Error string      ` + "`json:\"error,omitempty\"`" + `
+	Data  interface{} ` + "`json:\"data,omitempty\"`" + `
+}
+
+// ResponseEnvelope wraps the returned value of routes with ` + "`@response envelope`" + `
+type ResponseEnvelope struct {
+	Code    int         ` + "`json:\"code\"`" + `
+	Data    interface{} ` + "`json:\"data,omitempty\"`" + `
+	Message string      ` + "`json:\"message\"`" + `
+}
+
+// BindError is returned if the request cannot be bound to params
+type BindError struct {
+	Err error
+}
+
+func (e *BindError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// MapError maps errors returned by routes to status codes & messages of the
+// responses, it can be replaced to map errors of other packages
+var MapError = DefaultMapError
+
+// DefaultMapError maps BindError to 400, errors having ` + "`StatusCode() int`" + `
+// method to the status code, and others to 500
+func DefaultMapError(err error) (int, string) {
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return http.StatusBadRequest, err.Error()
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode(), err.Error()
+	}
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// DeclaredError is implemented by errors declared in api files
+type DeclaredError interface {
+	error
+	StatusCode() int
+	ErrorName() string
+	ErrorData() interface{}
+}
+
+func _errorResponse(err error) (int, ErrorResponse) {
+	status, message := MapError(err)
+	resp := ErrorResponse{Code: status, Message: message}
+	var declared DeclaredError
+	if errors.As(err, &declared) {
+		resp.Error = declared.ErrorName()
+		resp.Data = declared.ErrorData()
+	}
+	return status, resp
+}
+
+func _envelope(v interface{}) ResponseEnvelope {
+	return ResponseEnvelope{Code: 0, Data: v, Message: "ok"}
+}
+`
+
+// streamCode writes streamed responses of routes returning `stream`
+const streamCode = `// _streamWriter writes the streamed response, the response starts on the first
+// write, so errors returned before it are responded as usual
+type _streamWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	started     bool
+}
+
+func (s *_streamWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", s.contentType)
+	if s.contentType == "text/event-stream" {
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("Connection", "keep-alive")
+	}
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// Write writes p to the response, and flushes it to the client
+func (s *_streamWriter) Write(p []byte) (int, error) {
+	s.start()
+	n, err := s.w.Write(p)
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+// send writes v as json data of an event
+func (s *_streamWriter) send(v interface{}) error {
+	return s.event("", v)
+}
+
+func (s *_streamWriter) event(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var event string
+	if name != "" {
+		event = fmt.Sprintf("event: %s\n", name)
+	}
+	_, err = fmt.Fprintf(s, "%sdata: %s\n\n", event, data)
+	return err
+}
+
+// end ends the stream with err returned by the route, which is returned if
+// the response has not started. Otherwise it is sent as an ` + "`error`" + ` event
+// of ErrorResponse for server-sent events, or dropped for bytes as the
+// status has been sent
+func (s *_streamWriter) end(err error) error {
+	if !s.started {
+		if err != nil {
+			return err
+		}
+		s.start()
+		return nil
+	}
+	if err != nil && s.contentType == "text/event-stream" {
+		_, resp := _errorResponse(err)
+		s.event("error", resp)
+	}
+	return nil
+}
+`
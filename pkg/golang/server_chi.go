@@ -0,0 +1,65 @@
+package golang
+
+import (
+	"strings"
+)
+
+const chiImportPath = "github.com/go-chi/chi/v5"
+
+// chiServer registers routes to `chi.Router`, the returned value is
+// responded as json
+type chiServer struct{}
+
+func (chiServer) ContextParams() []GoParam {
+	return netHTTPServer{}.ContextParams()
+}
+
+func (chiServer) Imports() []string {
+	return []string{"net/http"}
+}
+
+// chiPath converts path in colon style to the pattern of chi, wildcard is
+// unnamed in chi, e.g. `/files/:id/*path` to `/files/{id}/*`
+func chiPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if len(part) > 1 && part[0] == ':' {
+			parts[i] = sprintf("{%s}", part[1:])
+		} else if len(part) > 1 && part[0] == '*' {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (chiServer) RouteCode(route *RouteStatement) string {
+	return stdRouteCode(route.withParamTags("uri", "form", "*"), chiPath(route.Path), "_urlParam(_req)")
+}
+
+func (chiServer) HelperFile(dir string, pkg string) GoFile {
+	code := `type ApiRoutes struct {
+	Router chi.Router
+	// ErrorHandler responds errors returned by routes, defaults to
+	// DefaultErrorHandler
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func (r *ApiRoutes) _handle(method string, path string, f func(http.ResponseWriter, *http.Request) error,
+	middlewares ...func(http.Handler) http.Handler) {
+	r.Router.With(middlewares...).Method(method, path, _handler(f, r.ErrorHandler))
+}
+
+func _urlParam(r *http.Request) func(string) string {
+	return func(name string) string {
+		return chi.URLParam(r, name)
+	}
+}
+
+` + stdHelperCode
+	return GoFile{
+		Name:     sprintf("%s/zz_helper.go", dir),
+		Package:  pkg,
+		Imports:  append(append([]string{}, stdHelperImports...), chiImportPath),
+		CodeGens: []CodeGen{&RawCode{code: code}},
+	}
+}
@@ -0,0 +1,180 @@
+package golang
+
+import (
+	"strings"
+)
+
+const echoImportPath = "github.com/labstack/echo/v4"
+
+// echoServer registers routes to `*echo.Group`, params are bound by the
+// default binder of echo, the returned value is responded as json
+type echoServer struct{}
+
+func (echoServer) ContextParams() []GoParam {
+	return []GoParam{{
+		Name: "c",
+		Type: &GoType{Name: "echo.Context"},
+	}}
+}
+
+func (echoServer) Imports() []string {
+	return []string{echoImportPath}
+}
+
+// echoPath converts path in colon style to the path of echo, wildcard is
+// unnamed in echo, e.g. `/files/:id/*path` to `/files/:id/*`
+func echoPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if len(part) > 1 && part[0] == '*' {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (echoServer) RouteCode(route *RouteStatement) string {
+	path := echoPath(route.Path)
+	route = route.withParamTags("param", "query", "*")
+	code := sprintf("_r.Group.%s(\"%s\", _wrap(func(_c echo.Context) error {\n", strings.ToUpper(route.Method), path)
+	code += indent(route.codeParams("_bindPath(_c, &_path)", "_bindQuery(_c, &_query)"))
+	code += indent(route.codeHeaders("_bindValues(&_header, \"header\", _c.Request().Header.Get)",
+		"_bindValues(&_cookie, \"cookie\", _getCookie(_c))"))
+	code += indent(route.codeForm("_bindForm(_c, &_form)"))
+	if route.BodyParam != nil {
+		bind := "_bindBody"
+		if route.FormType != "" {
+			bind = "_bindForm"
+		}
+		code += indent(route.codeBody(sprintf("%s(_c, &%s)", bind, route.BodyParam.Name),
+			"_c.Request().ContentLength"))
+	}
+	code += indent(route.codeCall([]string{"_c"}, "_c.Response()", "return _writeJSON(_c, %s)\n"))
+	code += "})"
+	for _, middleware := range route.Middlewares {
+		code += ", " + middleware
+	}
+	code += ")\n"
+	return code
+}
+
+func (echoServer) HelperFile(dir string, pkg string) GoFile {
+	code := `type ApiRoutes struct {
+	Group *echo.Group
+}
+
+` + validationCode + `
+` + validateCode + `
+var _binder = &echo.DefaultBinder{}
+
+// _validated validates dst after binding, returns bad request error if invalid
+func _validated(dst interface{}) error {
+	if err := _validateValue(reflect.ValueOf(dst)); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+func _bindPath(c echo.Context, dst interface{}) error {
+	if err := _binder.BindPathParams(c, dst); err != nil {
+		return err
+	}
+	return _validated(dst)
+}
+
+func _bindQuery(c echo.Context, dst interface{}) error {
+	if err := _binder.BindQueryParams(c, dst); err != nil {
+		return err
+	}
+	return _validated(dst)
+}
+
+func _bindBody(c echo.Context, dst interface{}) error {
+	if err := _binder.BindBody(c, dst); err != nil {
+		return err
+	}
+	return _validated(dst)
+}
+
+` + setValuesCode + `
+// _bindValues sets fields of dst to values got by names in tag, e.g.
+// headers, then validates dst
+func _bindValues(dst interface{}, tag string, get func(string) string) error {
+	if err := _setValues(dst, tag, get); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return _validated(dst)
+}
+
+` + formCode + `
+// _bindForm sets fields of dst to the form (with files) of the request, then
+// validates dst
+func _bindForm(c echo.Context, dst interface{}) error {
+	form, err := _parseForm(c.Request())
+	if err == nil {
+		err = _setForm(dst, form)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return _validated(dst)
+}
+
+// _getCookie returns the getter of cookie values of c
+func _getCookie(c echo.Context) func(string) string {
+	return func(name string) string {
+		if cookie, err := c.Cookie(name); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}
+}
+
+` + responseCode + `
+` + streamCode + `
+// _wrap converts errors returned by f to echo.HTTPError of ErrorResponse,
+// errors of echo (e.g. binding) keep their status codes
+func _wrap(f echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := f(c)
+		if err == nil {
+			return nil
+		}
+		var status int
+		var resp ErrorResponse
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			status = he.Code
+			resp = ErrorResponse{Code: he.Code, Message: fmt.Sprint(he.Message)}
+		} else {
+			status, resp = _errorResponse(err)
+		}
+		return echo.NewHTTPError(status, resp).SetInternal(err)
+	}
+}
+
+func _writeJSON(c echo.Context, v interface{}) error {
+	return c.JSON(http.StatusOK, v)
+}
+`
+	return GoFile{
+		Name:    sprintf("%s/zz_helper.go", dir),
+		Package: pkg,
+		Imports: []string{
+			"encoding",
+			"encoding/json",
+			"errors",
+			"fmt",
+			"mime/multipart",
+			"net/http",
+			"reflect",
+			"regexp",
+			"strconv",
+			"strings",
+			"sync",
+			"github.com/go-playground/validator/v10",
+			echoImportPath,
+		},
+		CodeGens: []CodeGen{&RawCode{code: code}},
+	}
+}
@@ -0,0 +1,137 @@
+package golang
+
+import (
+	"strings"
+)
+
+const ginImportPath = "github.com/gin-gonic/gin"
+
+// ginServer registers routes to `*gin.RouterGroup`, the returned value is
+// responded as json
+type ginServer struct{}
+
+func (ginServer) ContextParams() []GoParam {
+	return []GoParam{{
+		Name: "c",
+		Type: &GoType{
+			Name:      "gin.Context",
+			IsPointer: true,
+		},
+	}}
+}
+
+func (ginServer) Imports() []string {
+	return []string{ginImportPath}
+}
+
+func (ginServer) RouteCode(route *RouteStatement) string {
+	code := sprintf("_r.Router.%s(\"%s\", ", strings.ToUpper(route.Method), route.Path)
+
+	for _, middleware := range route.Middlewares {
+		code += middleware + ", "
+	}
+
+	code += "_wrap(func(_c *gin.Context) error {\n"
+	bindPath := "_bindError(_c.ShouldBindUri(&_path))"
+	if name := wildcardParam(route.Path); name != "" {
+		bindPath = sprintf("_bindUri(_c, &_path, %q)", name)
+	}
+	code += indent(route.codeParams(bindPath, "_bindError(_c.ShouldBindQuery(&_query))"))
+	code += indent(route.codeHeaders("_bindError(_c.ShouldBindHeader(&_header))", "_bindCookies(_c, &_cookie)"))
+	// binds form values & files by the content type
+	code += indent(route.codeForm("_bindError(_c.ShouldBind(&_form))"))
+	if route.BodyParam != nil {
+		code += indent(route.codeBody(sprintf("_bindError(_c.ShouldBind(&%s))", route.BodyParam.Name),
+			"_c.Request.ContentLength"))
+	}
+	code += indent(route.codeCall([]string{"_c"}, "_c.Writer", "return _writeJSON(_c, %s)\n"))
+	code += "}))\n"
+	return code
+}
+
+func (ginServer) HelperFile(dir string, pkg string) GoFile {
+	code := `type ApiRoutes struct {
+	Router *gin.RouterGroup
+}
+
+` + validationCode + `
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		registerValidations(v)
+	}
+}
+
+` + responseCode + `
+` + streamCode + `
+// _wrap responds errors returned by f, which are also added to the context
+func _wrap(f func(*gin.Context) error) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if err := f(c); err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(_errorResponse(err))
+		}
+	}
+}
+
+func _bindError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &BindError{Err: err}
+}
+
+// _bindUri binds path params to dst, the leading ` + "`/`" + ` which gin keeps in the
+// wildcard param is trimmed, e.g. ` + "`a/b`" + ` instead of ` + "`/a/b`" + `
+func _bindUri(c *gin.Context, dst interface{}, wildcard string) error {
+	for i := range c.Params {
+		if c.Params[i].Key == wildcard {
+			c.Params[i].Value = strings.TrimPrefix(c.Params[i].Value, "/")
+		}
+	}
+	return _bindError(c.ShouldBindUri(dst))
+}
+
+func _writeJSON(c *gin.Context, v interface{}) error {
+	c.JSON(http.StatusOK, v)
+	return nil
+}
+
+` + setValuesCode + `
+// _bindCookies sets fields of dst to cookies named in the ` + "`cookie`" + ` tags,
+// then validates dst
+func _bindCookies(c *gin.Context, dst interface{}) error {
+	err := _setValues(dst, "cookie", func(name string) string {
+		v, _ := c.Cookie(name)
+		return v
+	})
+	if err == nil {
+		err = binding.Validator.ValidateStruct(dst)
+	}
+	return _bindError(err)
+}
+`
+	return GoFile{
+		Name:    sprintf("%s/zz_helper.go", dir),
+		Package: pkg,
+		Imports: []string{
+			"encoding",
+			"encoding/json",
+			"errors",
+			"fmt",
+			"net/http",
+			"reflect",
+			"regexp",
+			"strconv",
+			"strings",
+			"sync",
+			ginImportPath,
+			"github.com/gin-gonic/gin/binding",
+			"github.com/go-playground/validator/v10",
+		},
+		CodeGens: []CodeGen{
+			&RawCode{
+				code: code,
+			},
+		},
+	}
+}
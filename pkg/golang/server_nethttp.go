@@ -0,0 +1,215 @@
+package golang
+
+import (
+	"strings"
+)
+
+// netHTTPServer registers routes to `*http.ServeMux` with method & wildcard
+// patterns (requires go1.22), the returned value is responded as json
+type netHTTPServer struct{}
+
+func (netHTTPServer) ContextParams() []GoParam {
+	return []GoParam{
+		{
+			Name: "w",
+			Type: &GoType{Name: "http.ResponseWriter"},
+		},
+		{
+			Name: "r",
+			Type: &GoType{
+				Name:      "http.Request",
+				IsPointer: true,
+			},
+		},
+	}
+}
+
+func (netHTTPServer) Imports() []string {
+	return []string{"net/http"}
+}
+
+// serveMuxPath converts path in colon style to the pattern of ServeMux,
+// e.g. `/files/:id/*path` to `/files/{id}/{path...}`
+func serveMuxPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if len(part) > 1 && part[0] == ':' {
+			parts[i] = sprintf("{%s}", part[1:])
+		} else if len(part) > 1 && part[0] == '*' {
+			parts[i] = sprintf("{%s...}", part[1:])
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (netHTTPServer) RouteCode(route *RouteStatement) string {
+	return stdRouteCode(route, serveMuxPath(route.Path), "_req.PathValue")
+}
+
+// stdRouteCode registers route of path by `_r._handle`, path params are got
+// by getPath, e.g. `_req.PathValue`
+func stdRouteCode(route *RouteStatement, path string, getPath string) string {
+	code := sprintf("_r._handle(%q, %q, func(_w http.ResponseWriter, _req *http.Request) error {\n",
+		strings.ToUpper(route.Method), path)
+	code += indent(route.codeParams(sprintf("_bindValues(&_path, \"uri\", %s)", getPath),
+		"_bindValues(&_query, \"form\", _req.URL.Query().Get)"))
+	code += indent(route.codeHeaders("_bindValues(&_header, \"header\", _req.Header.Get)",
+		"_bindValues(&_cookie, \"cookie\", _getCookie(_req))"))
+	code += indent(route.codeForm("_bindForm(_req, &_form)"))
+	if route.BodyParam != nil {
+		bind := "_bindJSON"
+		if route.FormType != "" {
+			bind = "_bindForm"
+		}
+		code += indent(route.codeBody(sprintf("%s(_req, &%s)", bind, route.BodyParam.Name),
+			"_req.ContentLength"))
+	}
+	code += indent(route.codeCall([]string{"_w", "_req"}, "_w", "return _writeJSON(_w, %s)\n"))
+	code += "}"
+	for _, middleware := range route.Middlewares {
+		code += ", " + middleware
+	}
+	code += ")\n"
+	return code
+}
+
+func (netHTTPServer) HelperFile(dir string, pkg string) GoFile {
+	code := `type ApiRoutes struct {
+	// Mux defaults to http.DefaultServeMux
+	Mux *http.ServeMux
+	// Prefix is prepended to paths of routes, e.g. "/api"
+	Prefix string
+	// Middlewares wrap handlers of all the routes, the first one is outermost
+	Middlewares []func(http.Handler) http.Handler
+	// ErrorHandler responds errors returned by routes, defaults to
+	// DefaultErrorHandler
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func (r *ApiRoutes) _handle(method string, path string, f func(http.ResponseWriter, *http.Request) error,
+	middlewares ...func(http.Handler) http.Handler) {
+	var h http.Handler = _handler(f, r.ErrorHandler)
+	middlewares = append(append([]func(http.Handler) http.Handler{}, r.Middlewares...), middlewares...)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	mux := r.Mux
+	if mux == nil {
+		mux = http.DefaultServeMux
+	}
+	mux.Handle(method+" "+r.Prefix+path, h)
+}
+
+` + stdHelperCode
+	return GoFile{
+		Name:     sprintf("%s/zz_helper.go", dir),
+		Package:  pkg,
+		Imports:  append([]string{}, stdHelperImports...),
+		CodeGens: []CodeGen{&RawCode{code: code}},
+	}
+}
+
+// stdHelperCode binds requests & responds by the standard library, which is
+// shared by servers based on `net/http` handlers
+const stdHelperCode = responseCode + `
+` + streamCode + `
+// DefaultErrorHandler responds ErrorResponse with the status mapped by MapError
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := _errorResponse(err)
+	_writeStatusJSON(w, status, resp)
+}
+
+func _handler(f func(http.ResponseWriter, *http.Request) error,
+	errorHandler func(http.ResponseWriter, *http.Request, error)) http.HandlerFunc {
+	if errorHandler == nil {
+		errorHandler = DefaultErrorHandler
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := f(w, req); err != nil {
+			errorHandler(w, req, err)
+		}
+	}
+}
+
+` + validationCode + `
+` + validateCode + `
+` + setValuesCode + `
+` + formCode + `
+// _bindValues sets fields of dst to values got by names in tag, then
+// validates dst
+func _bindValues(dst interface{}, tag string, get func(string) string) error {
+	if err := _setValues(dst, tag, get); err != nil {
+		return &BindError{Err: err}
+	}
+	if err := _validate.Struct(dst); err != nil {
+		return &BindError{Err: err}
+	}
+	return nil
+}
+
+// _getCookie returns the getter of cookie values of r
+func _getCookie(r *http.Request) func(string) string {
+	return func(name string) string {
+		if c, err := r.Cookie(name); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+}
+
+// _bindForm sets fields of dst to the form of r, then validates dst
+func _bindForm(r *http.Request, dst interface{}) error {
+	form, err := _parseForm(r)
+	if err == nil {
+		err = _setForm(dst, form)
+	}
+	if err == nil {
+		err = _validateValue(reflect.ValueOf(dst))
+	}
+	if err != nil {
+		return &BindError{Err: err}
+	}
+	return nil
+}
+
+// _bindJSON decodes the json body into dst, then validates it
+func _bindJSON(r *http.Request, dst interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return &BindError{Err: err}
+	}
+	if err := _validateValue(reflect.ValueOf(dst)); err != nil {
+		return &BindError{Err: err}
+	}
+	return nil
+}
+
+func _writeJSON(w http.ResponseWriter, v interface{}) error {
+	return _writeStatusJSON(w, http.StatusOK, v)
+}
+
+func _writeStatusJSON(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+	return nil
+}
+`
+
+var stdHelperImports = []string{
+	"encoding",
+	"encoding/json",
+	"errors",
+	"fmt",
+	"mime/multipart",
+	"net/http",
+	"reflect",
+	"regexp",
+	"strconv",
+	"strings",
+	"sync",
+	"github.com/go-playground/validator/v10",
+}
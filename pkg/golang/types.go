@@ -30,6 +30,7 @@ type GoEnumCodeBlock struct {
 
 type GoType struct {
 	Name      string
+	TypeArgs  []GoType
 	KeyType   *GoType
 	ItemType  *GoType
 	IsPointer bool
@@ -43,9 +44,52 @@ type GoStructField struct {
 }
 
 type GoStructType struct {
+	Comments   []string
+	Name       string
+	TypeParams []string
+	Embeds     []GoType
+	Fields     []GoStructField
+	// fields with default values, and embedded structs & struct fields having
+	// defaults, which are set by the generated `SetDefaults` method
+	Defaults       []GoDefault
+	DefaultStructs []string
+}
+
+// GoDefault assigns default value to Target, e.g. `s.PageSize = 10`
+type GoDefault struct {
+	Target string
+	Value  string
+}
+
+// RouteDefault is the default value of route param, which is assigned before
+// binding values of In (e.g. "query"), so it's kept if the param is absent
+type RouteDefault struct {
+	GoDefault
+	In string
+}
+
+// GoUnionType is a struct wrapping one of the member types, which is
+// (un)marshaled according to the discriminator field
+type GoUnionType struct {
 	Comments []string
 	Name     string
-	Fields   []GoStructField
+	Members  []string
+	// json name of the discriminator field, empty if not specified
+	Discriminator string
+	// go name of the discriminator field
+	DiscriminatorField string
+}
+
+// GoErrorType is an error responded with Status, and Data (if DataType is
+// not nil) in the body
+type GoErrorType struct {
+	Comments []string
+	// declared name, e.g. `NotFound`
+	Name string
+	// Go type name, e.g. `NotFoundError`
+	TypeName string
+	Status   int
+	DataType *GoType
 }
 
 type GoParam struct {
@@ -75,16 +119,64 @@ type GoStatement interface {
 }
 
 type RouteStatement struct {
-	Comments    []string
-	Name        string
-	Method      string
+	Comments     []string
+	Name         string
+	Method       string
+	Path         string
+	Middlewares  []string
+	PathParams   []GoStructField
+	QueryParams  []GoStructField
+	HeaderParams []GoStructField
+	CookieParams []GoStructField
+	FormParams   []GoStructField
+	BodyParam    *GoParam
+	// content type of the form body, e.g. `multipart/form-data`, empty if
+	// the body is json
+	FormType string
+	// body param is struct with defaults
+	BodySetDefaults bool
+	// default value used if request body is empty
+	BodyDefault   string
+	ParamDefaults []RouteDefault
+	ParamExprs    []string
+	HasRet        bool
+	// returned value is wrapped in ResponseEnvelope
+	Envelope bool
+	// content type of the streamed response, empty if not streamed
+	StreamType string
+	// item type of server-sent events, nil if bytes are streamed
+	StreamItem *GoType
+	// server framework the route is registered to, gin if nil
+	Server Server
+}
+
+// ClientStatement sends request of a route by the generated api client
+type ClientStatement struct {
+	Method string
+	// path expression, e.g. `"/users/" + _pathParam(id, false)`
 	Path        string
-	Middlewares []string
-	PathParams  []GoStructField
-	QueryParams []GoStructField
-	BodyParam   *GoParam
-	ParamExprs  []string
-	HasRet      bool
+	QueryParams []ClientQueryParam
+	Headers     []ClientQueryParam
+	Cookies     []ClientQueryParam
+	Form        []ClientQueryParam
+	// content type of the form body, empty if the body is json
+	FormType string
+	// body param name, empty if no body
+	Body    string
+	RetType *GoType
+	// response is wrapped in ResponseEnvelope
+	Envelope bool
+	// content type of the streamed response, empty if not streamed
+	StreamType string
+	// item type of server-sent events, nil if bytes are streamed
+	StreamItem *GoType
+}
+
+// ClientQueryParam maps a query (or header, cookie) param name to the method
+// param
+type ClientQueryParam struct {
+	Name  string
+	Param string
 }
 
 type GoFile struct {
@@ -0,0 +1,1294 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	paramRefPrefix    = "#/components/parameters/"
+	bodyRefPrefix     = "#/components/requestBodies/"
+	responseRefPrefix = "#/components/responses/"
+	// heading of enum items in descriptions rendered by api1
+	enumItemsHeading = "### Items:\n"
+)
+
+var (
+	reIdentifier = regexp.MustCompile("^[A-Za-z_][0-9A-Za-z_]*$")
+	rePathParam  = regexp.MustCompile(`^\{([^{}]+)\}$`)
+	// m[1] = name, m[2] = value, m[3] = comment (maybe)
+	reEnumItem = regexp.MustCompile(`^- ([A-Za-z_][0-9A-Za-z_]*) \((.*)\)\s*(?::\s*(.*))?$`)
+)
+
+// LoadOpenAPI decodes an OpenAPI 3.x document of JSON or YAML
+func LoadOpenAPI(content []byte) (*OpenAPI, error) {
+	// YAML is a superset of JSON
+	var v interface{}
+	if err := yaml.Unmarshal(content, &v); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON or YAML")
+	}
+	b, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return nil, err
+	}
+	var doc OpenAPI
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid OpenAPI document")
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, errors.Errorf("OpenAPI version [%s] is not supported, 3.x is expected", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// jsonValue converts maps decoded from YAML, whose keys may be numbers (e.g.
+// status codes of responses), to maps of string keys
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = jsonValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = jsonValue(val)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonValue(v[i])
+		}
+	}
+	return v
+}
+
+// Importer converts an OpenAPI document to an api group: schemas of
+// components are imported as scalars, enums, structs & unions, and operations
+// as functions with `@route`, which are grouped into interfaces by their
+// first tags. Constructs which cannot be represented are skipped or
+// approximated, and reported as Problems.
+type Importer struct {
+	// Group is the name of the imported group
+	Group string
+	// Problems are constructs skipped or approximated, e.g.
+	// `schema [Pet]: anyOf is not supported, imported as [any]`
+	Problems []string
+
+	doc   *OpenAPI
+	group api1.ApiGroup
+	// component schemas by their names in the document
+	components map[string]*component
+	// declared type names, which are unique in the group
+	names map[string]bool
+	kinds map[string]api1.TypeKind
+	// openapi types of scalars
+	scalars map[string]string
+	// scalars of string formats, e.g. `date-time`
+	formats map[string]string
+	// option names of enums, keyed by the values
+	options map[string]map[string]string
+	// names of declared errors, keyed by names, statuses & payloads
+	errors map[string]string
+	// indexes of interfaces & function names used in them
+	ifaces   map[string]int
+	funNames map[string]map[string]bool
+}
+
+type component struct {
+	schema *Schema
+	// name of the declared type
+	name string
+	kind api1.TypeKind
+	// alias is the type which the component is replaced by if it's not
+	// declared, e.g. arrays
+	alias     *api1.TypeRef
+	resolving bool
+	skipped   bool
+}
+
+// Import converts doc to the content of an `*.api` file, which is checked
+// to be valid.
+func (im *Importer) Import(doc *OpenAPI) (string, error) {
+	im.doc = doc
+	im.group = api1.ApiGroup{}
+	im.group.Name = im.Group
+	im.components = make(map[string]*component)
+	im.names = make(map[string]bool)
+	im.kinds = make(map[string]api1.TypeKind)
+	im.scalars = make(map[string]string)
+	im.formats = make(map[string]string)
+	im.options = make(map[string]map[string]string)
+	im.errors = make(map[string]string)
+	im.ifaces = make(map[string]int)
+	im.funNames = make(map[string]map[string]bool)
+
+	im.setComments(&im.group.HasComments, doc.Info.Title+"\n"+doc.Info.Description, false)
+	im.importComponents()
+	im.importPaths()
+	im.fixDiscriminators()
+
+	content := api1.PrintGroup(&im.group)
+	parser := &api1.Parser{}
+	schema, err := parser.Parse(content)
+	if err == nil {
+		err = schema.SupplyRouteInfo()
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "imported api is invalid")
+	}
+	return content, nil
+}
+
+func (im *Importer) report(where string, format string, args ...interface{}) {
+	im.Problems = append(im.Problems, where+": "+fmt.Sprintf(format, args...))
+}
+
+func (im *Importer) importComponents() {
+	schemas := im.doc.Components.Schemas
+	var names []string
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// names are reserved first, since schemas refer to the ones after them
+	for _, name := range names {
+		s := schemas[name]
+		c := &component{schema: &s, kind: im.componentKind(&s)}
+		im.components[name] = c
+		switch {
+		case name == "any" && len(s.OneOf) > 0:
+			// rendered by api1 for the builtin type
+			c.kind = ""
+			c.alias = namedType("any")
+		case name == errorResponse && s.Properties["code"].Type != "" && s.Properties["message"].Type != "":
+			// rendered by api1 for errors, see importErrors
+			c.kind = ""
+			c.alias = namedType("any")
+			c.skipped = true
+		case c.kind != "":
+			c.name = im.reserve(upperFirst(identifier(name)), c.kind)
+			if c.kind == api1.TypeKindScalar && s.Type == "string" && s.Format != "" && im.formats[s.Format] == "" {
+				im.formats[s.Format] = c.name
+			}
+		}
+	}
+
+	// enums are declared first, whose options are used by default values
+	for _, kind := range []api1.TypeKind{api1.TypeKindEnum, ""} {
+		for _, name := range names {
+			c := im.components[name]
+			if (kind == api1.TypeKindEnum) != (c.kind == api1.TypeKindEnum) {
+				continue
+			}
+			where := "schema [" + name + "]"
+			switch c.kind {
+			case api1.TypeKindScalar:
+				im.declareScalar(c.name, c.schema)
+			case api1.TypeKindEnum:
+				im.declareEnum(c.name, c.schema, where)
+			case api1.TypeKindStruct:
+				im.declareStruct(c.name, c.schema, where)
+			case api1.TypeKindUnion:
+				im.declareUnion(c.name, c.schema, where)
+			default:
+				if c.alias == nil {
+					t := im.refType(refPrefix+name, where)
+					im.report(where, "schema is not declared as a type, but inlined as [%s] where it's used", t)
+				}
+			}
+		}
+	}
+}
+
+// componentKind returns the kind of type which schema s is declared as,
+// empty if s is replaced by its type where it's used, e.g. refs & arrays
+func (im *Importer) componentKind(s *Schema) api1.TypeKind {
+	switch {
+	case s.Ref != "" || len(s.AnyOf) > 0:
+		return ""
+	case len(s.Enum) > 0:
+		return api1.TypeKindEnum
+	case len(s.OneOf) == 1:
+		return ""
+	case len(s.OneOf) > 0:
+		for i := range s.OneOf {
+			if !im.isStructSchema(&s.OneOf[i]) {
+				return ""
+			}
+		}
+		return api1.TypeKindUnion
+	case len(s.AllOf) == 1 && len(s.Properties) == 0:
+		return im.componentKind(&s.AllOf[0])
+	case len(s.Properties) > 0 || len(s.AllOf) > 0:
+		return api1.TypeKindStruct
+	}
+	switch s.Type {
+	case "integer", "number", "string", "boolean":
+		return api1.TypeKindScalar
+	}
+	return ""
+}
+
+// isStructSchema reports whether s is (or refers to) an object of properties
+func (im *Importer) isStructSchema(s *Schema) bool {
+	if s.Ref != "" {
+		target, ok := im.doc.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+		return ok && strings.HasPrefix(s.Ref, refPrefix) && target.Ref == "" && im.componentKind(&target) == api1.TypeKindStruct
+	}
+	return len(s.Enum) == 0 && len(s.OneOf) == 0 && len(s.AnyOf) == 0 &&
+		(len(s.Properties) > 0 || len(s.AllOf) > 0)
+}
+
+// reserve returns a unique type name based on name
+func (im *Importer) reserve(name string, kind api1.TypeKind) string {
+	name = uniqueName(name, im.names)
+	im.kinds[name] = kind
+	return name
+}
+
+// typeOf returns the type of schema s, inline enums, objects & unions are
+// declared as types named by name
+func (im *Importer) typeOf(s *Schema, name string, where string) *api1.TypeRef {
+	t := im.baseType(s, name, where)
+	t.Nullable = t.Nullable || s.Nullable
+	return t
+}
+
+func (im *Importer) baseType(s *Schema, name string, where string) *api1.TypeRef {
+	if s.Ref != "" {
+		return im.refType(s.Ref, where)
+	}
+	if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+		// wrapped for siblings of $ref, e.g. defaults & constraints
+		return im.typeOf(&s.AllOf[0], name, where)
+	}
+	if len(s.OneOf) == 1 {
+		return im.typeOf(&s.OneOf[0], name, where)
+	}
+	if len(s.AnyOf) > 0 {
+		im.report(where, "anyOf is not supported, imported as [any]")
+		return namedType("any")
+	}
+	switch kind := im.componentKind(s); kind {
+	case api1.TypeKindEnum:
+		return namedType(im.declareEnum(im.reserve(name, kind), s, where))
+	case api1.TypeKindStruct:
+		return namedType(im.declareStruct(im.reserve(name, kind), s, where))
+	case api1.TypeKindUnion:
+		return namedType(im.declareUnion(im.reserve(name, kind), s, where))
+	}
+	if len(s.OneOf) > 0 {
+		im.report(where, "oneOf of schemas other than objects is not supported, imported as [any]")
+		return namedType("any")
+	}
+
+	switch s.Type {
+	case "integer":
+		return namedType("int")
+	case "number":
+		return namedType("float")
+	case "boolean":
+		return namedType("boolean")
+	case "string":
+		if s.Format == "" {
+			return namedType("string")
+		}
+		sc, ok := im.formats[s.Format]
+		if !ok {
+			sc = im.declareScalar(im.reserve(formatScalarName(s.Format), api1.TypeKindScalar),
+				&Schema{Type: s.Type, Format: s.Format})
+			im.formats[s.Format] = sc
+		}
+		return namedType(sc)
+	case "array":
+		if s.Items == nil {
+			return &api1.TypeRef{ItemType: namedType("any")}
+		}
+		return &api1.TypeRef{ItemType: im.typeOf(s.Items, name+"Item", where)}
+	case "object":
+		if s.AdditionalProperties != nil {
+			return &api1.TypeRef{
+				KeyType:  namedType("string"),
+				ItemType: im.typeOf(s.AdditionalProperties, name+"Value", where),
+			}
+		}
+		return namedType("object")
+	}
+	return namedType("any")
+}
+
+// refType returns the type of component schema referred by ref
+func (im *Importer) refType(ref string, where string) *api1.TypeRef {
+	c, ok := im.components[strings.TrimPrefix(ref, refPrefix)]
+	if !ok || !strings.HasPrefix(ref, refPrefix) {
+		im.report(where, "reference [%s] cannot be resolved, imported as [any]", ref)
+		return namedType("any")
+	}
+	if c.name != "" {
+		return namedType(c.name)
+	}
+	if c.alias == nil {
+		if c.resolving {
+			im.report(where, "reference [%s] is circular, imported as [any]", ref)
+			return namedType("any")
+		}
+		c.resolving = true
+		name := strings.TrimPrefix(ref, refPrefix)
+		c.alias = im.typeOf(c.schema, upperFirst(identifier(name)), "schema ["+name+"]")
+		c.resolving = false
+	}
+	t := *c.alias
+	return &t
+}
+
+func (im *Importer) isBodyType(t *api1.TypeRef) bool {
+	if t.ItemType != nil || t.Name == "object" || t.Name == "any" {
+		return true
+	}
+	kind := im.kinds[t.Name]
+	return kind == api1.TypeKindStruct || kind == api1.TypeKindUnion
+}
+
+func (im *Importer) declareScalar(name string, s *Schema) string {
+	var sc api1.ScalarType
+	sc.Name = name
+	im.setComments(&sc.HasComments, s.Description, s.Deprecated)
+	goType := map[string]string{"integer": "int64", "number": "float64", "boolean": "bool"}[s.Type]
+	switch {
+	case s.Type != "string":
+	case s.Format == "date-time":
+		goType = "time/Time"
+	case s.Format == "binary":
+		goType = "*multipart.FileHeader"
+		sc.AddSemComment("go.typePkg", "mime/multipart")
+	case s.Format == "byte":
+		goType = "[]byte"
+	default:
+		goType = "string"
+	}
+	sc.AddSemComment("go.type", goType)
+	sc.AddSemComment("openapi.type", s.Type)
+	if s.Format != "" {
+		sc.AddSemComment("openapi.format", s.Format)
+	}
+	im.scalars[name] = s.Type
+	im.group.ScalarTypes = append(im.group.ScalarTypes, sc)
+	return name
+}
+
+// formatScalarName returns name of the scalar of string format, e.g. `File`
+// for binary
+func formatScalarName(format string) string {
+	if format == "binary" {
+		return "File"
+	}
+	return upperFirst(identifier(format))
+}
+
+func (im *Importer) declareEnum(name string, s *Schema, where string) string {
+	var en api1.EnumType
+	en.Name = name
+	// items described by api1, e.g. `- Admin (1) : the admin`
+	description := s.Description
+	itemNames := make(map[string]string)
+	itemComments := make(map[string]string)
+	if i := strings.Index(description, enumItemsHeading); i >= 0 {
+		for _, line := range strings.Split(description[i+len(enumItemsHeading):], "\n") {
+			if m := reEnumItem.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				itemNames[m[2]] = m[1]
+				itemComments[m[2]] = m[3]
+			}
+		}
+		description = description[:i]
+	}
+	im.setComments(&en.HasComments, description, s.Deprecated)
+
+	used := make(map[string]bool)
+	options := make(map[string]string)
+	for _, v := range s.Enum {
+		var o api1.EnumOption
+		switch v := v.(type) {
+		case string:
+			o.Name = identifier(v)
+			if v == "" {
+				o.Name = "Empty"
+			}
+			o.Value = &api1.IntOrString{StrVal: &v}
+		case float64:
+			if v != math.Trunc(v) {
+				im.report(where, "enum value [%v] is not an integer or string, skipped", v)
+				continue
+			}
+			n := int64(v)
+			o.Name = "Value" + strings.Replace(strconv.FormatInt(n, 10), "-", "Minus", 1)
+			o.Value = &api1.IntOrString{IntVal: &n}
+		case nil:
+			// null of nullable enums
+			continue
+		default:
+			im.report(where, "enum value [%v] is not an integer or string, skipped", v)
+			continue
+		}
+		value := fmt.Sprint(v)
+		if itemName, ok := itemNames[value]; ok {
+			o.Name = itemName
+			im.setComments(&o.HasComments, itemComments[value], false)
+		}
+		o.Name = uniqueName(o.Name, used)
+		if o.Value.StrVal != nil && o.Name == *o.Value.StrVal {
+			// the value defaults to the name
+			o.Value = nil
+		}
+		options[value] = o.Name
+		en.Options = append(en.Options, o)
+	}
+	im.options[name] = options
+	im.group.EnumTypes = append(im.group.EnumTypes, en)
+	return name
+}
+
+func (im *Importer) declareStruct(name string, s *Schema, where string) string {
+	// the slot is taken first, so that the struct is declared before types
+	// of its fields
+	i := len(im.group.StructTypes)
+	im.group.StructTypes = append(im.group.StructTypes, api1.StructType{})
+	var st api1.StructType
+	st.Name = name
+	im.setComments(&st.HasComments, s.Description, s.Deprecated)
+
+	properties := make(map[string]Schema)
+	required := make(map[string]bool)
+	add := func(part *Schema) {
+		for key, property := range part.Properties {
+			properties[key] = property
+		}
+		for _, key := range part.Required {
+			required[key] = true
+		}
+		if part.AdditionalProperties != nil {
+			im.report(where, "additionalProperties of objects with properties is not supported, ignored")
+		}
+		if part.MinProperties != nil || part.MaxProperties != nil {
+			im.report(where, "minProperties & maxProperties are not supported, ignored")
+		}
+	}
+	for j := range s.AllOf {
+		part := &s.AllOf[j]
+		switch {
+		case part.Ref != "":
+			if t := im.refType(part.Ref, where); im.kinds[t.Name] == api1.TypeKindStruct {
+				st.Extends = append(st.Extends, *t)
+			} else {
+				im.report(where, "allOf member [%s] is not an object, skipped", part.Ref)
+			}
+		case len(part.AllOf) > 0 || len(part.OneOf) > 0 || len(part.AnyOf) > 0:
+			im.report(where, "nested composition in allOf is not supported, skipped")
+		default:
+			add(part)
+		}
+	}
+	add(s)
+
+	var keys []string
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	used := make(map[string]bool)
+	for _, key := range keys {
+		property := properties[key]
+		propertyWhere := where + " property [" + key + "]"
+		var f api1.StructField
+		f.Name = uniqueName(identifier(key), used)
+		if f.Name != key {
+			im.report(where, "property [%s] is renamed to [%s]", key, f.Name)
+		}
+		f.Type = im.typeOf(&property, name+upperFirst(f.Name), propertyWhere)
+		f.Default = im.literal(property.Default, f.Type, propertyWhere)
+		f.Type.Nullable = f.Type.Nullable || (!required[key] && f.Default == nil)
+		im.setComments(&f.HasComments, property.Description, property.Deprecated)
+		im.setConstraints(&f.HasComments, &property, f.Type, propertyWhere)
+		st.Fields = append(st.Fields, f)
+	}
+	im.group.StructTypes[i] = st
+	return name
+}
+
+func (im *Importer) declareUnion(name string, s *Schema, where string) string {
+	var un api1.UnionType
+	un.Name = name
+	im.setComments(&un.HasComments, s.Description, s.Deprecated)
+	for i := range s.OneOf {
+		member := &s.OneOf[i]
+		memberName := member.Title
+		if memberName == "" {
+			memberName = name + strconv.Itoa(i+1)
+		}
+		un.Types = append(un.Types, *im.typeOf(member, upperFirst(identifier(memberName)), where))
+	}
+	if d := s.Discriminator; d != nil {
+		un.AddSemComment("discriminator", d.PropertyName)
+		var values []string
+		for value := range d.Mapping {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			if t := im.refType(d.Mapping[value], where); t.Name != value {
+				im.report(where, "discriminator value [%s] of [%s] is not kept, which is the type name [%s]",
+					value, d.Mapping[value], t.Name)
+			}
+		}
+	}
+	im.group.UnionTypes = append(im.group.UnionTypes, un)
+	return name
+}
+
+// fixDiscriminators makes discriminator fields of union members non-null,
+// which may be optional in OpenAPI, the discriminator is dropped if it's not
+// a string field of all the members
+func (im *Importer) fixDiscriminators() {
+	for i := range im.group.UnionTypes {
+		un := &im.group.UnionTypes[i]
+		d, _ := un.SemComments["discriminator"].(string)
+		if d == "" {
+			continue
+		}
+		var fields []*api1.StructField
+		for _, t := range un.Types {
+			if f := im.findField(t.Name, d, 0); f != nil && f.Type.Name == "string" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) < len(un.Types) {
+			im.report("schema ["+un.Name+"]", "discriminator [%s] is not a string property of all the members, ignored", d)
+			delete(un.SemComments, "discriminator")
+			continue
+		}
+		for _, f := range fields {
+			f.Type.Nullable = false
+		}
+	}
+}
+
+// findField finds field of the declared struct, including inherited ones
+func (im *Importer) findField(structName string, name string, depth int) *api1.StructField {
+	for i := range im.group.StructTypes {
+		st := &im.group.StructTypes[i]
+		if st.Name != structName || depth > 8 {
+			continue
+		}
+		for j := range st.Fields {
+			if st.Fields[j].Name == name {
+				return &st.Fields[j]
+			}
+		}
+		for _, parent := range st.Extends {
+			if f := im.findField(parent.Name, name, depth+1); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// literal returns the default value v of type t, nil if it's not supported
+func (im *Importer) literal(v interface{}, t *api1.TypeRef, where string) *api1.Literal {
+	if v == nil {
+		return nil
+	}
+	unsupported := func() *api1.Literal {
+		im.report(where, "default value [%v] of [%s] is not supported, ignored", v, t)
+		return nil
+	}
+	if t.Name == "" {
+		items, ok := v.([]interface{})
+		if t.KeyType != nil || !ok {
+			return unsupported()
+		}
+		l := &api1.Literal{Kind: api1.LiteralArray}
+		for _, item := range items {
+			itemLiteral := im.literal(item, t.ItemType, where)
+			if itemLiteral == nil {
+				return nil
+			}
+			l.Items = append(l.Items, *itemLiteral)
+		}
+		return l
+	}
+	if options, ok := im.options[t.Name]; ok {
+		if name, ok := options[fmt.Sprint(v)]; ok {
+			return &api1.Literal{Kind: api1.LiteralIdent, Text: name}
+		}
+		return unsupported()
+	}
+	kind := t.Name
+	if typ, ok := im.scalars[t.Name]; ok {
+		kind = map[string]string{"integer": "int", "number": "float"}[typ]
+		if kind == "" {
+			kind = typ
+		}
+	}
+	switch v := v.(type) {
+	case bool:
+		if kind == "boolean" || kind == "any" {
+			return &api1.Literal{Kind: api1.LiteralBool, Text: strconv.FormatBool(v)}
+		}
+	case float64:
+		if v == math.Trunc(v) && (kind == "int" || kind == "float" || kind == "any") {
+			return &api1.Literal{Kind: api1.LiteralInt, Text: strconv.FormatInt(int64(v), 10)}
+		}
+		if kind == "float" || kind == "any" {
+			return &api1.Literal{Kind: api1.LiteralFloat, Text: strconv.FormatFloat(v, 'f', -1, 64)}
+		}
+	case string:
+		if kind == "string" || kind == "any" {
+			return &api1.Literal{Kind: api1.LiteralString, Text: v}
+		}
+	}
+	return unsupported()
+}
+
+// setConstraints adds validation constraints of s as semantic comments
+func (im *Importer) setConstraints(c *api1.HasComments, s *Schema, t *api1.TypeRef, where string) {
+	kind := "array"
+	if t.Name != "" {
+		kind = map[string]string{"int": "integer", "float": "number", "string": "string"}[t.Name]
+		if typ, ok := im.scalars[t.Name]; ok {
+			kind = typ
+		}
+	} else if t.KeyType != nil {
+		kind = ""
+	}
+	add := func(key string, kinds string, val string) {
+		if kind != "" && strings.Contains(kinds, kind+",") {
+			c.AddSemComment(key, val)
+		} else {
+			im.report(where, "constraint [%s] of [%s] is not supported, ignored", key, t)
+		}
+	}
+	if s.Minimum != nil && s.ExclusiveMinimum == nil {
+		add("minimum", "integer,number,", strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+	}
+	if s.Maximum != nil && s.ExclusiveMaximum == nil {
+		add("maximum", "integer,number,", strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+	}
+	if s.MinLength != nil {
+		add("minLength", "string,", strconv.Itoa(*s.MinLength))
+	}
+	if s.MaxLength != nil {
+		add("maxLength", "string,", strconv.Itoa(*s.MaxLength))
+	}
+	if s.Pattern != "" {
+		add("pattern", "string,", s.Pattern)
+	}
+	if s.MinItems != nil {
+		add("minItems", "array,", strconv.Itoa(*s.MinItems))
+	}
+	if s.MaxItems != nil {
+		add("maxItems", "array,", strconv.Itoa(*s.MaxItems))
+	}
+	for key, set := range map[string]bool{
+		"exclusiveMinimum": s.ExclusiveMinimum != nil,
+		"exclusiveMaximum": s.ExclusiveMaximum != nil,
+		"multipleOf":       s.MultipleOf != nil,
+		"uniqueItems":      s.UniqueItems != nil && *s.UniqueItems,
+	} {
+		if set {
+			im.report(where, "constraint [%s] is not supported, ignored", key)
+		}
+	}
+}
+
+// setComments sets lines of description as comments, blank lines are dropped
+func (im *Importer) setComments(c *api1.HasComments, description string, deprecated bool) {
+	for _, line := range strings.Split(description, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			c.Comments = append(c.Comments, line)
+		}
+	}
+	if deprecated {
+		c.AddSemComment("deprecated", "")
+	}
+}
+
+func (im *Importer) importPaths() {
+	var paths []string
+	for path := range im.doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := im.doc.Paths[path]
+		for _, method := range []Method{MethodGet, MethodPut, MethodPost, MethodDelete,
+			MethodOptions, MethodHead, MethodPatch, MethodTrace} {
+			if op, ok := item[method]; ok {
+				im.importOperation(path, method, &op)
+			}
+		}
+	}
+}
+
+func (im *Importer) importOperation(path string, method Method, op *Operation) {
+	where := strings.ToUpper(string(method)) + " " + path
+
+	// path params are renamed to identifiers, e.g. `{user-id}` to `:userId`
+	pathParams := make(map[string]string)
+	used := make(map[string]bool)
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "{}") {
+			m := rePathParam.FindStringSubmatch(part)
+			if m == nil {
+				im.report(where, "path segment [%s] is not supported, operation skipped", part)
+				return
+			}
+			name := uniqueName(lowerFirst(identifier(m[1])), used)
+			pathParams[m[1]] = name
+			parts[i] = ":" + name
+		}
+	}
+
+	iface := im.iface(op.Tags)
+	var fun api1.Fun
+	name := op.OperationID
+	if name == "" {
+		name = string(method)
+		for _, part := range strings.Split(path, "/") {
+			if m := rePathParam.FindStringSubmatch(part); m != nil {
+				name += "By" + upperFirst(identifier(m[1]))
+			} else if part != "" {
+				name += upperFirst(identifier(part))
+			}
+		}
+	}
+	fun.Name = uniqueName(lowerFirst(identifier(name)), im.funNames[iface.Name])
+	im.setComments(&fun.HasComments, op.Description, op.Deprecated)
+	if op.Summary != "" {
+		fun.AddSemComment("summary", strings.TrimSpace(op.Summary))
+	}
+	fun.AddSemComment("route", strings.ToUpper(string(method))+" "+strings.Join(parts, "/"))
+
+	typeName := upperFirst(fun.Name)
+	declared := make(map[string]bool)
+	for _, p := range op.Parameters {
+		param, ok := im.parameter(p, pathParams, used, typeName, where)
+		if ok && !declared[param.Name] {
+			declared[param.Name] = true
+			fun.Params = append(fun.Params, *param)
+		}
+	}
+	var undeclared []string
+	for wireName, name := range pathParams {
+		if !declared[name] {
+			undeclared = append(undeclared, wireName)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, wireName := range undeclared {
+		im.report(where, "path param [%s] is not declared, imported as [string]", wireName)
+		var param api1.Param
+		param.Name = pathParams[wireName]
+		param.Type = namedType("string")
+		fun.Params = append(fun.Params, param)
+	}
+	if op.RequestBody != nil {
+		fun.Params = append(fun.Params, im.requestBody(&fun, op.RequestBody, used, typeName, where)...)
+	}
+	im.importResponses(&fun, op.Responses, typeName, where)
+	iface.Funs = append(iface.Funs, fun)
+}
+
+// iface returns the interface of the first tag, or the default interface of
+// the group if there's no tag
+func (im *Importer) iface(tags []string) *api1.Iface {
+	name := upperFirst(identifier(im.Group)) + "Api"
+	if len(tags) > 0 {
+		name = upperFirst(identifier(tags[0]))
+	}
+	if i, ok := im.ifaces[name]; ok {
+		return &im.group.Ifaces[i]
+	}
+	var iface api1.Iface
+	iface.Name = name
+	if im.names[name] {
+		// interfaces are generated along with types in a package
+		iface.Name = uniqueName(name+"Api", im.names)
+	} else {
+		im.names[name] = true
+	}
+	im.ifaces[name] = len(im.group.Ifaces)
+	im.funNames[iface.Name] = make(map[string]bool)
+	im.group.Ifaces = append(im.group.Ifaces, iface)
+	return &im.group.Ifaces[len(im.group.Ifaces)-1]
+}
+
+// parameter imports the path, query, header or cookie param p
+func (im *Importer) parameter(p Parameter, pathParams map[string]string, used map[string]bool,
+	typeName string, where string) (*api1.Param, bool) {
+	for i := 0; p.Ref != "" && i < 8; i++ {
+		ref, ok := im.doc.Components.Parameters[strings.TrimPrefix(p.Ref, paramRefPrefix)]
+		if !ok || !strings.HasPrefix(p.Ref, paramRefPrefix) {
+			im.report(where, "reference [%s] cannot be resolved, param skipped", p.Ref)
+			return nil, false
+		}
+		p = ref
+	}
+	if p.Schema == nil {
+		im.report(where, "%s param [%s] has no schema, skipped", p.In, p.Name)
+		return nil, false
+	}
+
+	var param api1.Param
+	name := lowerFirst(identifier(p.Name))
+	switch p.In {
+	case PositionPath:
+		if pathParams[p.Name] == "" {
+			im.report(where, "path param [%s] is not in the path, skipped", p.Name)
+			return nil, false
+		}
+		param.Name = pathParams[p.Name]
+	case PositionQuery, PositionHeader, PositionCookie:
+		param.Name = uniqueName(name, used)
+		if param.Name != p.Name {
+			param.AddSemComment("in", string(p.In)+" "+p.Name)
+		} else if p.In != PositionQuery {
+			param.AddSemComment("in", string(p.In))
+		}
+	default:
+		im.report(where, "param [%s] in [%s] is not supported, skipped", p.Name, p.In)
+		return nil, false
+	}
+	param.Type = im.typeOf(p.Schema, typeName+upperFirst(name), where)
+	if im.isBodyType(param.Type) {
+		im.report(where, "%s param [%s] of type [%s] is not supported, skipped", p.In, p.Name, param.Type)
+		return nil, false
+	}
+	param.Default = im.literal(p.Schema.Default, param.Type, where)
+	if p.In != PositionPath {
+		param.Type.Nullable = param.Type.Nullable || (!p.Required && param.Default == nil)
+	}
+	im.setComments(&param.HasComments, p.Description, p.Deprecated)
+	im.setConstraints(&param.HasComments, p.Schema, param.Type, where+" param ["+p.Name+"]")
+	return &param, true
+}
+
+// requestBody imports the json body as a param, or the form fields as params
+func (im *Importer) requestBody(fun *api1.Fun, body *RequestBody, used map[string]bool,
+	typeName string, where string) []api1.Param {
+	for i := 0; body.Ref != "" && i < 8; i++ {
+		ref, ok := im.doc.Components.RequestBodies[strings.TrimPrefix(body.Ref, bodyRefPrefix)]
+		if !ok || !strings.HasPrefix(body.Ref, bodyRefPrefix) {
+			im.report(where, "reference [%s] cannot be resolved, request body skipped", body.Ref)
+			return nil
+		}
+		body = &ref
+	}
+	contentType, media := pickContent(body.Content)
+	if len(body.Content) > 1 {
+		im.report(where, "request body is imported as [%s], other content types are ignored", contentType)
+	}
+	s := media.Schema
+	if s == nil {
+		s = &Schema{}
+	}
+
+	switch {
+	case contentType == "":
+		return nil
+	case isJSON(contentType):
+		var param api1.Param
+		param.Type = im.typeOf(s, typeName+"Body", where)
+		if !im.isBodyType(param.Type) {
+			im.report(where, "request body of type [%s] is not supported, skipped", param.Type)
+			return nil
+		}
+		name := "body"
+		if s.Ref != "" && param.Type.Name != "" {
+			name = lowerFirst(param.Type.Name)
+		}
+		param.Name = uniqueName(name, used)
+		param.Default = im.literal(s.Default, param.Type, where)
+		param.Type.Nullable = param.Type.Nullable || (!body.Required && param.Default == nil)
+		im.setComments(&param.HasComments, body.Description, false)
+		return []api1.Param{param}
+	case contentType == api1.MimeFormData || contentType == api1.MimeFormUrlencoded:
+		fun.AddSemComment("accept", contentType)
+		if st := im.formStruct(s, where); st != nil {
+			// the whole form is a struct of `@form`
+			var param api1.Param
+			param.Name = uniqueName(lowerFirst(st.Name), used)
+			param.Type = namedType(st.Name)
+			param.Type.Nullable = !body.Required
+			im.setComments(&param.HasComments, body.Description, false)
+			return []api1.Param{param}
+		}
+		properties, required := im.properties(s, where)
+		var keys []string
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var params []api1.Param
+		for _, key := range keys {
+			property := properties[key]
+			var param api1.Param
+			param.Name = uniqueName(identifier(key), used)
+			if param.Name != key {
+				im.report(where, "form field [%s] is renamed to [%s]", key, param.Name)
+			}
+			param.Type = im.typeOf(&property, typeName+upperFirst(param.Name), where)
+			param.Default = im.literal(property.Default, param.Type, where)
+			param.Type.Nullable = param.Type.Nullable || (!required[key] && param.Default == nil)
+			im.setComments(&param.HasComments, property.Description, property.Deprecated)
+			im.setConstraints(&param.HasComments, &property, param.Type, where+" form field ["+key+"]")
+			params = append(params, param)
+		}
+		return params
+	}
+	im.report(where, "request body of [%s] is not supported, skipped", contentType)
+	return nil
+}
+
+// formStruct returns the declared struct which form body s refers to, which
+// is marked by `@form`, nil if s is not a ref of struct
+func (im *Importer) formStruct(s *Schema, where string) *api1.StructType {
+	c, ok := im.components[strings.TrimPrefix(s.Ref, refPrefix)]
+	if s.Ref == "" || !ok || c.kind != api1.TypeKindStruct {
+		return nil
+	}
+	for i := range im.group.StructTypes {
+		if st := &im.group.StructTypes[i]; st.Name == c.name {
+			if _, ok := st.SemComments["form"]; !ok {
+				st.AddSemComment("form", "")
+			}
+			return st
+		}
+	}
+	return nil
+}
+
+// properties returns properties of object s, including the ones of allOf
+func (im *Importer) properties(s *Schema, where string) (map[string]Schema, map[string]bool) {
+	properties := make(map[string]Schema)
+	required := make(map[string]bool)
+	var add func(s *Schema, depth int)
+	add = func(s *Schema, depth int) {
+		if s.Ref != "" {
+			target, ok := im.doc.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+			if !ok || depth > 8 {
+				im.report(where, "reference [%s] cannot be resolved, skipped", s.Ref)
+				return
+			}
+			s = &target
+		}
+		for i := range s.AllOf {
+			add(&s.AllOf[i], depth+1)
+		}
+		for key, property := range s.Properties {
+			properties[key] = property
+		}
+		for _, key := range s.Required {
+			required[key] = true
+		}
+	}
+	add(s, 0)
+	return properties, required
+}
+
+// importResponses imports the first successful response as the returned
+// value or stream, and responses of 4xx & 5xx as errors in `@throws`
+func (im *Importer) importResponses(fun *api1.Fun, responses Responses, typeName string, where string) {
+	var statuses []string
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	var throws []string
+	succeeded := false
+	for _, status := range statuses {
+		code, err := strconv.Atoi(status)
+		if err != nil && status != "default" {
+			im.report(where, "response of status [%s] is not supported, skipped", status)
+			continue
+		}
+		resp := responses[status]
+		for i := 0; resp.Ref != "" && i < 8; i++ {
+			ref, ok := im.doc.Components.Responses[strings.TrimPrefix(resp.Ref, responseRefPrefix)]
+			if !ok || !strings.HasPrefix(resp.Ref, responseRefPrefix) {
+				im.report(where, "reference [%s] cannot be resolved, response skipped", resp.Ref)
+				break
+			}
+			resp = ref
+		}
+		if status == "default" {
+			// errors of any status are not represented, except ErrorResponse
+			// rendered by api1, which any function may respond
+			if len(resp.Content) > 0 && !im.isDefaultErrorResponse(&resp) {
+				im.report(where, "default response is not supported, skipped")
+			}
+			continue
+		}
+		switch {
+		case code >= 200 && code < 300:
+			if succeeded {
+				if len(resp.Content) > 0 {
+					im.report(where, "response of status [%s] is ignored, only the first successful one is imported", status)
+				}
+				continue
+			}
+			succeeded = true
+			im.importResponse(fun, &resp, typeName, where)
+		case code >= 400 && code < 600:
+			throws = append(throws, im.importErrors(code, &resp, where)...)
+		}
+	}
+	if len(throws) > 0 {
+		fun.AddSemComment("throws", strings.Join(throws, ", "))
+	}
+}
+
+func (im *Importer) importResponse(fun *api1.Fun, resp *Response, typeName string, where string) {
+	contentType, media := pickContent(resp.Content)
+	if len(resp.Content) > 1 {
+		im.report(where, "response is imported as [%s], other content types are ignored", contentType)
+	}
+	s := media.Schema
+	switch {
+	case contentType == "":
+	case isJSON(contentType):
+		if s == nil {
+			s = &Schema{}
+		}
+		if data, ok := envelopeData(s); ok {
+			fun.AddSemComment("response", string(api1.ResponseEnvelope))
+			if s = data; s == nil {
+				return
+			}
+		}
+		fun.Type = im.typeOf(s, typeName+"Response", where)
+	case contentType == api1.MimeEventStream && s != nil && s.Type != "string":
+		fun.Stream = &api1.Stream{Item: im.typeOf(s, typeName+"Event", where)}
+	default:
+		fun.Stream = &api1.Stream{}
+		if contentType != api1.MimeOctetStream {
+			fun.AddSemComment("produces", contentType)
+		}
+	}
+}
+
+// envelopeData returns data of the envelope `{"code": 0, "data": ...,
+// "message": "ok"}` of `@response envelope`
+func envelopeData(s *Schema) (*Schema, bool) {
+	if s.Ref != "" || s.Properties["code"].Type != "integer" || s.Properties["message"].Type != "string" {
+		return nil, false
+	}
+	data, ok := s.Properties["data"]
+	switch {
+	case ok && len(s.Properties) == 3:
+		return &data, true
+	case !ok && len(s.Properties) == 2:
+		return nil, true
+	}
+	return nil, false
+}
+
+// importErrors declares errors of the response, returns their names. Errors
+// rendered by api1 are ErrorResponse of the error name & data, other ones
+// are named by the status, whose payloads are the objects responded.
+func (im *Importer) importErrors(status int, resp *Response, where string) []string {
+	_, media := pickContent(resp.Content)
+	s := media.Schema
+	members := []Schema{}
+	if s != nil {
+		members = append(members, *s)
+		if len(s.OneOf) > 0 {
+			members = s.OneOf
+		}
+	}
+	var names []string
+	for _, m := range members {
+		if len(m.AllOf) != 2 || !im.isErrorResponse(&m.AllOf[0]) {
+			break
+		}
+		enum := m.AllOf[1].Properties["error"].Enum
+		name, ok := "", len(enum) == 1
+		if ok {
+			name, ok = enum[0].(string)
+		}
+		if !ok || !reIdentifier.MatchString(name) {
+			break
+		}
+		var payload *api1.TypeRef
+		if data, ok := m.AllOf[1].Properties["data"]; ok {
+			payload = im.payload(&data, name, where)
+		}
+		description := ""
+		if len(members) == 1 && resp.Description != name {
+			description = resp.Description
+		}
+		names = append(names, im.declareError(name, status, payload, description))
+	}
+	if len(names) == len(members) && len(names) > 0 {
+		return names
+	}
+
+	name := upperFirst(identifier(http.StatusText(status)))
+	if http.StatusText(status) == "" {
+		name = "Error" + strconv.Itoa(status)
+	}
+	var payload *api1.TypeRef
+	if s != nil && !im.isErrorResponse(s) {
+		payload = im.payload(s, name, where)
+	}
+	return []string{im.declareError(name, status, payload, resp.Description)}
+}
+
+// isDefaultErrorResponse reports whether resp is the default response
+// rendered by api1, i.e. ErrorResponse in json
+func (im *Importer) isDefaultErrorResponse(resp *Response) bool {
+	media, ok := resp.Content[mimeJson]
+	return ok && len(resp.Content) == 1 && media.Schema != nil && im.isErrorResponse(media.Schema)
+}
+
+// isErrorResponse reports whether s refers to ErrorResponse rendered by api1
+func (im *Importer) isErrorResponse(s *Schema) bool {
+	c, ok := im.components[errorResponse]
+	return ok && c.skipped && s.Ref == refPrefix+errorResponse
+}
+
+// payload returns the payload type of error, nil if it's not a struct
+func (im *Importer) payload(s *Schema, name string, where string) *api1.TypeRef {
+	t := im.typeOf(s, name+"Detail", where)
+	if im.kinds[t.Name] != api1.TypeKindStruct || t.Nullable {
+		im.report(where, "payload of error [%s] is not an object but [%s], ignored", name, t)
+		return nil
+	}
+	return t
+}
+
+// declareError declares the error or reuses the declared one of the same
+// status & payload, returns its name
+func (im *Importer) declareError(name string, status int, payload *api1.TypeRef, description string) string {
+	key := name + " " + strconv.Itoa(status)
+	if payload != nil {
+		key += " " + payload.String()
+	}
+	if declared, ok := im.errors[key]; ok {
+		return declared
+	}
+	var er api1.ErrorType
+	er.Name = im.reserve(name, "")
+	im.errors[key] = er.Name
+	er.Status = status
+	er.Payload = payload
+	if description != http.StatusText(status) {
+		im.setComments(&er.HasComments, description, false)
+	}
+	im.group.ErrorTypes = append(im.group.ErrorTypes, er)
+	return er.Name
+}
+
+// pickContent returns the content of json preferably, or of forms, or the
+// first one by content types
+func pickContent(content map[string]MediaType) (string, MediaType) {
+	var contentTypes []string
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	for _, match := range []func(string) bool{
+		isJSON,
+		func(ct string) bool { return ct == api1.MimeFormData || ct == api1.MimeFormUrlencoded },
+		func(string) bool { return true },
+	} {
+		for _, contentType := range contentTypes {
+			if match(contentType) {
+				return contentType, content[contentType]
+			}
+		}
+	}
+	return "", MediaType{}
+}
+
+func isJSON(contentType string) bool {
+	return contentType == mimeJson || strings.HasSuffix(contentType, "+json") || contentType == "*/*"
+}
+
+func namedType(name string) *api1.TypeRef {
+	t := &api1.TypeRef{}
+	t.Name = name
+	return t
+}
+
+// identifier converts s to an identifier, words separated by other chars are
+// joined in camel case, e.g. `user-name` to `userName`, valid identifiers are
+// kept as is
+func identifier(s string) string {
+	if reIdentifier.MatchString(s) {
+		return s
+	}
+	var b strings.Builder
+	upper := false
+	for _, r := range s {
+		isDigit := r >= '0' && r <= '9'
+		if !(isDigit || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && isDigit {
+			b.WriteByte('_')
+		} else if upper && b.Len() > 0 && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		b.WriteRune(r)
+		upper = false
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func upperFirst(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
+
+// lowerFirst lowers the leading upper case letters, the last of which is
+// kept if it begins the next word, e.g. `HTTPServer` to `httpServer`
+func lowerFirst(s string) string {
+	n := 0
+	for n < len(s) && s[n] >= 'A' && s[n] <= 'Z' {
+		n++
+	}
+	if n > 1 && n < len(s) && s[n] >= 'a' && s[n] <= 'z' {
+		n--
+	}
+	return strings.ToLower(s[:n]) + s[n:]
+}
+
+// uniqueName returns name, or name suffixed by a number if it's used, and
+// marks it used
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
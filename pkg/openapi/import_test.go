@@ -0,0 +1,360 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const petsYaml = `openapi: 3.0.1
+info:
+  title: Pet Store
+  description: |
+    Legacy pet service.
+  version: 1.0.0
+paths:
+  /pets:
+    parameters:
+      - $ref: '#/components/parameters/TraceId'
+    get:
+      tags: [pets]
+      summary: List pets
+      operationId: ListPets
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, format: int32, minimum: 1, maximum: 100, default: 20}
+        - name: sort-by
+          in: query
+          schema: {type: string, enum: [name, created-at]}
+        - name: tags
+          in: query
+          schema: {type: array, items: {type: string}}
+      responses:
+        200:
+          description: pets
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pets'}
+        '4XX':
+          description: client error
+        default:
+          description: error
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Error'}
+    post:
+      tags: [pets]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/NewPet'}
+          application/xml:
+            schema: {$ref: '#/components/schemas/NewPet'}
+      responses:
+        '201':
+          description: created
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+        '409':
+          description: conflict
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Error'}
+  /pets/{pet-id}:
+    get:
+      tags: [pets]
+      deprecated: true
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                oneOf:
+                  - $ref: '#/components/schemas/Cat'
+                  - $ref: '#/components/schemas/Dog'
+        '404':
+          description: Not Found
+    delete:
+      tags: [pets]
+      parameters:
+        - name: pet-id
+          in: path
+          required: true
+          schema: {type: string, format: uuid}
+      responses:
+        '204':
+          description: deleted
+        default:
+          description: error without content
+  /pets/{id}/photo.{ext}:
+    get:
+      responses:
+        '200': {description: ok}
+  /stats:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                additionalProperties: {type: integer}
+components:
+  parameters:
+    TraceId:
+      name: X-Trace-Id
+      in: header
+      schema: {type: string}
+  schemas:
+    Pets:
+      type: array
+      items: {$ref: '#/components/schemas/Pet'}
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/NewPet'
+        - type: object
+          required: [id]
+          properties:
+            id: {type: integer, format: int64}
+            born-at: {type: string, format: date-time}
+            owner:
+              type: object
+              properties:
+                name: {type: string}
+    NewPet:
+      type: object
+      required: [name]
+      description: a new pet
+      properties:
+        name: {type: string, minLength: 1}
+        tag: {type: string, nullable: true}
+        weight: {type: number, exclusiveMinimum: true, minimum: 0}
+        labels: {type: array, items: {type: string}, uniqueItems: true}
+        extra: {anyOf: [{type: string}, {type: integer}]}
+    Cat:
+      type: object
+      properties:
+        kind: {type: string}
+        meow: {type: boolean, default: true}
+    Dog:
+      type: object
+      properties:
+        kind: {type: string}
+        bark: {type: boolean}
+    Animal:
+      oneOf: [{$ref: '#/components/schemas/Cat'}, {$ref: '#/components/schemas/Dog'}]
+      discriminator:
+        propertyName: kind
+        mapping:
+          cat: '#/components/schemas/Cat'
+          Dog: '#/components/schemas/Dog'
+    Status:
+      type: integer
+      enum: [1, 2, 3]
+    Error:
+      type: object
+      required: [code, message]
+      properties:
+        code: {type: integer}
+        message: {type: string}
+`
+
+func TestImport(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(petsYaml))
+	assert.NoError(t, err)
+	im := &Importer{Group: "pets"}
+	content, err := im.Import(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, `# Pet Store
+# Legacy pet service.
+group pets
+
+# @go.type time/Time
+# @openapi.format date-time
+# @openapi.type string
+scalar DateTime
+
+# @go.type string
+# @openapi.format uuid
+# @openapi.type string
+scalar Uuid
+
+enum Status {
+  Value1 = 1
+  Value2 = 2
+  Value3 = 3
+}
+
+enum ListPetsSortBy {
+  name
+  createdAt = "created-at"
+}
+
+struct Cat {
+  kind: string
+  meow: boolean = true
+}
+
+struct Dog {
+  bark: boolean?
+  kind: string
+}
+
+struct Error {
+  code: int
+  message: string
+}
+
+# a new pet
+struct NewPet {
+  extra: any?
+  labels: [string]?
+  # @minLength 1
+  name: string
+  tag: string?
+  weight: float?
+}
+
+struct Pet extends NewPet {
+  bornAt: DateTime?
+  id: int
+  owner: PetOwner?
+}
+
+struct PetOwner {
+  name: string?
+}
+
+# @discriminator kind
+union Animal = Cat | Dog
+
+union GetPetsByPetIdResponse = Cat | Dog
+
+# conflict
+error Conflict(409): Error
+
+error NotFound(404)
+
+interface Pets {
+  # @route GET /pets
+  # @summary List pets
+  listPets(
+    # @maximum 100
+    # @minimum 1
+    limit: int = 20,
+    # @in query sort-by
+    sortBy: ListPetsSortBy?,
+    # @in header X-Trace-Id
+    xTraceId: string?
+  ): [Pet]
+
+  # @route POST /pets
+  # @throws Conflict
+  postPets(
+    # @in header X-Trace-Id
+    xTraceId: string?,
+    newPet: NewPet
+  ): Pet
+
+  # @deprecated
+  # @route GET /pets/:petId
+  # @throws NotFound
+  getPetsByPetId(petId: string): GetPetsByPetIdResponse
+
+  # @route DELETE /pets/:petId
+  deletePetsByPetId(petId: Uuid)
+}
+
+interface PetsApi {
+  # @route GET /stats
+  getStats(): {string: int}
+}
+`, content)
+	assert.Equal(t, []string{
+		"schema [Animal]: discriminator value [cat] of [#/components/schemas/Cat] is not kept, which is the type name [Cat]",
+		"schema [NewPet] property [extra]: anyOf is not supported, imported as [any]",
+		"schema [NewPet] property [labels]: constraint [uniqueItems] is not supported, ignored",
+		"schema [NewPet] property [weight]: constraint [exclusiveMinimum] is not supported, ignored",
+		"schema [Pet]: property [born-at] is renamed to [bornAt]",
+		"schema [Pets]: schema is not declared as a type, but inlined as [[Pet]] where it's used",
+		"GET /pets: query param [tags] of type [[string]] is not supported, skipped",
+		"GET /pets: response of status [4XX] is not supported, skipped",
+		"GET /pets: default response is not supported, skipped",
+		"POST /pets: request body is imported as [application/json], other content types are ignored",
+		"GET /pets/{id}/photo.{ext}: path segment [photo.{ext}] is not supported, operation skipped",
+		"GET /pets/{pet-id}: path param [pet-id] is not declared, imported as [string]",
+	}, im.Problems)
+}
+
+// TestImportRendered imports the document rendered by api1, which is
+// rendered the same again
+func TestImportRendered(t *testing.T) {
+	content, err := ioutil.ReadFile("../golang/testdata/server.api")
+	assert.NoError(t, err)
+	doc, err := parseAndRender(string(content))
+	assert.NoError(t, err)
+	b, err := json.Marshal(doc)
+	assert.NoError(t, err)
+
+	loaded, err := LoadOpenAPI(b)
+	assert.NoError(t, err)
+	im := &Importer{Group: "user"}
+	imported, err := im.Import(loaded)
+	assert.NoError(t, err)
+	assert.Empty(t, im.Problems)
+	again, err := parseAndRender(imported)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, again)
+}
+
+func TestLoadOpenAPI(t *testing.T) {
+	doc, err := LoadOpenAPI([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "t", "version": "1"},
+		"paths": {},
+		"components": {"schemas": {
+			"Tags": {"type": "object", "additionalProperties": true},
+			"Strict": {"type": "object", "additionalProperties": false},
+			"Age": {"type": "integer", "minimum": 0, "exclusiveMinimum": true, "maximum": 10, "exclusiveMaximum": 12}
+		}}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Schema{}, doc.Components.Schemas["Tags"].AdditionalProperties)
+	assert.Nil(t, doc.Components.Schemas["Strict"].AdditionalProperties)
+	age := doc.Components.Schemas["Age"]
+	assert.Equal(t, 0.0, *age.ExclusiveMinimum)
+	assert.Equal(t, 12.0, *age.ExclusiveMaximum)
+
+	_, err = LoadOpenAPI([]byte("swagger: '2.0'\ninfo: {title: t, version: '1'}\npaths: {}\n"))
+	assert.EqualError(t, err, "OpenAPI version [] is not supported, 3.x is expected")
+}
+
+func TestIdentifier(t *testing.T) {
+	for s, expected := range map[string]string{
+		"user":         "user",
+		"User_Name":    "User_Name",
+		"user-name":    "userName",
+		"X-Request-Id": "XRequestId",
+		"created at":   "createdAt",
+		"2fa":          "_2fa",
+		"":             "_",
+	} {
+		assert.Equal(t, expected, identifier(s), s)
+	}
+	for s, expected := range map[string]string{
+		"ListPets":   "listPets",
+		"ID":         "id",
+		"HTTPServer": "httpServer",
+		"XRequestId": "xRequestId",
+		"user":       "user",
+	} {
+		assert.Equal(t, expected, lowerFirst(s), s)
+	}
+}
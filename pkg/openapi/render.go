@@ -2,35 +2,60 @@ package openapi
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/pkg/errors"
 )
 
 const (
 	OpenAPIVersion = "3.0.3"
 	refPrefix      = "#/components/schemas/"
 	mimeJson       = "application/json"
-	mimeFormData   = "multipart/form-data"
+	mimeFormData   = api1.MimeFormData
+	mimeBinary     = "application/octet-stream"
+	// schema of errors responded by the generated servers
+	errorResponse = "ErrorResponse"
 )
 
 type Render struct {
+	// Info is the metadata of the api, e.g. title & version
+	Info Info
+	// Servers are the servers providing the api
+	Servers []Server
+
+	schema  *api1.Schema
 	rParser *api1.RouteParser
+	// type names defined in more than one group
+	dupNames map[string]bool
+	// rendered component schemas, to resolve refs
+	components map[string]Schema
+	// monomorphized instances of generic structs
+	instances api1.Instances
 }
 
 func (o *Render) Render(s *api1.Schema) (*OpenAPI, error) {
+	o.schema = s
 	o.rParser = &api1.RouteParser{}
 	o.rParser.LoadSchema(s)
+	o.loadDupNames(s)
+	instances, err := s.Instances()
+	if err != nil {
+		return nil, err
+	}
+	o.instances = instances
 
 	var openAPI OpenAPI
 	openAPI.OpenAPI = OpenAPIVersion
-	openAPI.Info.Title = ""
-	openAPI.Info.Version = ""
+	openAPI.Info = o.Info
+	openAPI.Servers = o.Servers
 	c, err := o.renderComponents(s)
 	if err != nil {
 		return nil, err
 	}
 	openAPI.Components = *c
+	o.components = c.Schemas
 
 	if paths, err := o.renderPaths(s); err != nil {
 		return nil, err
@@ -55,36 +80,140 @@ func (o *Render) renderComponents(s *api1.Schema) (*Components, error) {
 		},
 	}
 	c.Schemas["any"] = any
+	c.Schemas[errorResponse] = Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"code":    {Type: "integer", Description: "HTTP status code"},
+			"message": {Type: "string"},
+			"error":   {Type: "string", Description: "name of the declared error"},
+		},
+		Required: []string{"code", "message"},
+	}
 	for _, g := range s.Groups {
 		for _, sc := range g.ScalarTypes {
 			s := o.renderSchemaScalar(sc)
 			if s != nil {
-				c.Schemas[sc.Name] = *s
+				c.Schemas[o.componentName(g.Name, sc.Name)] = *s
 			}
 		}
 		for _, en := range g.EnumTypes {
 			s := o.renderSchemaEnum(en)
-			c.Schemas[en.Name] = *s
+			c.Schemas[o.componentName(g.Name, en.Name)] = *s
 		}
 		for _, st := range g.StructTypes {
+			if len(st.TypeParams) > 0 {
+				// rendered as monomorphized instances
+				continue
+			}
 			s, err := o.renderSchemaObject(st)
 			if err != nil {
 				return nil, err
 			}
-			c.Schemas[st.Name] = *s
+			c.Schemas[o.componentName(g.Name, st.Name)] = *s
+		}
+		for _, un := range g.UnionTypes {
+			c.Schemas[o.componentName(g.Name, un.Name)] = *o.renderSchemaUnion(un)
 		}
 	}
+	for _, inst := range o.instances {
+		s, err := o.renderSchemaObject(inst.Struct)
+		if err != nil {
+			return nil, err
+		}
+		c.Schemas[o.componentName(inst.Group, inst.Struct.Name)] = *s
+	}
 	return &c, nil
 }
 
+func (o *Render) loadDupNames(s *api1.Schema) {
+	groups := make(map[string]string)
+	o.dupNames = make(map[string]bool)
+	add := func(group string, name string) {
+		if g, ok := groups[name]; ok && g != group {
+			o.dupNames[name] = true
+		}
+		groups[name] = group
+	}
+	for _, g := range s.Groups {
+		for _, sc := range g.ScalarTypes {
+			add(g.Name, sc.Name)
+		}
+		for _, en := range g.EnumTypes {
+			add(g.Name, en.Name)
+		}
+		for _, st := range g.StructTypes {
+			add(g.Name, st.Name)
+		}
+		for _, un := range g.UnionTypes {
+			add(g.Name, un.Name)
+		}
+	}
+}
+
+// componentName returns name of the schema component, which is qualified by
+// group only if the type name is defined in multiple groups, e.g. `common.Status`
+func (o *Render) componentName(group string, name string) string {
+	if group != "" && o.dupNames[name] {
+		return group + "." + name
+	}
+	return name
+}
+
+// instanceName returns the component name of type t, which is the
+// monomorphized instance if t is a generic struct instance
+func (o *Render) instanceName(t *api1.TypeRef) string {
+	if inst := o.instances.Find(t); inst != nil {
+		return o.componentName(inst.Group, inst.Struct.Name)
+	}
+	return o.componentName(t.Group, t.Name)
+}
+
+// withDefault sets default value of schema, $ref is wrapped by allOf since
+// its siblings are ignored
+func (o *Render) withDefault(s *Schema, l *api1.Literal, t *api1.TypeRef) *Schema {
+	value := o.schema.LiteralValue(l, t)
+	if value == nil {
+		return s
+	}
+	if s.Ref != "" {
+		return &Schema{AllOf: []Schema{*s}, Default: value}
+	}
+	s.Default = value
+	return s
+}
+
+// withConstraints sets validation constraints declared in semantic comments,
+// $ref is wrapped by allOf since its siblings are ignored
+func (o *Render) withConstraints(s *Schema, semComments map[string]interface{}) *Schema {
+	c, _ := api1.GetConstraints(semComments)
+	if c == nil {
+		return s
+	}
+	if s.Ref != "" {
+		s = &Schema{AllOf: []Schema{*s}}
+	}
+	s.Minimum = c.Minimum
+	s.Maximum = c.Maximum
+	s.MinLength = c.MinLength
+	s.MaxLength = c.MaxLength
+	s.Pattern = c.Pattern
+	s.MinItems = c.MinItems
+	s.MaxItems = c.MaxItems
+	return s
+}
+
 // return schema, required, err
 func (o *Render) renderSchemaRef(t *api1.TypeRef) (*Schema, bool) {
 	if t.Name != "" {
 		if s, ok := tryGetSchema(t.Name); ok {
 			return s, !t.Nullable
 		}
-		// any/scalar/enum/struct
-		return &Schema{Ref: fmt.Sprintf("%s%s", refPrefix, t.Name)}, !t.Nullable
+		// any/scalar/enum/struct, generic struct instance is monomorphized
+		return &Schema{Ref: fmt.Sprintf("%s%s", refPrefix, o.instanceName(t))}, !t.Nullable
+	}
+	if t.KeyType != nil {
+		itemSchema, _ := o.renderSchemaRef(t.ItemType)
+		return &Schema{Type: "object", AdditionalProperties: itemSchema}, !t.Nullable
 	}
 	if t.ItemType != nil {
 		itemSchema, _ := o.renderSchemaRef(t.ItemType)
@@ -157,21 +286,61 @@ func (o *Render) renderSchemaObject(st api1.StructType) (*Schema, error) {
 			continue
 		}
 		property, required := o.renderSchemaRef(field.Type)
-		if required {
+		if required && field.Default == nil {
 			s.Required = append(s.Required, field.Name)
 		}
+		property = o.withConstraints(property, field.SemComments)
+		property = o.withDefault(property, field.Default, field.Type)
 		if property.Ref == "" {
 			property.Description = strings.Join(field.Comments, "\n\n")
 		}
 		s.Properties[field.Name] = *property
 	}
 
+	// inherited struct: allOf parents & own fields
+	if len(st.Extends) > 0 {
+		own := Schema{
+			Type:       s.Type,
+			Properties: s.Properties,
+			Required:   s.Required,
+		}
+		s.Type = ""
+		s.Properties = nil
+		s.Required = nil
+		for _, parent := range st.Extends {
+			s.AllOf = append(s.AllOf, Schema{Ref: refPrefix + o.instanceName(&parent)})
+		}
+		s.AllOf = append(s.AllOf, own)
+	}
+
 	return &s, nil
 }
 
+func (o *Render) renderSchemaUnion(un api1.UnionType) *Schema {
+	s := Schema{
+		Description: strings.Join(un.Comments, "\n\n"),
+	}
+	if _, ok := un.SemComments["deprecated"]; ok {
+		s.Deprecated = true
+	}
+	for _, t := range un.Types {
+		s.OneOf = append(s.OneOf, Schema{Ref: refPrefix + o.componentName(t.Group, t.Name)})
+	}
+	if discriminator, ok := un.SemComments["discriminator"].(string); ok {
+		s.Discriminator = &Discriminator{
+			PropertyName: discriminator,
+			Mapping:      make(map[string]string),
+		}
+		for _, t := range un.Types {
+			s.Discriminator.Mapping[t.Name] = refPrefix + o.componentName(t.Group, t.Name)
+		}
+	}
+	return &s
+}
+
 func (o *Render) renderPaths(s *api1.Schema) (Paths, error) {
 	paths := make(Paths)
-	for _, g := range s.Groups {
+	for i, g := range s.Groups {
 		for _, iface := range g.Ifaces {
 			for _, fun := range iface.Funs {
 				var route string
@@ -186,7 +355,7 @@ func (o *Render) renderPaths(s *api1.Schema) (Paths, error) {
 				}
 
 				method := parseMethod(m)
-				operation, err := o.renderOperation(&iface, &fun, method, pathParams)
+				operation, err := o.renderOperation(&s.Groups[i], &iface, &fun, method, pathParams)
 				if err != nil {
 					return nil, err
 				}
@@ -200,7 +369,7 @@ func (o *Render) renderPaths(s *api1.Schema) (Paths, error) {
 	return paths, nil
 }
 
-func (o *Render) renderOperation(iface *api1.Iface, fun *api1.Fun, method Method, pathParams []string) (*Operation, error) {
+func (o *Render) renderOperation(g *api1.ApiGroup, iface *api1.Iface, fun *api1.Fun, method Method, pathParams []string) (*Operation, error) {
 	operation := Operation{
 		Tags:        []string{iface.Name},
 		Description: strings.Join(fun.Comments, "\n\n"),
@@ -220,7 +389,7 @@ func (o *Render) renderOperation(iface *api1.Iface, fun *api1.Fun, method Method
 	operation.Parameters = parameters
 	operation.RequestBody = requestBody
 
-	responses, err := o.renderResponses(fun.Type)
+	responses, err := o.renderResponses(g, fun)
 	if err != nil {
 		return nil, err
 	}
@@ -232,6 +401,8 @@ func (o *Render) renderOperation(iface *api1.Iface, fun *api1.Fun, method Method
 func (o *Render) renderParameters(iface *api1.Iface, fun *api1.Fun, method Method, pathParams []string) ([]Parameter, *RequestBody, error) {
 	var parameters []Parameter
 	var requestBody *RequestBody
+	var form *Schema
+	formType := api1.GetFormType(fun)
 
 	routeParams, err := o.rParser.ParseParams(iface, fun, pathParams)
 	if err != nil {
@@ -240,6 +411,25 @@ func (o *Render) renderParameters(iface *api1.Iface, fun *api1.Fun, method Metho
 
 	for _, param := range routeParams {
 		s, required := o.renderSchemaRef(param.Type)
+		s = o.withConstraints(s, param.SemComments)
+		if param.Default != nil {
+			s = o.withDefault(s, param.Default, param.Type)
+			required = param.In == api1.PositionPath
+		}
+
+		if param.In == api1.PositionForm {
+			if form == nil {
+				form = &Schema{Type: "object", Properties: make(map[string]Schema)}
+			}
+			if required {
+				form.Required = append(form.Required, param.WireName)
+			}
+			if s.Ref == "" {
+				s.Description = strings.Join(param.Comments, "\n\n")
+			}
+			form.Properties[param.WireName] = *s
+			continue
+		}
 
 		if param.In == api1.PositionBody {
 			contentType, ok := fun.SemComments["accept"].(string)
@@ -248,7 +438,11 @@ func (o *Render) renderParameters(iface *api1.Iface, fun *api1.Fun, method Metho
 			}
 
 			content := make(map[string]MediaType)
-			content[contentType] = MediaType{Schema: s}
+			media := MediaType{Schema: s}
+			if formType != "" {
+				media.Encoding = o.renderEncodings(o.formProperties(s), formType)
+			}
+			content[contentType] = media
 
 			requestBody = &RequestBody{
 				Description: strings.Join(param.Comments, "\n\n"),
@@ -259,7 +453,7 @@ func (o *Render) renderParameters(iface *api1.Iface, fun *api1.Fun, method Metho
 		}
 
 		p := Parameter{
-			Name:        param.Name,
+			Name:        param.WireName,
 			In:          parsePosition(string(param.In)),
 			Description: strings.Join(param.Comments, "\n\n"),
 			Required:    required,
@@ -271,14 +465,119 @@ func (o *Render) renderParameters(iface *api1.Iface, fun *api1.Fun, method Metho
 		parameters = append(parameters, p)
 	}
 
+	if form != nil {
+		requestBody = &RequestBody{
+			Content: map[string]MediaType{
+				formType: {Schema: form, Encoding: o.renderEncodings(form.Properties, formType)},
+			},
+			Required: len(form.Required) > 0,
+		}
+	}
+
 	return parameters, requestBody, nil
 }
 
-// currently, only status code 200 repsonse
-func (o *Render) renderResponses(t *api1.TypeRef) (Responses, error) {
+// resolve returns the schema referred by s, or s if it's not a ref
+func (o *Render) resolve(s *Schema) *Schema {
+	for s != nil {
+		if s.Ref != "" {
+			c, ok := o.components[strings.TrimPrefix(s.Ref, refPrefix)]
+			if !ok {
+				return s
+			}
+			s = &c
+		} else if len(s.AllOf) == 1 && s.Type == "" {
+			// ref wrapped for defaults or constraints
+			s = &s.AllOf[0]
+		} else {
+			return s
+		}
+	}
+	return s
+}
+
+// formProperties returns properties of form body s, including the inherited
+// ones
+func (o *Render) formProperties(s *Schema) map[string]Schema {
+	properties := make(map[string]Schema)
+	s = o.resolve(s)
+	for _, parent := range s.AllOf {
+		for name, property := range o.formProperties(&parent) {
+			properties[name] = property
+		}
+	}
+	for name, property := range s.Properties {
+		properties[name] = property
+	}
+	return properties
+}
+
+// renderEncodings renders encodings of form properties which are not sent
+// as plain text, i.e. files & json of multipart, and arrays as repeated
+// fields, returns nil if none
+func (o *Render) renderEncodings(properties map[string]Schema, formType string) map[string]Encoding {
+	var encodings map[string]Encoding
+	for name, property := range properties {
+		var e Encoding
+		p := o.resolve(&property)
+		switch {
+		case p.Type == "array":
+			if item := o.resolve(p.Items); formType == mimeFormData && isBinary(item) {
+				e.ContentType = mimeBinary
+			} else {
+				explode := true
+				e.Style = "form"
+				e.Explode = &explode
+			}
+		case formType != mimeFormData:
+			continue
+		case isBinary(p):
+			e.ContentType = mimeBinary
+		case p.Type == "object" || len(p.AllOf) > 0 || len(p.OneOf) > 0:
+			e.ContentType = mimeJson
+		default:
+			continue
+		}
+		if encodings == nil {
+			encodings = make(map[string]Encoding)
+		}
+		encodings[name] = e
+	}
+	return encodings
+}
+
+func isBinary(s *Schema) bool {
+	return s != nil && s.Type == "string" && s.Format == "binary"
+}
+
+// renderResponses renders status code 200 response of the returned value,
+// which is wrapped in an envelope for `@response envelope`, and the default
+// response of errors
+func (o *Render) renderResponses(g *api1.ApiGroup, fun *api1.Fun) (Responses, error) {
+	style, err := api1.GetResponseStyle(g, fun)
+	if err != nil {
+		return nil, err
+	}
 	content := make(map[string]MediaType)
-	if t != nil {
-		s, _ := o.renderSchemaRef(t)
+	var s *Schema
+	if fun.Type != nil {
+		s, _ = o.renderSchemaRef(fun.Type)
+	}
+	if style == api1.ResponseEnvelope {
+		envelope := Schema{
+			Type: "object",
+			Properties: map[string]Schema{
+				"code":    {Type: "integer", Description: "0 if succeeded"},
+				"message": {Type: "string"},
+			},
+			Required: []string{"code", "message"},
+		}
+		if s != nil {
+			envelope.Properties["data"] = *s
+		}
+		s = &envelope
+	}
+	if s != nil {
 		content[mimeJson] = MediaType{Schema: s}
 	}
 	responses := make(Responses)
@@ -286,5 +585,92 @@ func (o *Render) renderResponses(t *api1.TypeRef) (Responses, error) {
 		Description: "Default Response",
 		Content:     content,
 	}
+	if fun.Stream != nil {
+		responses["200"] = o.renderStreamResponse(fun.Stream)
+	}
+	responses["default"] = Response{
+		Description: "Error Response",
+		Content: map[string]MediaType{
+			mimeJson: {Schema: &Schema{Ref: refPrefix + errorResponse}},
+		},
+	}
+	if err := o.renderErrorResponses(responses, fun); err != nil {
+		return nil, err
+	}
 	return responses, nil
 }
+
+// renderStreamResponse renders the response of stream, whose schema is of
+// the data of each event, or binary for the stream of bytes
+func (o *Render) renderStreamResponse(stream *api1.Stream) Response {
+	if stream.Item == nil {
+		return Response{
+			Description: "Streamed Response",
+			Content: map[string]MediaType{
+				stream.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			},
+		}
+	}
+	s, _ := o.renderSchemaRef(stream.Item)
+	return Response{
+		Description: "Server-Sent Events, the data of each event is json",
+		Content:     map[string]MediaType{stream.ContentType: {Schema: s}},
+	}
+}
+
+// renderErrorResponses renders responses of errors in `@throws`, errors of
+// the same status are rendered as oneOf
+func (o *Render) renderErrorResponses(responses Responses, fun *api1.Fun) error {
+	var statuses []string
+	errorTypes := make(map[string][]*api1.ErrorType)
+	for i := range fun.Throws {
+		er, ok := o.schema.FindErrorType(&fun.Throws[i])
+		if !ok {
+			return errors.Errorf("Error [%s] cannot be found", fun.Throws[i].QualName())
+		}
+		status := strconv.Itoa(er.Status)
+		if errorTypes[status] == nil {
+			statuses = append(statuses, status)
+		}
+		errorTypes[status] = append(errorTypes[status], er)
+	}
+	for _, status := range statuses {
+		var schemas []Schema
+		var names []string
+		for _, er := range errorTypes[status] {
+			schemas = append(schemas, o.renderSchemaError(er))
+			names = append(names, er.Name)
+		}
+		description := strings.Join(names, ", ")
+		if ers := errorTypes[status]; len(ers) == 1 && len(ers[0].Comments) > 0 {
+			description = strings.Join(ers[0].Comments, "\n\n")
+		}
+		s := &schemas[0]
+		if len(schemas) > 1 {
+			s = &Schema{OneOf: schemas}
+		}
+		responses[status] = Response{
+			Description: description,
+			Content:     map[string]MediaType{mimeJson: {Schema: s}},
+		}
+	}
+	return nil
+}
+
+// renderSchemaError renders ErrorResponse of the error, whose `error` is the
+// error name, and `data` is the payload
+func (o *Render) renderSchemaError(er *api1.ErrorType) Schema {
+	s := Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"error": {Type: "string", Enum: []interface{}{er.Name}},
+		},
+		Required: []string{"error"},
+	}
+	if er.Payload != nil {
+		data, _ := o.renderSchemaRef(er.Payload)
+		s.Properties["data"] = *data
+		s.Required = append(s.Required, "data")
+	}
+	return Schema{AllOf: []Schema{{Ref: refPrefix + errorResponse}, s}}
+}
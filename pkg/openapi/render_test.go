@@ -2,6 +2,7 @@ package openapi
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/jinzhenj/api1/pkg/api1"
@@ -310,6 +311,96 @@ interface sometest {
 	assert.Error(t, err)
 }
 
+func TestRenderMap(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct S1 {
+			f1: {string: int}
+			f2: map<string, S1>?
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	s1 := doc.Components.Schemas["S1"]
+	assert.Equal(t, Schema{
+		Type:                 "object",
+		AdditionalProperties: &Schema{Type: "integer"},
+	}, s1.Properties["f1"])
+	assert.Equal(t, Schema{
+		Type:                 "object",
+		AdditionalProperties: &Schema{Ref: refPrefix + "S1"},
+	}, s1.Properties["f2"])
+	assert.Equal(t, []string{"f1"}, s1.Required)
+}
+
+func TestRenderExtends(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct Base {
+			id: int
+		}
+
+		# user
+		struct User extends Base {
+			name: string?
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	assert.Equal(t, Schema{
+		Description: "user",
+		AllOf: []Schema{
+			{Ref: refPrefix + "Base"},
+			{
+				Type: "object",
+				Properties: map[string]Schema{
+					"name": {Type: "string"},
+				},
+			},
+		},
+	}, doc.Components.Schemas["User"])
+}
+
+func TestRenderUnion(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct A {
+			kind: string
+		}
+
+		struct B {
+			kind: string
+		}
+
+		# @discriminator kind
+		union U = A | B
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	assert.Equal(t, Schema{
+		OneOf: []Schema{
+			{Ref: refPrefix + "A"},
+			{Ref: refPrefix + "B"},
+		},
+		Discriminator: &Discriminator{
+			PropertyName: "kind",
+			Mapping: map[string]string{
+				"A": refPrefix + "A",
+				"B": refPrefix + "B",
+			},
+		},
+	}, doc.Components.Schemas["U"])
+}
+
 func parseAndRender(s string) (*OpenAPI, error) {
 	parser := api1.Parser{}
 	schema, err := parser.Parse(s)
@@ -320,3 +411,563 @@ func parseAndRender(s string) (*OpenAPI, error) {
 	render := Render{}
 	return render.Render(schema)
 }
+
+func TestRenderGeneric(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct Page<T> {
+			items: [T]
+		}
+
+		struct User {
+			name: string
+		}
+
+		interface I {
+			# @route GET /users
+			listUsers(): Page<User>
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	_, ok := doc.Components.Schemas["Page"]
+	assert.False(t, ok)
+	assert.Equal(t, Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"items": {Type: "array", Items: &Schema{Ref: refPrefix + "User"}},
+		},
+		Required: []string{"items"},
+	}, doc.Components.Schemas["Page_User"])
+	assert.Equal(t, refPrefix+"Page_User",
+		doc.Paths["/users"][MethodGet].Responses["200"].Content[mimeJson].Schema.Ref)
+}
+
+func TestRenderGenericGroups(t *testing.T) {
+	common := `
+	  group common
+
+		struct Page<T> {
+			items: [T]
+		}
+	`
+	a := `
+	  group a
+
+		struct User {
+			name: string
+		}
+
+		interface A {
+			# @route GET /a
+			list(): common.Page<User>
+		}
+	`
+	b := `
+	  group b
+
+		struct User {
+			id: int
+		}
+
+		interface B {
+			# @route GET /b
+			list(): common.Page<User>
+		}
+	`
+	parser := api1.Parser{}
+	schema, err := parser.Parse(common, a, b)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	render := Render{}
+	doc, err := render.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	// instances of the same name are qualified by groups of type args
+	_, ok := doc.Components.Schemas["Page_User"]
+	assert.False(t, ok)
+	assert.Equal(t, refPrefix+"a.User", doc.Components.Schemas["Page_AUser"].Properties["items"].Items.Ref)
+	assert.Equal(t, refPrefix+"b.User", doc.Components.Schemas["Page_BUser"].Properties["items"].Items.Ref)
+	assert.Equal(t, refPrefix+"Page_AUser",
+		doc.Paths["/a"][MethodGet].Responses["200"].Content[mimeJson].Schema.Ref)
+	assert.Equal(t, refPrefix+"Page_BUser",
+		doc.Paths["/b"][MethodGet].Responses["200"].Content[mimeJson].Schema.Ref)
+}
+
+func TestRenderDupNames(t *testing.T) {
+	t1 := `
+	  group common
+
+		enum Status {
+			Active
+		}
+	`
+	t2 := `
+	  group user
+
+		enum Status {
+			Online
+		}
+
+		struct User {
+			status: Status
+			accountStatus: common.Status
+		}
+	`
+	parser := api1.Parser{}
+	schema, err := parser.Parse(t1, t2)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	render := Render{}
+	doc, err := render.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	_, ok := doc.Components.Schemas["Status"]
+	assert.False(t, ok)
+	assert.Equal(t, []interface{}{"Active"}, doc.Components.Schemas["common.Status"].Enum)
+	assert.Equal(t, []interface{}{"Online"}, doc.Components.Schemas["user.Status"].Enum)
+	properties := doc.Components.Schemas["User"].Properties
+	assert.Equal(t, refPrefix+"user.Status", properties["status"].Ref)
+	assert.Equal(t, refPrefix+"common.Status", properties["accountStatus"].Ref)
+}
+
+func TestRenderDefault(t *testing.T) {
+	t1 := `
+	  group t1
+
+		enum Role {
+			Admin = 1
+			Normal = 2
+		}
+
+		struct User {
+			name: string = "anonymous"
+			role: Role = Normal
+			age: int
+		}
+
+		interface I {
+			# @route GET /users
+			listUsers(page: int = 1, role: Role? = Admin)
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	user := doc.Components.Schemas["User"]
+	assert.Equal(t, []string{"age"}, user.Required)
+	assert.Equal(t, Schema{Type: "string", Default: "anonymous"}, user.Properties["name"])
+	assert.Equal(t, Schema{
+		AllOf:   []Schema{{Ref: refPrefix + "Role"}},
+		Default: int64(2),
+	}, user.Properties["role"])
+
+	params := doc.Paths["/users"][MethodGet].Parameters
+	assert.Equal(t, false, params[0].Required)
+	assert.Equal(t, &Schema{Type: "integer", Default: int64(1)}, params[0].Schema)
+	assert.Equal(t, int64(1), params[1].Schema.Default)
+}
+
+func TestRenderConstraints(t *testing.T) {
+	t1 := `
+	  group t1
+
+		# @openapi.type integer
+		scalar Timestamp
+
+		struct User {
+			# @minLength 1
+			# @maxLength 32
+			# @pattern ^[a-z]+$
+			name: string
+			# @minimum 0
+			# @maximum 150
+			age: int?
+			# @maxItems 3
+			tags: [string] = ["a"]
+			# @minimum 0
+			createdAt: Timestamp
+		}
+
+		interface I {
+			# @route GET /users
+			listUsers(
+				# @minimum 1
+				page: int = 1
+			)
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	one, max, three := 1, 32, 3
+	zero, old, first := 0.0, 150.0, 1.0
+	user := doc.Components.Schemas["User"]
+	assert.Equal(t, Schema{Type: "string", MinLength: &one, MaxLength: &max, Pattern: "^[a-z]+$"},
+		user.Properties["name"])
+	assert.Equal(t, Schema{Type: "integer", Minimum: &zero, Maximum: &old}, user.Properties["age"])
+	assert.Equal(t, Schema{
+		Type:     "array",
+		Items:    &Schema{Type: "string"},
+		MaxItems: &three,
+		Default:  []interface{}{"a"},
+	}, user.Properties["tags"])
+	assert.Equal(t, Schema{
+		AllOf:   []Schema{{Ref: refPrefix + "Timestamp"}},
+		Minimum: &zero,
+	}, user.Properties["createdAt"])
+
+	params := doc.Paths["/users"][MethodGet].Parameters
+	assert.Equal(t, &Schema{Type: "integer", Minimum: &first, Default: int64(1)}, params[0].Schema)
+}
+
+func TestRenderParamsIn(t *testing.T) {
+	t1 := `
+	  group t1
+
+		# @route.in cookie
+		scalar Session
+
+		interface I {
+			# @route GET /users
+			listUsers(
+				# @in header X-Request-Id
+				requestId: string?,
+				session: Session,
+				# @in query
+				page: int
+			)
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	params := doc.Paths["/users"][MethodGet].Parameters
+	assert.Equal(t, 3, len(params))
+	assert.Equal(t, "X-Request-Id", params[0].Name)
+	assert.Equal(t, PositionHeader, params[0].In)
+	assert.Equal(t, false, params[0].Required)
+	assert.Equal(t, "session", params[1].Name)
+	assert.Equal(t, PositionCookie, params[1].In)
+	assert.Equal(t, true, params[1].Required)
+	assert.Equal(t, PositionQuery, params[2].In)
+
+	t2 := `
+	  group t2
+
+		interface I {
+			# @route GET /users/:id
+			getUser(
+				# @in header
+				id: int
+			)
+		}
+	`
+	_, err = parseAndRender(t2)
+	assert.EqualError(t, err, "8:5: error: Function [I.getUser] path param [id] cannot be in header [route]")
+
+	t3 := `
+	  group t3
+
+		interface I {
+			# @route GET /users
+			listUsers(
+				# @in body
+				page: int
+			)
+		}
+	`
+	_, err = parseAndRender(t3)
+	assert.EqualError(t, err, "8:5: error: Function [I.listUsers] param [page] position [body] should be one of [header, cookie, query] [route]")
+
+	t4 := `
+	  group t4
+
+		interface I {
+			# @route GET /files/:dir
+			getFile(dir: string = "home")
+		}
+	`
+	_, err = parseAndRender(t4)
+	assert.EqualError(t, err, "6:12: error: Function [I.getFile] path param [dir] cannot have default value [route]")
+}
+
+func TestRenderForm(t *testing.T) {
+	t1 := `
+	  group t1
+
+		# @openapi.type string
+		# @openapi.format binary
+		scalar File
+
+		struct Meta {
+			size: int
+		}
+
+		# @form
+		struct Avatar {
+			file: File
+			title: string?
+		}
+
+		interface I {
+			# @route POST /users/:id/files
+			# @accept multipart/form-data
+			uploadFiles(
+				id: int,
+				# files to upload
+				files: [File],
+				meta: Meta?,
+				labels: [string] = [],
+				note: string?
+			)
+
+			# @route POST /users/:id/avatar
+			# @accept multipart/form-data
+			uploadAvatar(id: int, avatar: Avatar)
+
+			# @route POST /login
+			# @accept application/x-www-form-urlencoded
+			login(name: string, password: string, tags: [string])
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	explode := true
+	op := doc.Paths["/users/{id}/files"][MethodPost]
+	assert.Equal(t, 1, len(op.Parameters))
+	assert.Equal(t, &RequestBody{
+		Content: map[string]MediaType{
+			mimeFormData: {
+				Schema: &Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"files": {
+							Type:        "array",
+							Items:       &Schema{Ref: refPrefix + "File"},
+							Description: "files to upload",
+						},
+						"meta":   {Ref: refPrefix + "Meta"},
+						"labels": {Type: "array", Items: &Schema{Type: "string"}, Default: []interface{}{}},
+						"note":   {Type: "string"},
+					},
+					Required: []string{"files"},
+				},
+				Encoding: map[string]Encoding{
+					"files":  {ContentType: mimeBinary},
+					"meta":   {ContentType: mimeJson},
+					"labels": {Style: "form", Explode: &explode},
+				},
+			},
+		},
+		Required: true,
+	}, op.RequestBody)
+
+	assert.Equal(t, &RequestBody{
+		Content: map[string]MediaType{
+			mimeFormData: {
+				Schema:   &Schema{Ref: refPrefix + "Avatar"},
+				Encoding: map[string]Encoding{"file": {ContentType: mimeBinary}},
+			},
+		},
+		Required: true,
+	}, doc.Paths["/users/{id}/avatar"][MethodPost].RequestBody)
+
+	assert.Equal(t, map[string]Encoding{"tags": {Style: "form", Explode: &explode}},
+		doc.Paths["/login"][MethodPost].RequestBody.Content[api1.MimeFormUrlencoded].Encoding)
+
+	t2 := `
+	  group t2
+
+		# @form
+		struct Avatar {
+			title: string?
+		}
+
+		interface I {
+			# @route POST /avatar
+			# @accept multipart/form-data
+			uploadAvatar(avatar: Avatar, note: string)
+		}
+	`
+	_, err = parseAndRender(t2)
+	assert.EqualError(t, err, "12:4: error: Function [I.uploadAvatar] has both bodyParam [avatar] and form params [note] [route]")
+}
+
+func TestRenderStream(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct Event {
+			name: string
+		}
+
+		interface I {
+			# @route GET /events
+			# @response envelope
+			watch(): stream<Event>
+
+			# @route GET /export
+			# @produces text/csv
+			export(): stream
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	assert.Equal(t, Response{
+		Description: "Server-Sent Events, the data of each event is json",
+		Content: map[string]MediaType{
+			"text/event-stream": {Schema: &Schema{Ref: refPrefix + "Event"}},
+		},
+	}, doc.Paths["/events"][MethodGet].Responses["200"])
+	assert.Equal(t, Response{
+		Description: "Streamed Response",
+		Content: map[string]MediaType{
+			"text/csv": {Schema: &Schema{Type: "string", Format: "binary"}},
+		},
+	}, doc.Paths["/export"][MethodGet].Responses["200"])
+	assert.Contains(t, doc.Paths["/export"][MethodGet].Responses, "default")
+}
+
+func TestRenderResponses(t *testing.T) {
+	t1 := `
+	  # @response envelope
+	  group t1
+
+		struct User {
+			name: string
+		}
+
+		interface I {
+			# @route GET /users/:id
+			getUser(id: int): User
+
+			# @route DELETE /users/:id
+			deleteUser(id: int)
+
+			# @route GET /users
+			# @response json
+			listUsers(): [User]
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	errResponse := Response{
+		Description: "Error Response",
+		Content: map[string]MediaType{
+			mimeJson: {Schema: &Schema{Ref: refPrefix + "ErrorResponse"}},
+		},
+	}
+	assert.Equal(t, Responses{
+		"200": {
+			Description: "Default Response",
+			Content: map[string]MediaType{
+				mimeJson: {Schema: &Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"code":    {Type: "integer", Description: "0 if succeeded"},
+						"data":    {Ref: refPrefix + "User"},
+						"message": {Type: "string"},
+					},
+					Required: []string{"code", "message"},
+				}},
+			},
+		},
+		"default": errResponse,
+	}, doc.Paths["/users/{id}"][MethodGet].Responses)
+	assert.NotContains(t, doc.Paths["/users/{id}"][MethodDelete].Responses["200"].Content[mimeJson].Schema.Properties, "data")
+	assert.Equal(t, Responses{
+		"200": {
+			Description: "Default Response",
+			Content: map[string]MediaType{
+				mimeJson: {Schema: &Schema{Type: "array", Items: &Schema{Ref: refPrefix + "User"}}},
+			},
+		},
+		"default": errResponse,
+	}, doc.Paths["/users"][MethodGet].Responses)
+	assert.Contains(t, doc.Components.Schemas, "ErrorResponse")
+
+	t2 := `
+	  # @response xml
+	  group t2
+
+		interface I {
+			# @route GET /users
+			listUsers()
+		}
+	`
+	_, err = parseAndRender(t2)
+	assert.EqualError(t, err, "3:4: error: Response style [xml] should be one of [json, envelope] [route]")
+}
+
+func TestRenderErrorResponses(t *testing.T) {
+	t1 := `
+	  group t1
+
+		struct Detail {
+			field: string
+		}
+
+		# user cannot be found
+		error NotFound(404)
+		error Conflict(409): Detail
+		error Duplicated(409)
+
+		interface I {
+			# @route POST /users
+			# @throws NotFound, Conflict, Duplicated
+			createUser(): string
+		}
+	`
+	doc, err := parseAndRender(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	errorSchema := func(name string, data *Schema) Schema {
+		s := Schema{
+			Type:       "object",
+			Properties: map[string]Schema{"error": {Type: "string", Enum: []interface{}{name}}},
+			Required:   []string{"error"},
+		}
+		if data != nil {
+			s.Properties["data"] = *data
+			s.Required = append(s.Required, "data")
+		}
+		return Schema{AllOf: []Schema{{Ref: refPrefix + "ErrorResponse"}, s}}
+	}
+	responses := doc.Paths["/users"][MethodPost].Responses
+	var statuses []string
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	assert.Equal(t, []string{"200", "404", "409", "default"}, statuses)
+	notFound := errorSchema("NotFound", nil)
+	assert.Equal(t, Response{
+		Description: "user cannot be found",
+		Content:     map[string]MediaType{mimeJson: {Schema: &notFound}},
+	}, responses["404"])
+	assert.Equal(t, Response{
+		Description: "Conflict, Duplicated",
+		Content: map[string]MediaType{mimeJson: {Schema: &Schema{OneOf: []Schema{
+			errorSchema("Conflict", &Schema{Ref: refPrefix + "Detail"}),
+			errorSchema("Duplicated", nil),
+		}}}},
+	}, responses["409"])
+}
@@ -1,5 +1,9 @@
 package openapi
 
+import (
+	"encoding/json"
+)
+
 // see: https://swagger.io/specification/
 
 type OpenAPI struct {
@@ -17,8 +21,9 @@ type Info struct {
 }
 
 type Server struct {
-	Url       string                    `json:"url"` // required
-	Variables map[string]ServerVariable `json:"variables"`
+	Url         string                    `json:"url"` // required
+	Description string                    `json:"description,omitempty"`
+	Variables   map[string]ServerVariable `json:"variables,omitempty"`
 }
 
 type ServerVariable struct {
@@ -27,6 +32,46 @@ type ServerVariable struct {
 
 type Paths map[string]PathItem
 type PathItem map[Method]Operation
+
+// UnmarshalJSON decodes operations of the path item, parameters shared by
+// the operations are added to each of them unless overridden, other fields
+// (e.g. summary & servers) are ignored
+func (item *PathItem) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var shared []Parameter
+	if params, ok := raw["parameters"]; ok {
+		if err := json.Unmarshal(params, &shared); err != nil {
+			return err
+		}
+	}
+	*item = make(PathItem)
+	for _, method := range []Method{MethodGet, MethodPut, MethodPost, MethodDelete,
+		MethodOptions, MethodHead, MethodPatch, MethodTrace} {
+		v, ok := raw[string(method)]
+		if !ok {
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal(v, &op); err != nil {
+			return err
+		}
+		for _, p := range shared {
+			overridden := false
+			for _, q := range op.Parameters {
+				overridden = overridden || (q.Ref == "" && q.Name == p.Name && q.In == p.In)
+			}
+			if !overridden {
+				op.Parameters = append(op.Parameters, p)
+			}
+		}
+		(*item)[method] = op
+	}
+	return nil
+}
+
 type Method string
 
 const (
@@ -52,6 +97,8 @@ type Operation struct {
 }
 
 type Parameter struct {
+	// Ref refers to a parameter of components, other fields are ignored
+	Ref         string   `json:"$ref,omitempty"`
 	Name        string   `json:"name"` // required
 	In          Position `json:"in"`   // required
 	Description string   `json:"description,omitempty"`
@@ -70,6 +117,8 @@ const (
 )
 
 type RequestBody struct {
+	// Ref refers to a request body of components, other fields are ignored
+	Ref         string               `json:"$ref,omitempty"`
 	Description string               `json:"description,omitempty"`
 	Content     map[string]MediaType `json:"content"` // required
 	Required    bool                 `json:"required,omitempty"`
@@ -78,39 +127,54 @@ type RequestBody struct {
 type Responses map[string]Response
 
 type Response struct {
+	// Ref refers to a response of components, other fields are ignored
+	Ref         string               `json:"$ref,omitempty"`
 	Description string               `json:"description"` // required
 	Content     map[string]MediaType `json:"content,omitempty"`
 }
 
 type MediaType struct {
 	Schema *Schema `json:"schema,omitempty"`
+	// encodings of properties of form bodies
+	Encoding map[string]Encoding `json:"encoding,omitempty"`
+}
+
+type Encoding struct {
+	ContentType string `json:"contentType,omitempty"`
+	Style       string `json:"style,omitempty"`
+	Explode     *bool  `json:"explode,omitempty"`
 }
 
 type Components struct {
-	Schemas map[string]Schema `json:"schemas,omitempty"`
+	Schemas       map[string]Schema      `json:"schemas,omitempty"`
+	Parameters    map[string]Parameter   `json:"parameters,omitempty"`
+	RequestBodies map[string]RequestBody `json:"requestBodies,omitempty"`
+	Responses     map[string]Response    `json:"responses,omitempty"`
 }
 
 type Schema struct {
 	Ref string `json:"$ref,omitempty"`
 
-	Type        string `json:"type,omitempty"`
-	Format      string `json:"format,omitempty"`
-	Title       string `json:"title,omitempty"`
-	Description string `json:"description,omitempty"`
-	Default     string `json:"default,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Format      string      `json:"format,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Nullable    bool        `json:"nullable,omitempty"`
 
 	// object
-	Properties    map[string]Schema `json:"properties,omitempty"`
-	Required      []string          `json:"required,omitempty"`
-	Deprecated    bool              `json:"deprecated,omitempty"`
-	MinProperties *int              `json:"minProperties,omitempty"`
-	MaxProperties *int              `json:"maxProperties,omitempty"`
+	Properties           map[string]Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema           `json:"additionalProperties,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+	Deprecated           bool              `json:"deprecated,omitempty"`
+	MinProperties        *int              `json:"minProperties,omitempty"`
+	MaxProperties        *int              `json:"maxProperties,omitempty"`
 
 	// array
 	Items       *Schema `json:"items,omitempty"`
 	MinItems    *int    `json:"minItems,omitempty"`
 	MaxItems    *int    `json:"maxItems,omitempty"`
-	UniqueItems *int    `json:"uniqueItems,omitempty"`
+	UniqueItems *bool   `json:"uniqueItems,omitempty"`
 
 	// string
 	Enum      []interface{} `json:"enum,omitempty"`
@@ -126,7 +190,62 @@ type Schema struct {
 	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
 
 	// other
-	AllOf []Schema `json:"allOf,omitempty"`
-	OneOf []Schema `json:"oneOf,omitempty"`
-	AnyOf []Schema `json:"anyOf,omitempty"`
+	AllOf         []Schema       `json:"allOf,omitempty"`
+	OneOf         []Schema       `json:"oneOf,omitempty"`
+	AnyOf         []Schema       `json:"anyOf,omitempty"`
+	Discriminator *Discriminator `json:"discriminator,omitempty"`
+}
+
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"` // required
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// UnmarshalJSON decodes s, `additionalProperties` may be a boolean, which is
+// an empty schema if true, `exclusiveMinimum` & `exclusiveMaximum` may be
+// booleans of OpenAPI 3.0, which take the values of minimum & maximum if true
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	type schema Schema
+	var raw struct {
+		schema
+		AdditionalProperties json.RawMessage `json:"additionalProperties,omitempty"`
+		ExclusiveMinimum     json.RawMessage `json:"exclusiveMinimum,omitempty"`
+		ExclusiveMaximum     json.RawMessage `json:"exclusiveMaximum,omitempty"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Schema(raw.schema)
+	switch string(raw.AdditionalProperties) {
+	case "", "false", "null":
+	case "true":
+		s.AdditionalProperties = &Schema{}
+	default:
+		s.AdditionalProperties = &Schema{}
+		if err := json.Unmarshal(raw.AdditionalProperties, s.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+	var err error
+	if s.ExclusiveMinimum, err = exclusiveBound(raw.ExclusiveMinimum, s.Minimum); err != nil {
+		return err
+	}
+	if s.ExclusiveMaximum, err = exclusiveBound(raw.ExclusiveMaximum, s.Maximum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func exclusiveBound(raw json.RawMessage, bound *float64) (*float64, error) {
+	switch string(raw) {
+	case "", "false", "null":
+		return nil, nil
+	case "true":
+		return bound, nil
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
 }
@@ -0,0 +1,265 @@
+package python
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+)
+
+var sprintf = fmt.Sprintf
+
+// indent4 indents non-empty lines by 4 spaces, as PEP 8 recommends
+func indent4(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func CodeComments(c []string) string {
+	code := ""
+	for _, line := range c {
+		if line == "" {
+			code += "#\n"
+		} else {
+			code += sprintf("# %s\n", line)
+		}
+	}
+	return code
+}
+
+// CodeDocString renders comments as a docstring
+func CodeDocString(c []string) string {
+	if len(c) == 0 {
+		return ""
+	}
+	doc := strings.Join(c, "\n")
+	doc = strings.ReplaceAll(doc, "\\", "\\\\")
+	doc = strings.ReplaceAll(doc, `"""`, `\"\"\"`)
+	if len(c) == 1 {
+		return sprintf("\"\"\"%s\"\"\"\n", doc)
+	}
+	return sprintf("\"\"\"\n%s\n\"\"\"\n", doc)
+}
+
+func (t *PyTypeAlias) Code() string {
+	code := CodeComments(t.Comments)
+	code += sprintf("%s = %s\n", t.Name, t.Type.Code())
+	return code
+}
+
+func (e *PyEnum) Code() string {
+	code := sprintf("class %s(%s, Enum):\n", e.Name, e.BaseType)
+	body := CodeDocString(e.Comments)
+	for _, o := range e.Options {
+		body += CodeComments(o.Comments)
+		body += sprintf("%s = %s\n", o.Name, o.Value)
+	}
+	if body == "" {
+		body = "pass\n"
+	}
+	code += indent4(body)
+	return code
+}
+
+func (t *PyType) Code() string {
+	if t == nil {
+		return "None"
+	}
+	var code string
+	if t.Name != "" {
+		code = t.Name
+		if len(t.TypeArgs) > 0 {
+			var args []string
+			for i := range t.TypeArgs {
+				args = append(args, t.TypeArgs[i].Code())
+			}
+			code += sprintf("[%s]", strings.Join(args, ", "))
+		}
+	} else if t.KeyType != nil {
+		code = sprintf("Dict[%s, %s]", t.KeyType.Code(), t.ItemType.Code())
+	} else {
+		code = sprintf("List[%s]", t.ItemType.Code())
+	}
+	if t.Nullable {
+		code = sprintf("Optional[%s]", code)
+	}
+	return code
+}
+
+func (f *PyField) Code() string {
+	code := CodeComments(f.Comments)
+	code += sprintf("%s: %s", f.Name, f.Type.Code())
+	var args []string
+	if f.Alias != "" {
+		args = append(args, sprintf("alias=%q", f.Alias))
+	}
+	args = append(args, f.Args...)
+	if len(args) > 0 {
+		if f.Default != "" {
+			args = append([]string{"default=" + f.Default}, args...)
+		}
+		code += sprintf(" = Field(%s)", strings.Join(args, ", "))
+	} else if f.Default != "" {
+		code += " = " + f.Default
+	}
+	return code + "\n"
+}
+
+func (m *PyModel) Code() string {
+	var bases []string
+	for i := range m.Bases {
+		bases = append(bases, m.Bases[i].Code())
+	}
+	if len(bases) == 0 {
+		bases = append(bases, "BaseModel")
+	}
+	if len(m.TypeParams) > 0 {
+		bases = append(bases, sprintf("Generic[%s]", strings.Join(m.TypeParams, ", ")))
+	}
+	code := sprintf("class %s(%s):\n", m.Name, strings.Join(bases, ", "))
+	body := CodeDocString(m.Comments)
+	var hasAlias bool
+	for _, f := range m.Fields {
+		hasAlias = hasAlias || f.Alias != ""
+	}
+	var config []string
+	if hasAlias {
+		config = append(config, "populate_by_name=True")
+	}
+	if m.ArbitraryTypes {
+		config = append(config, "arbitrary_types_allowed=True")
+	}
+	if len(config) > 0 {
+		body += sprintf("model_config = ConfigDict(%s)\n", strings.Join(config, ", "))
+	}
+	for _, f := range m.Fields {
+		body += f.Code()
+	}
+	if body == "" {
+		body = "pass\n"
+	}
+	code += indent4(body)
+	return code
+}
+
+func (u *PyUnion) Code() string {
+	code := CodeComments(u.Comments)
+	var members []string
+	for i := range u.Members {
+		members = append(members, u.Members[i].Code())
+	}
+	switch len(members) {
+	case 0:
+		code += sprintf("%s = Any\n", u.Name)
+	case 1:
+		code += sprintf("%s = %s\n", u.Name, members[0])
+	default:
+		code += sprintf("%s = Union[%s]\n", u.Name, strings.Join(members, ", "))
+	}
+	return code
+}
+
+func (m *PyMethod) Code() string {
+	params := []string{"self"}
+	for i, p := range m.Params {
+		if i == m.KeywordOnly {
+			params = append(params, "*")
+		}
+		param := sprintf("%s: %s", p.Name, p.Type.Code())
+		if p.Default != "" {
+			param += " = " + p.Default
+		}
+		params = append(params, param)
+	}
+	code := sprintf("def %s(%s) -> %s:\n", m.Name, strings.Join(params, ", "), m.RetType.Code())
+
+	body := CodeDocString(m.Comments)
+	for _, stmt := range m.Prelude {
+		body += stmt + "\n"
+	}
+	args := []string{sprintf("%q", strings.ToUpper(m.Method)), m.Path}
+	if m.StreamItem != nil {
+		args = append(args, "ret="+m.StreamItem.Code())
+	} else if m.RetType != nil && m.StreamType == "" {
+		args = append(args, "ret="+m.RetType.Code())
+	}
+	dict := func(params []PyQueryParam) string {
+		var items []string
+		for _, q := range params {
+			items = append(items, sprintf("%q: %s", q.Name, q.Param))
+		}
+		return sprintf("{%s}", strings.Join(items, ", "))
+	}
+	if len(m.QueryParams) > 0 {
+		args = append(args, "query="+dict(m.QueryParams))
+	}
+	switch {
+	case len(m.Form) > 0:
+		args = append(args, "form="+dict(m.Form))
+	case m.Body != "" && m.FormType != "":
+		args = append(args, "form="+m.Body)
+	case m.Body != "":
+		args = append(args, "body="+m.Body)
+	}
+	if m.FormType == api1.MimeFormData {
+		args = append(args, "multipart=True")
+	}
+	if m.Envelope {
+		args = append(args, "envelope=True")
+	}
+	if len(m.Headers) > 0 {
+		args = append(args, "headers="+dict(m.Headers))
+	}
+	if len(m.Cookies) > 0 {
+		args = append(args, "cookies="+dict(m.Cookies))
+	}
+	call := "request"
+	if m.StreamType != "" {
+		call = "stream"
+	}
+	body += sprintf("return self.client.%s(%s)\n", call, strings.Join(args, ", "))
+	code += indent4(body)
+	return code
+}
+
+func (c *PyClient) Code() string {
+	code := sprintf("class %s:\n", c.Name)
+	body := CodeDocString(c.Comments)
+	body += "def __init__(self, client: ApiClient) -> None:\n"
+	body += indent4("self.client = client\n")
+	for i := range c.Methods {
+		body += "\n"
+		body += c.Methods[i].Code()
+	}
+	code += indent4(body)
+	return code
+}
+
+func (file *PyFile) Code() string {
+	code := "# Code generated by api1; DO NOT EDIT.\n"
+	if len(file.Imports) > 0 {
+		code += "\n"
+		for _, imp := range file.Imports {
+			if imp == "" {
+				code += "\n"
+			} else {
+				code += imp + "\n"
+			}
+		}
+	}
+	for _, codeGen := range file.CodeGens {
+		// two blank lines around top level definitions, as PEP 8 recommends
+		code += "\n\n"
+		code += codeGen.Code()
+	}
+	return code
+}
+
+func (r *RawCode) Code() string {
+	return r.code
+}
@@ -0,0 +1,749 @@
+package python
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/jinzhenj/api1/pkg/utils"
+)
+
+const (
+	defaultOutputDir = "python/api"
+	clientModule     = "_client"
+)
+
+// keywords of python, which cannot be used as names
+var keywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true,
+	"assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true,
+	"if": true, "import": true, "in": true, "is": true, "lambda": true,
+	"nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+type Render struct {
+	// OutputDir is the dir of generated files, defaults to `python/api`
+	OutputDir string
+
+	// group being rendered, and names it imports
+	group   string
+	imports map[string]map[string]bool
+	groups  map[string]bool
+	// qualified names of scalars of binary format, i.e. files
+	binaries map[string]bool
+}
+
+func (r *Render) getOutputDir() string {
+	trimed := strings.TrimLeft(r.OutputDir, "/")
+	trimed = strings.TrimRight(trimed, "/")
+	if len(trimed) == 0 {
+		return defaultOutputDir
+	}
+	return trimed
+}
+
+func (r *Render) addImport(module string, name string) {
+	if r.imports[module] == nil {
+		r.imports[module] = make(map[string]bool)
+	}
+	r.imports[module][name] = true
+}
+
+// popImports returns import statements of the group being rendered
+func (r *Render) popImports() []string {
+	var lines []string
+	lines = append(lines, "from __future__ import annotations", "")
+	var std []string
+	if r.imports["enum"] != nil {
+		std = append(std, "from enum import Enum")
+	}
+	if r.imports["typing"] != nil {
+		std = append(std, "from typing import "+joinNames(r.imports["typing"]))
+	}
+	if len(std) > 0 {
+		lines = append(lines, std...)
+		lines = append(lines, "")
+	}
+	if r.imports["pydantic"] != nil {
+		lines = append(lines, "from pydantic import "+joinNames(r.imports["pydantic"]), "")
+	}
+	var local []string
+	var groups []string
+	for group := range r.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		local = append(local, "from . import "+group)
+	}
+	if r.imports[clientModule] != nil {
+		local = append(local, sprintf("from .%s import %s", clientModule, joinNames(r.imports[clientModule])))
+	}
+	lines = append(lines, local...)
+	if len(local) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	r.imports = make(map[string]map[string]bool)
+	r.groups = make(map[string]bool)
+	return lines
+}
+
+func joinNames(m map[string]bool) string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Render renders each group into a module of pydantic models & client
+// classes, and a shared `_client.py` which sends requests by httpx.
+func (r *Render) Render(schema *api1.Schema) ([]PyFile, error) {
+	if err := schema.SupplyRouteInfo(); err != nil {
+		return nil, err
+	}
+	outputDir := r.getOutputDir()
+	r.imports = make(map[string]map[string]bool)
+	r.groups = make(map[string]bool)
+	r.binaries = make(map[string]bool)
+	for _, g := range schema.Groups {
+		for i := range g.ScalarTypes {
+			if isBinary(&g.ScalarTypes[i]) {
+				r.binaries[g.Name+"."+g.ScalarTypes[i].Name] = true
+			}
+		}
+	}
+
+	var files []PyFile
+	for _, g := range schema.Groups {
+		r.group = g.Name
+		file := PyFile{
+			Name: fmt.Sprintf("%s/%s.py", outputDir, g.Name),
+		}
+		var typeVars []string
+		seen := make(map[string]bool)
+		for _, st := range g.StructTypes {
+			for _, param := range st.TypeParams {
+				if !seen[param] {
+					seen[param] = true
+					typeVars = append(typeVars, sprintf("%s = TypeVar(%q)\n", param, param))
+				}
+			}
+		}
+		if len(typeVars) > 0 {
+			r.addImport("typing", "TypeVar")
+			r.addImport("typing", "Generic")
+			file.CodeGens = append(file.CodeGens, &RawCode{code: strings.Join(typeVars, "")})
+		}
+		for _, sc := range g.ScalarTypes {
+			file.CodeGens = append(file.CodeGens, r.renderScalar(&sc))
+		}
+		for _, en := range g.EnumTypes {
+			file.CodeGens = append(file.CodeGens, r.renderEnum(&en))
+		}
+		for _, st := range g.StructTypes {
+			file.CodeGens = append(file.CodeGens, r.renderStruct(&st))
+		}
+		for _, un := range g.UnionTypes {
+			file.CodeGens = append(file.CodeGens, r.renderUnion(&un))
+		}
+		for _, iface := range g.Ifaces {
+			if client := r.renderClient(&iface); client != nil {
+				file.CodeGens = append(file.CodeGens, client)
+			}
+		}
+		file.Imports = r.popImports()
+		files = append(files, file)
+	}
+	files = append(files, PyFile{
+		Name: sprintf("%s/__init__.py", outputDir),
+	})
+	files = append(files, r.renderClientFile(outputDir))
+	return files, nil
+}
+
+// qualify returns name of type defined in group, which is referenced by the
+// module if defined in another group, e.g. `common.Status`
+func (r *Render) qualify(group string, name string) string {
+	if group == "" || group == r.group {
+		return name
+	}
+	r.groups[group] = true
+	return group + "." + name
+}
+
+func (r *Render) renderScalar(sc *api1.ScalarType) *PyTypeAlias {
+	t := PyTypeAlias{
+		Comments: sc.Comments,
+		Name:     sc.Name,
+	}
+	if typ, ok := sc.SemComments["py.type"].(string); ok {
+		t.Type = &PyType{Name: typ}
+	} else if typ, ok := sc.SemComments["openapi.type"].(string); ok {
+		switch typ {
+		case "integer":
+			t.Type = &PyType{Name: "int"}
+		case "number":
+			t.Type = &PyType{Name: "float"}
+		case "string":
+			t.Type = &PyType{Name: "str"}
+			if isBinary(sc) {
+				// sent as files by multipart forms
+				r.addImport("typing", "Union")
+				r.addImport("typing", "IO")
+				t.Type = &PyType{Name: "Union", TypeArgs: []PyType{
+					{Name: "bytes"},
+					{Name: "IO", TypeArgs: []PyType{{Name: "bytes"}}},
+				}}
+			}
+		case "boolean":
+			t.Type = &PyType{Name: "bool"}
+		case "object":
+			t.Type = r.objectType()
+		}
+	}
+	if t.Type == nil {
+		t.Type = r.anyType()
+	}
+	return &t
+}
+
+// isBinary returns whether the scalar is a file, i.e. a string of binary
+// format without `@py.type`
+func isBinary(sc *api1.ScalarType) bool {
+	if _, ok := sc.SemComments["py.type"]; ok {
+		return false
+	}
+	typ, _ := sc.SemComments["openapi.type"].(string)
+	format, _ := sc.SemComments["openapi.format"].(string)
+	return typ == "string" && format == "binary"
+}
+
+// refersBinary returns whether t refers to files, which are not validated by
+// pydantic
+func (r *Render) refersBinary(t *api1.TypeRef) bool {
+	if t == nil {
+		return false
+	}
+	if r.refersBinary(t.KeyType) || r.refersBinary(t.ItemType) {
+		return true
+	}
+	for i := range t.TypeArgs {
+		if r.refersBinary(&t.TypeArgs[i]) {
+			return true
+		}
+	}
+	return r.binaries[t.Group+"."+t.Name]
+}
+
+func (r *Render) renderEnum(en *api1.EnumType) *PyEnum {
+	r.addImport("enum", "Enum")
+	e := PyEnum{
+		Comments: en.Comments,
+		Name:     en.Name,
+		BaseType: "str",
+	}
+	for _, o := range en.Options {
+		var value string
+		if o.Value == nil {
+			value = quote(o.Name)
+		} else if o.Value.IntVal != nil {
+			e.BaseType = "int"
+			value = fmt.Sprint(*o.Value.IntVal)
+		} else {
+			value = quote(*o.Value.StrVal)
+		}
+		e.Options = append(e.Options, PyEnumOption{
+			Comments: o.Comments,
+			Name:     pyName(o.Name),
+			Value:    value,
+		})
+	}
+	return &e
+}
+
+func (r *Render) renderStruct(st *api1.StructType) *PyModel {
+	r.addImport("pydantic", "BaseModel")
+	m := PyModel{
+		Comments:   st.Comments,
+		Name:       st.Name,
+		TypeParams: st.TypeParams,
+	}
+	for i := range st.Extends {
+		m.Bases = append(m.Bases, *r.renderType(&st.Extends[i]))
+	}
+	for _, field := range st.Fields {
+		if _, ok := field.SemComments["ignore"]; ok {
+			continue
+		}
+		f := PyField{
+			Comments: field.Comments,
+			Name:     pyName(field.Name),
+			Type:     r.renderType(field.Type),
+			Args:     constraintArgs(field.SemComments),
+		}
+		if f.Name != field.Name {
+			f.Alias = field.Name
+			r.addImport("pydantic", "ConfigDict")
+		}
+		if field.Default != nil {
+			f.Default = r.renderLiteral(field.Default, field.Type)
+		} else if _, ok := field.SemComments["omitempty"]; ok || field.Type.Nullable {
+			// absent or null
+			f.Type.Nullable = true
+			f.Default = "None"
+			r.addImport("typing", "Optional")
+		}
+		if f.Alias != "" || len(f.Args) > 0 {
+			r.addImport("pydantic", "Field")
+		}
+		if r.refersBinary(field.Type) {
+			m.ArbitraryTypes = true
+			r.addImport("pydantic", "ConfigDict")
+		}
+		m.Fields = append(m.Fields, f)
+	}
+	return &m
+}
+
+// constraintArgs returns keyword arguments of pydantic `Field()` validating
+// constraints declared in semComments
+func constraintArgs(semComments map[string]interface{}) []string {
+	c, _ := api1.GetConstraints(semComments)
+	if c == nil {
+		return nil
+	}
+	var args []string
+	number := func(f *float64) string {
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	if c.Minimum != nil {
+		args = append(args, "ge="+number(c.Minimum))
+	}
+	if c.Maximum != nil {
+		args = append(args, "le="+number(c.Maximum))
+	}
+	for _, n := range []*int{c.MinLength, c.MinItems} {
+		if n != nil {
+			args = append(args, sprintf("min_length=%d", *n))
+		}
+	}
+	for _, n := range []*int{c.MaxLength, c.MaxItems} {
+		if n != nil {
+			args = append(args, sprintf("max_length=%d", *n))
+		}
+	}
+	if c.Pattern != "" {
+		args = append(args, "pattern="+quote(c.Pattern))
+	}
+	return args
+}
+
+func (r *Render) renderUnion(un *api1.UnionType) *PyUnion {
+	u := PyUnion{
+		Comments: un.Comments,
+		Name:     un.Name,
+	}
+	for i := range un.Types {
+		u.Members = append(u.Members, *r.renderType(&un.Types[i]))
+	}
+	switch len(u.Members) {
+	case 0:
+		r.addImport("typing", "Any")
+	case 1:
+	default:
+		r.addImport("typing", "Union")
+	}
+	return &u
+}
+
+func (r *Render) renderType(t *api1.TypeRef) *PyType {
+	if t == nil {
+		return nil
+	}
+	var typ PyType
+	if t.Name != "" {
+		switch t.Name {
+		case "int":
+			typ.Name = "int"
+		case "float":
+			typ.Name = "float"
+		case "string":
+			typ.Name = "str"
+		case "boolean":
+			typ.Name = "bool"
+		case "any":
+			typ = *r.anyType()
+		case "object":
+			typ = *r.objectType()
+		default:
+			typ.Name = r.qualify(t.Group, t.Name)
+			for i := range t.TypeArgs {
+				typ.TypeArgs = append(typ.TypeArgs, *r.renderType(&t.TypeArgs[i]))
+			}
+		}
+	} else if t.KeyType != nil {
+		r.addImport("typing", "Dict")
+		typ.KeyType = r.renderType(t.KeyType)
+		typ.ItemType = r.renderType(t.ItemType)
+	} else {
+		r.addImport("typing", "List")
+		typ.ItemType = r.renderType(t.ItemType)
+	}
+	typ.Nullable = t.Nullable
+	if typ.Nullable {
+		r.addImport("typing", "Optional")
+	}
+	return &typ
+}
+
+func (r *Render) anyType() *PyType {
+	r.addImport("typing", "Any")
+	return &PyType{Name: "Any"}
+}
+
+func (r *Render) objectType() *PyType {
+	r.addImport("typing", "Dict")
+	return &PyType{KeyType: &PyType{Name: "str"}, ItemType: r.anyType()}
+}
+
+func (r *Render) renderLiteral(l *api1.Literal, t *api1.TypeRef) string {
+	switch l.Kind {
+	case api1.LiteralString:
+		return quote(l.Text)
+	case api1.LiteralBool:
+		return pyBool(l.Text == "true")
+	case api1.LiteralNull:
+		return "None"
+	case api1.LiteralIdent:
+		// enum option
+		return r.qualify(t.Group, t.Name) + "." + pyName(l.Text)
+	case api1.LiteralArray:
+		var items []string
+		for i := range l.Items {
+			items = append(items, r.renderLiteral(&l.Items[i], t.ItemType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return l.Text
+}
+
+// renderClient renders a client class sending requests of the routes in
+// iface, returns nil if iface has no route.
+func (r *Render) renderClient(iface *api1.Iface) *PyClient {
+	c := PyClient{
+		Comments: iface.Comments,
+		Name:     utils.PascalCase(iface.Name) + "Client",
+	}
+	for _, fun := range iface.Funs {
+		if fun.Route == nil {
+			continue
+		}
+		c.Methods = append(c.Methods, *r.renderMethod(&fun))
+	}
+	if len(c.Methods) == 0 {
+		return nil
+	}
+	r.addImport(clientModule, "ApiClient")
+	return &c
+}
+
+func (r *Render) renderMethod(fun *api1.Fun) *PyMethod {
+	m := PyMethod{
+		Comments:    fun.Comments,
+		Name:        pyName(fun.Name),
+		KeywordOnly: -1,
+		RetType:     r.renderType(fun.Type),
+		Method:      fun.Route.Method,
+		Envelope:    fun.Route.Response == api1.ResponseEnvelope,
+		FormType:    fun.Route.FormType,
+	}
+	if fun.Stream != nil {
+		// events are yielded as items, bytes are yielded in chunks, which are
+		// never wrapped
+		m.StreamType = fun.Stream.ContentType
+		m.Envelope = false
+		m.RetType = &PyType{Name: "bytes"}
+		if fun.Stream.Item != nil {
+			m.StreamItem = r.renderType(fun.Stream.Item)
+			m.RetType = m.StreamItem
+		}
+		m.RetType = &PyType{Name: "Iterator", TypeArgs: []PyType{*m.RetType}}
+		r.addImport("typing", "Iterator")
+	}
+	names := make(map[string]string)
+	firstOptional := -1
+	for i, param := range fun.Params {
+		name := pyName(param.Name)
+		names[param.Name] = name
+		p := PyParam{
+			Name: name,
+			Type: r.renderType(param.Type),
+		}
+		if param.Default != nil {
+			p.Default = r.renderLiteral(param.Default, param.Type)
+			if param.Default.Kind == api1.LiteralArray {
+				// mutable default value is set in method body
+				m.Prelude = append(m.Prelude,
+					sprintf("if %s is None:", name),
+					sprintf("    %s = %s", name, p.Default))
+				p.Type.Nullable = true
+				p.Default = "None"
+			}
+		} else if param.Type.Nullable {
+			p.Default = "None"
+		}
+		if p.Type.Nullable {
+			r.addImport("typing", "Optional")
+		}
+		if p.Default != "" && firstOptional < 0 {
+			firstOptional = i
+		} else if p.Default == "" && firstOptional >= 0 {
+			// required params after optional ones must be keyword-only
+			m.KeywordOnly = firstOptional
+		}
+		m.Params = append(m.Params, p)
+
+		switch fun.Route.ParamsIn[param.Name] {
+		case api1.PositionBody:
+			m.Body = name
+		case api1.PositionQuery:
+			m.QueryParams = append(m.QueryParams, PyQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionHeader:
+			m.Headers = append(m.Headers, PyQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionCookie:
+			m.Cookies = append(m.Cookies, PyQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionForm:
+			m.Form = append(m.Form, PyQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		}
+	}
+	m.Path = r.renderPath(fun.Route.Path, names)
+	return &m
+}
+
+// renderPath renders route path (in colon style) as a string, or a f-string if
+// it has path params, e.g. `f"/users/{path_param(id)}"`
+func (r *Render) renderPath(path string, names map[string]string) string {
+	parts := strings.Split(path, "/")
+	var hasParam bool
+	for i, part := range parts {
+		if len(part) > 1 && (part[0] == ':' || part[0] == '*') {
+			name, ok := names[part[1:]]
+			if !ok {
+				continue
+			}
+			hasParam = true
+			if part[0] == '*' {
+				// wildcard param may contain slashes
+				parts[i] = sprintf("{path_param(%s, wildcard=True)}", name)
+			} else {
+				parts[i] = sprintf("{path_param(%s)}", name)
+			}
+		} else {
+			parts[i] = strings.NewReplacer("{", "{{", "}", "}}").Replace(part)
+		}
+	}
+	if !hasParam {
+		return quote(path)
+	}
+	r.addImport(clientModule, "path_param")
+	return "f" + quote(strings.Join(parts, "/"))
+}
+
+func (r *Render) renderClientFile(dir string) PyFile {
+	code := `class ApiError(Exception):
+    """Raised if the response status is not 2xx"""
+
+    def __init__(self, status_code: int, body: str) -> None:
+        super().__init__(f"Request failed with status {status_code}")
+        self.status_code = status_code
+        self.body = body
+
+
+class ApiClient:
+    """Sends requests by httpx, extra arguments are passed to httpx.Client"""
+
+    def __init__(self, base_url: str = "", client: Optional[httpx.Client] = None, **kwargs: Any) -> None:
+        self.client = client or httpx.Client(base_url=base_url, **kwargs)
+
+    def request(
+        self,
+        method: str,
+        path: str,
+        ret: Any = None,
+        query: Optional[Dict[str, Any]] = None,
+        body: Any = None,
+        envelope: bool = False,
+        headers: Optional[Dict[str, Any]] = None,
+        cookies: Optional[Dict[str, Any]] = None,
+        form: Any = None,
+        multipart: bool = False,
+    ) -> Any:
+        kwargs = self.options(query, body, headers, cookies, form, multipart)
+        resp = self.client.request(method, path, **kwargs)
+        if not resp.is_success:
+            raise ApiError(resp.status_code, resp.text)
+        if ret is None or not resp.content:
+            return None
+        data = resp.json()
+        if envelope:
+            # response is wrapped in {"code": 0, "data": ..., "message": "ok"}
+            data = data.get("data")
+        return TypeAdapter(ret).validate_python(data)
+
+    def stream(
+        self,
+        method: str,
+        path: str,
+        ret: Any = None,
+        query: Optional[Dict[str, Any]] = None,
+        body: Any = None,
+        headers: Optional[Dict[str, Any]] = None,
+        cookies: Optional[Dict[str, Any]] = None,
+        form: Any = None,
+        multipart: bool = False,
+    ) -> Iterator[Any]:
+        """Yields json data of each server-sent event as ret, or chunks of the
+        streamed bytes if ret is None, the error event is raised as ApiError"""
+        kwargs = self.options(query, body, headers, cookies, form, multipart)
+        with self.client.stream(method, path, **kwargs) as resp:
+            if not resp.is_success:
+                resp.read()
+                raise ApiError(resp.status_code, resp.text)
+            if ret is None:
+                yield from resp.iter_bytes()
+                return
+            adapter = TypeAdapter(ret)
+            event, data = "", []
+            for line in resp.iter_lines():
+                if line:
+                    field, _, value = line.partition(":")
+                    value = value[1:] if value.startswith(" ") else value
+                    if field == "event":
+                        event = value
+                    elif field == "data":
+                        data.append(value)
+                    continue
+                # a blank line dispatches the event
+                payload, name = "\n".join(data), event
+                event, data = "", []
+                if not payload:
+                    continue
+                if name == "error":
+                    raise ApiError(resp.status_code, payload)
+                yield adapter.validate_json(payload)
+
+    def options(
+        self,
+        query: Optional[Dict[str, Any]],
+        body: Any,
+        headers: Optional[Dict[str, Any]],
+        cookies: Optional[Dict[str, Any]],
+        form: Any,
+        multipart: bool,
+    ) -> Dict[str, Any]:
+        """Returns arguments of httpx sending the request"""
+        params = {}
+        for key, value in (query or {}).items():
+            if value is not None:
+                params[key] = to_json(value)
+        kwargs: Dict[str, Any] = {"params": params}
+        if form is not None:
+            kwargs.update(encode_form(form, multipart))
+        elif body is not None:
+            kwargs["json"] = to_json(body)
+        if headers:
+            kwargs["headers"] = {key: to_str(value) for key, value in headers.items() if value is not None}
+        if cookies:
+            # cookies of the request, instead of the persistent ones of client
+            cookie = "; ".join(
+                f"{key}={quote(to_str(value))}" for key, value in cookies.items() if value is not None
+            )
+            if cookie:
+                kwargs.setdefault("headers", {})["Cookie"] = cookie
+        return kwargs
+
+
+def to_json(value: Any) -> Any:
+    return TypeAdapter(Any).dump_python(value, mode="json", by_alias=True)
+
+
+def to_str(value: Any) -> str:
+    value = to_json(value)
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return str(value)
+
+
+def is_file(value: Any) -> bool:
+    return isinstance(value, (bytes, tuple)) or hasattr(value, "read")
+
+
+def encode_form(form: Any, multipart: bool) -> Dict[str, Any]:
+    """Returns arguments of httpx sending form (a dict or model), files are
+    sent only by multipart, and objects are sent as json"""
+    if isinstance(form, BaseModel):
+        form = {field.alias or name: getattr(form, name) for name, field in type(form).model_fields.items()}
+    data: Dict[str, List[str]] = {}
+    files = []
+    for key, value in form.items():
+        if value is None:
+            continue
+        for v in value if isinstance(value, list) else [value]:
+            if multipart and is_file(v):
+                files.append((key, v))
+                continue
+            v = to_json(v)
+            data.setdefault(key, []).append(json.dumps(v) if isinstance(v, (dict, list)) else to_str(v))
+    kwargs: Dict[str, Any] = {"data": data}
+    if files:
+        kwargs["files"] = files
+    return kwargs
+
+
+def path_param(value: Any, wildcard: bool = False) -> str:
+    return quote(to_str(value), safe="/" if wildcard else "")
+`
+	return PyFile{
+		Name: sprintf("%s/%s.py", dir, clientModule),
+		Imports: []string{
+			"import json",
+			"from typing import Any, Dict, Iterator, List, Optional",
+			"from urllib.parse import quote",
+			"",
+			"import httpx",
+			"from pydantic import BaseModel, TypeAdapter",
+		},
+		CodeGens: []CodeGen{
+			&RawCode{
+				code: code,
+			},
+		},
+	}
+}
+
+func pyName(name string) string {
+	if keywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+func pyBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,234 @@
+package python
+
+import (
+	"testing"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group user
+
+		# @openapi.type integer
+		scalar Timestamp
+
+		enum Role {
+			Admin = 1
+			# normal user
+			Normal = 2
+		}
+
+		# user info
+		struct User {
+			id: int
+			# @minLength 1
+			name: string = "anonymous"
+			# @omitempty
+			nickname: string
+			role: Role = Normal
+			tags: [string?]
+			props: {string: any}
+			createdAt: Timestamp?
+			from: string
+			# @ignore
+			password: string
+		}
+
+		struct Page<T> {
+			items: [T]
+			total: int
+		}
+
+		struct Cat {
+			meow: boolean
+		}
+
+		struct Dog {
+			bark: boolean
+		}
+
+		union Pet = Cat | Dog
+
+		# @py.type bytes
+		scalar File
+
+		interface UserApi {
+			# @route GET /users
+			listUsers(page: int = 1, roles: [Role] = [Admin], from: string): Page<User>
+
+			# get user by id
+			# @route GET /users/:id
+			getUser(id: int): User?
+
+			# @route PUT /files/*path
+			# @response envelope
+			putFile(path: string, data: object)
+
+			# @route POST /users/:id/avatar
+			# @accept multipart/form-data
+			uploadAvatar(id: int, file: File, title: string?)
+
+			# @route POST /login
+			# @accept application/x-www-form-urlencoded
+			login(name: string, tags: [string])
+
+			# @route DELETE /users/:id
+			deleteUser(
+				id: int,
+				# @in header X-Request-Id
+				requestId: string,
+				# @in cookie
+				session: string
+			)
+
+			# @route GET /users/:id/events
+			watchUser(id: int): stream<User>
+
+			# @route GET /users/:id/export
+			# @produces text/csv
+			exportUser(id: int, format: string): stream
+
+			# not a route
+			ping()
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Equal(t, 3, len(files))
+	assert.Equal(t, "python/api/user.py", files[0].Name)
+	assert.Equal(t, "python/api/__init__.py", files[1].Name)
+	assert.Equal(t, "python/api/_client.py", files[2].Name)
+
+	exp := `# Code generated by api1; DO NOT EDIT.
+
+from __future__ import annotations
+
+from enum import Enum
+from typing import Any, Dict, Generic, Iterator, List, Optional, TypeVar, Union
+
+from pydantic import BaseModel, ConfigDict, Field
+
+from ._client import ApiClient, path_param
+
+
+T = TypeVar("T")
+
+
+Timestamp = int
+
+
+File = bytes
+
+
+class Role(int, Enum):
+    Admin = 1
+    # normal user
+    Normal = 2
+
+
+class User(BaseModel):
+    """user info"""
+    model_config = ConfigDict(populate_by_name=True)
+    id: int
+    name: str = Field(default="anonymous", min_length=1)
+    nickname: Optional[str] = None
+    role: Role = Role.Normal
+    tags: List[Optional[str]]
+    props: Dict[str, Any]
+    createdAt: Optional[Timestamp] = None
+    from_: str = Field(alias="from")
+
+
+class Page(BaseModel, Generic[T]):
+    items: List[T]
+    total: int
+
+
+class Cat(BaseModel):
+    meow: bool
+
+
+class Dog(BaseModel):
+    bark: bool
+
+
+Pet = Union[Cat, Dog]
+
+
+class UserApiClient:
+    def __init__(self, client: ApiClient) -> None:
+        self.client = client
+
+    def listUsers(self, *, page: int = 1, roles: Optional[List[Role]] = None, from_: str) -> Page[User]:
+        if roles is None:
+            roles = [Role.Admin]
+        return self.client.request("GET", "/users", ret=Page[User], query={"page": page, "from": from_}, body=roles)
+
+    def getUser(self, id: int) -> Optional[User]:
+        """get user by id"""
+        return self.client.request("GET", f"/users/{path_param(id)}", ret=Optional[User])
+
+    def putFile(self, path: str, data: Dict[str, Any]) -> None:
+        return self.client.request("PUT", f"/files/{path_param(path, wildcard=True)}", body=data, envelope=True)
+
+    def uploadAvatar(self, id: int, file: File, title: Optional[str] = None) -> None:
+        return self.client.request("POST", f"/users/{path_param(id)}/avatar", form={"file": file, "title": title}, multipart=True)
+
+    def login(self, name: str, tags: List[str]) -> None:
+        return self.client.request("POST", "/login", form={"name": name, "tags": tags})
+
+    def deleteUser(self, id: int, requestId: str, session: str) -> None:
+        return self.client.request("DELETE", f"/users/{path_param(id)}", headers={"X-Request-Id": requestId}, cookies={"session": session})
+
+    def watchUser(self, id: int) -> Iterator[User]:
+        return self.client.stream("GET", f"/users/{path_param(id)}/events", ret=User)
+
+    def exportUser(self, id: int, format: str) -> Iterator[bytes]:
+        return self.client.stream("GET", f"/users/{path_param(id)}/export", query={"format": format})
+`
+	assert.Equal(t, exp, files[0].Code())
+}
+
+func TestRenderFile(t *testing.T) {
+	parser := api1.Parser{}
+	schema, err := parser.Parse(`
+group user
+
+# @openapi.type string
+# @openapi.format binary
+scalar File
+
+struct Avatar {
+	file: File
+	title: string?
+}
+
+interface UserApi {
+	# @route POST /users/:id/avatar
+	# @accept multipart/form-data
+	uploadAvatar(id: int, avatar: Avatar)
+}
+`)
+	assert.NoError(t, err)
+	r := Render{}
+	files, err := r.Render(schema)
+	assert.NoError(t, err)
+	code := files[0].Code()
+	assert.Contains(t, code, "from typing import IO, Optional, Union\n")
+	assert.Contains(t, code, "\nFile = Union[bytes, IO[bytes]]\n")
+	assert.Contains(t, code, "class Avatar(BaseModel):\n"+
+		"    model_config = ConfigDict(arbitrary_types_allowed=True)\n"+
+		"    file: File\n")
+}
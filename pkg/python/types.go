@@ -0,0 +1,126 @@
+package python
+
+type CodeGen interface {
+	Code() string
+}
+
+// PyTypeAlias is a type alias, e.g. `Timestamp = int`
+type PyTypeAlias struct {
+	Comments []string
+	Name     string
+	Type     *PyType
+}
+
+type PyEnumOption struct {
+	Comments []string
+	Name     string
+	// literal value, e.g. `"admin"` or `1`
+	Value string
+}
+
+type PyEnum struct {
+	Comments []string
+	Name     string
+	// `str` or `int`
+	BaseType string
+	Options  []PyEnumOption
+}
+
+type PyType struct {
+	// named type, or builtin type, e.g. `int`
+	Name     string
+	TypeArgs []PyType
+	KeyType  *PyType
+	ItemType *PyType
+	Nullable bool
+}
+
+type PyField struct {
+	Comments []string
+	Name     string
+	// json name if Name is renamed, e.g. `from` for `from_`
+	Alias string
+	Type  *PyType
+	// default value expression, empty if required
+	Default string
+	// keyword arguments of `Field()`, e.g. `ge=1`
+	Args []string
+}
+
+// PyModel is a pydantic model of struct
+type PyModel struct {
+	Comments   []string
+	Name       string
+	TypeParams []string
+	Bases      []PyType
+	Fields     []PyField
+	// ArbitraryTypes allows fields of files, e.g. `IO[bytes]`
+	ArbitraryTypes bool
+}
+
+// PyUnion is a union of member types, e.g. `Pet = Union[Cat, Dog]`
+type PyUnion struct {
+	Comments []string
+	Name     string
+	Members  []PyType
+}
+
+type PyParam struct {
+	Name string
+	Type *PyType
+	// default value expression, empty if required
+	Default string
+}
+
+// PyMethod is a client method sending request of a route
+type PyMethod struct {
+	Comments []string
+	Name     string
+	Params   []PyParam
+	// params from index KeywordOnly are keyword-only, -1 if none
+	KeywordOnly int
+	// statements before sending request, e.g. setting mutable defaults
+	Prelude []string
+	RetType *PyType
+	Method  string
+	// path expression, e.g. `f"/users/{path_param(id)}"`
+	Path        string
+	QueryParams []PyQueryParam
+	Headers     []PyQueryParam
+	Cookies     []PyQueryParam
+	Form        []PyQueryParam
+	// content type of the form body, empty if the body is json
+	FormType string
+	// body param name, empty if no body
+	Body string
+	// response is wrapped in `{"code": 0, "data": ..., "message": "ok"}`
+	Envelope bool
+	// content type of the streamed response, empty if not streamed
+	StreamType string
+	// item type of server-sent events, nil if bytes are streamed
+	StreamItem *PyType
+}
+
+// PyQueryParam maps a query (or header, cookie) param name to the client
+// method param
+type PyQueryParam struct {
+	Name  string
+	Param string
+}
+
+// PyClient is a client class of an interface
+type PyClient struct {
+	Comments []string
+	Name     string
+	Methods  []PyMethod
+}
+
+type PyFile struct {
+	Name     string
+	Imports  []string
+	CodeGens []CodeGen
+}
+
+type RawCode struct {
+	code string
+}
@@ -0,0 +1,244 @@
+package typescript
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/jinzhenj/api1/pkg/utils"
+)
+
+var (
+	sprintf = fmt.Sprintf
+	indent  = utils.Indent
+)
+
+var reTsIdent = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*$`)
+
+// tsPropertyName quotes name if it's not an identifier, e.g. `"X-Request-Id"`
+func tsPropertyName(name string) string {
+	if reTsIdent.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// CodeComments renders comments as a JSDoc block
+func CodeComments(c []string, deprecated bool) string {
+	if len(c) == 0 && !deprecated {
+		return ""
+	}
+	code := "/**\n"
+	for _, line := range c {
+		if line == "" {
+			code += " *\n"
+		} else {
+			code += sprintf(" * %s\n", strings.ReplaceAll(line, "*/", "*\\/"))
+		}
+	}
+	if deprecated {
+		code += " * @deprecated\n"
+	}
+	code += " */\n"
+	return code
+}
+
+func (t *TsTypeAlias) Code() string {
+	code := CodeComments(t.Comments, false)
+	code += sprintf("export type %s = %s;\n", t.Name, t.Type.Code())
+	return code
+}
+
+func (e *TsEnum) Code() string {
+	code := CodeComments(e.Comments, e.Deprecated)
+	code += sprintf("export enum %s {\n", e.Name)
+	for _, o := range e.Options {
+		code += indent(CodeComments(o.Comments, false))
+		code += indent(sprintf("%s = %s,\n", o.Name, o.Value))
+	}
+	code += "}\n"
+	return code
+}
+
+func (t *TsType) Code() string {
+	if t == nil {
+		return "void"
+	}
+	var code string
+	if t.Name != "" {
+		code = t.Name
+		if len(t.TypeArgs) > 0 {
+			var args []string
+			for i := range t.TypeArgs {
+				args = append(args, t.TypeArgs[i].Code())
+			}
+			code += sprintf("<%s>", strings.Join(args, ", "))
+		}
+	} else if t.KeyType != nil {
+		code = sprintf("{ [key: %s]: %s }", t.KeyType.Code(), t.ItemType.Code())
+	} else {
+		item := t.ItemType.Code()
+		if t.ItemType.Nullable {
+			item = "(" + item + ")"
+		}
+		code = item + "[]"
+	}
+	if t.Nullable {
+		code += " | null"
+	}
+	return code
+}
+
+func (f *TsField) Code() string {
+	code := CodeComments(f.Comments, f.Deprecated)
+	if f.Modifier != "" {
+		code += f.Modifier + " "
+	}
+	code += f.Name
+	if f.Optional {
+		code += "?"
+	}
+	code += sprintf(": %s;\n", f.Type.Code())
+	return code
+}
+
+func (i *TsInterface) Code() string {
+	code := CodeComments(i.Comments, i.Deprecated)
+	code += "export interface " + i.Name
+	if len(i.TypeParams) > 0 {
+		code += sprintf("<%s>", strings.Join(i.TypeParams, ", "))
+	}
+	if len(i.Extends) > 0 {
+		var parents []string
+		for j := range i.Extends {
+			parents = append(parents, i.Extends[j].Code())
+		}
+		code += " extends " + strings.Join(parents, ", ")
+	}
+	code += " {\n"
+	for _, f := range i.Fields {
+		code += indent(f.Code())
+	}
+	code += "}\n"
+	return code
+}
+
+func (u *TsUnion) Code() string {
+	code := CodeComments(u.Comments, u.Deprecated)
+	var members []string
+	for i := range u.Members {
+		members = append(members, u.Members[i].Code())
+	}
+	if len(members) == 0 {
+		members = append(members, "never")
+	}
+	code += sprintf("export type %s = %s;\n", u.Name, strings.Join(members, " | "))
+	return code
+}
+
+func (m *TsMethod) Code() string {
+	code := CodeComments(m.Comments, m.Deprecated)
+	var params []string
+	for _, p := range m.Params {
+		param := sprintf("%s: %s", p.Name, p.Type.Code())
+		if p.Default != "" {
+			param += " = " + p.Default
+		}
+		params = append(params, param)
+	}
+	retType := m.RetType.Code()
+	switch {
+	case m.StreamItem != nil:
+		params = append(params, sprintf("onEvent: (event: %s) => void", m.StreamItem.Code()))
+		retType = "void"
+	case m.StreamType != "":
+		// the streamed body is read from the response
+		retType = "Response"
+	}
+	code += sprintf("%s(%s): Promise<%s> {\n", m.Name, strings.Join(params, ", "), retType)
+
+	var options []string
+	for _, o := range []struct {
+		name   string
+		params []TsQueryParam
+	}{{"query", m.QueryParams}, {"headers", m.Headers}, {"cookies", m.Cookies}, {"form", m.Form}} {
+		if len(o.params) == 0 {
+			continue
+		}
+		var values []string
+		for _, q := range o.params {
+			if q.Name == q.Param {
+				values = append(values, q.Name)
+			} else {
+				values = append(values, sprintf("%s: %s", tsPropertyName(q.Name), q.Param))
+			}
+		}
+		options = append(options, sprintf("%s: { %s }", o.name, strings.Join(values, ", ")))
+	}
+	if m.Body != "" && m.FormType != "" {
+		// spread as interfaces are not assignable to index signatures
+		options = append(options, sprintf("form: { ...%s }", m.Body))
+	} else if m.Body != "" {
+		options = append(options, "body: "+m.Body)
+	}
+	if m.FormType == api1.MimeFormData {
+		options = append(options, "multipart: true")
+	}
+	if m.Envelope {
+		options = append(options, "envelope: true")
+	}
+	args := []string{sprintf("%q", strings.ToUpper(m.Method)), m.Path}
+	if len(options) > 0 {
+		args = append(args, sprintf("{ %s }", strings.Join(options, ", ")))
+	} else if m.StreamItem != nil {
+		args = append(args, "{}")
+	}
+	switch {
+	case m.StreamItem != nil:
+		args = append(args, "onEvent")
+		code += indent(sprintf("return this.client.events<%s>(%s);\n", m.StreamItem.Code(), strings.Join(args, ", ")))
+	case m.StreamType != "":
+		code += indent(sprintf("return this.client.send(%s);\n", strings.Join(args, ", ")))
+	default:
+		code += indent(sprintf("return this.client.request<%s>(%s);\n", m.RetType.Code(), strings.Join(args, ", ")))
+	}
+	code += "}\n"
+	return code
+}
+
+func (c *TsClient) Code() string {
+	code := CodeComments(c.Comments, false)
+	code += sprintf("export class %s {\n", c.Name)
+	code += indent("constructor(private client: ApiClient) {}\n")
+	for i := range c.Methods {
+		code += "\n"
+		code += indent(c.Methods[i].Code())
+	}
+	code += "}\n"
+	return code
+}
+
+func (file *TsFile) Code() string {
+	code := "// Code generated by api1; DO NOT EDIT.\n"
+	if len(file.Imports) > 0 {
+		code += "\n"
+		for _, imp := range file.Imports {
+			if len(imp.Names) > 0 {
+				code += sprintf("import { %s } from %q;\n", strings.Join(imp.Names, ", "), imp.Path)
+			} else {
+				code += sprintf("import * as %s from %q;\n", imp.Name, imp.Path)
+			}
+		}
+	}
+	for _, codeGen := range file.CodeGens {
+		code += "\n"
+		code += codeGen.Code()
+	}
+	return code
+}
+
+func (r *RawCode) Code() string {
+	return r.code
+}
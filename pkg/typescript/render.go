@@ -0,0 +1,517 @@
+package typescript
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/jinzhenj/api1/pkg/utils"
+)
+
+const (
+	defaultOutputDir = "web/api"
+	clientFile       = "zz_client"
+)
+
+// reserved words of javascript, which cannot be used as param names
+var reservedWords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true,
+	"continue": true, "debugger": true, "default": true, "delete": true, "do": true,
+	"else": true, "enum": true, "export": true, "extends": true, "false": true,
+	"finally": true, "for": true, "function": true, "if": true, "import": true,
+	"in": true, "instanceof": true, "new": true, "null": true, "return": true,
+	"super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true, "let": true, "static": true, "implements": true,
+	"interface": true, "package": true, "private": true, "protected": true,
+	"public": true, "await": true, "arguments": true, "eval": true,
+}
+
+type Render struct {
+	// OutputDir is the dir of generated files, defaults to `web/api`
+	OutputDir string
+
+	// group being rendered, and groups it references
+	group   string
+	imports map[string]bool
+}
+
+func (r *Render) getOutputDir() string {
+	trimed := strings.TrimLeft(r.OutputDir, "/")
+	trimed = strings.TrimRight(trimed, "/")
+	if len(trimed) == 0 {
+		return defaultOutputDir
+	}
+	return trimed
+}
+
+// Render renders each group into a `.ts` file of types & client classes, and
+// a shared `zz_client.ts` which sends requests by fetch.
+func (r *Render) Render(schema *api1.Schema) ([]TsFile, error) {
+	if err := schema.SupplyRouteInfo(); err != nil {
+		return nil, err
+	}
+	outputDir := r.getOutputDir()
+
+	var files []TsFile
+	for _, g := range schema.Groups {
+		r.group = g.Name
+		r.imports = make(map[string]bool)
+		file := TsFile{
+			Name: fmt.Sprintf("%s/%s.ts", outputDir, g.Name),
+		}
+		for _, sc := range g.ScalarTypes {
+			file.CodeGens = append(file.CodeGens, r.renderScalar(&sc))
+		}
+		for _, en := range g.EnumTypes {
+			file.CodeGens = append(file.CodeGens, r.renderEnum(&en))
+		}
+		for _, st := range g.StructTypes {
+			file.CodeGens = append(file.CodeGens, r.renderStruct(&st))
+		}
+		for _, un := range g.UnionTypes {
+			file.CodeGens = append(file.CodeGens, r.renderUnion(&un))
+		}
+		var hasClient bool
+		for _, iface := range g.Ifaces {
+			if client := r.renderClient(&iface); client != nil {
+				file.CodeGens = append(file.CodeGens, client)
+				hasClient = true
+			}
+		}
+		if hasClient {
+			file.Imports = append(file.Imports, TsImport{Names: []string{"ApiClient"}, Path: "./" + clientFile})
+		}
+		var groups []string
+		for group := range r.imports {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			file.Imports = append(file.Imports, TsImport{Name: group, Path: "./" + group})
+		}
+		files = append(files, file)
+	}
+	files = append(files, r.renderClientFile(outputDir))
+	return files, nil
+}
+
+// qualify returns name of type defined in group, which is imported as a
+// namespace if defined in another group, e.g. `common.Status`
+func (r *Render) qualify(group string, name string) string {
+	if group == "" || group == r.group {
+		return name
+	}
+	r.imports[group] = true
+	return group + "." + name
+}
+
+func (r *Render) renderScalar(sc *api1.ScalarType) *TsTypeAlias {
+	t := TsTypeAlias{
+		Comments: sc.Comments,
+		Name:     sc.Name,
+		Type:     &TsType{Name: "any"},
+	}
+	if typ, ok := sc.SemComments["ts.type"].(string); ok {
+		t.Type.Name = typ
+	} else if typ, ok := sc.SemComments["openapi.type"].(string); ok {
+		switch typ {
+		case "integer", "number":
+			t.Type.Name = "number"
+		case "string", "boolean":
+			t.Type.Name = typ
+			if format, _ := sc.SemComments["openapi.format"].(string); typ == "string" && format == "binary" {
+				// sent as files by multipart forms
+				t.Type.Name = "Blob"
+			}
+		case "object":
+			t.Type = objectType()
+		}
+	}
+	return &t
+}
+
+func (r *Render) renderEnum(en *api1.EnumType) *TsEnum {
+	e := TsEnum{
+		Comments:   en.Comments,
+		Deprecated: deprecated(en.SemComments),
+		Name:       en.Name,
+	}
+	for _, o := range en.Options {
+		var value string
+		if o.Value == nil {
+			value = quote(o.Name)
+		} else if o.Value.IntVal != nil {
+			value = fmt.Sprint(*o.Value.IntVal)
+		} else {
+			value = quote(*o.Value.StrVal)
+		}
+		e.Options = append(e.Options, TsEnumOption{
+			Comments: o.Comments,
+			Name:     o.Name,
+			Value:    value,
+		})
+	}
+	return &e
+}
+
+func (r *Render) renderStruct(st *api1.StructType) *TsInterface {
+	i := TsInterface{
+		Comments:   st.Comments,
+		Deprecated: deprecated(st.SemComments),
+		Name:       st.Name,
+		TypeParams: st.TypeParams,
+	}
+	for j := range st.Extends {
+		i.Extends = append(i.Extends, *r.renderType(&st.Extends[j]))
+	}
+	for _, field := range st.Fields {
+		if _, ok := field.SemComments["ignore"]; ok {
+			continue
+		}
+		_, omitempty := field.SemComments["omitempty"]
+		modifier, _ := field.SemComments["ts.modifier"].(string)
+		i.Fields = append(i.Fields, TsField{
+			Comments:   field.Comments,
+			Deprecated: deprecated(field.SemComments),
+			Modifier:   modifier,
+			Name:       field.Name,
+			Type:       r.renderType(field.Type),
+			Optional:   omitempty || field.Default != nil,
+		})
+	}
+	return &i
+}
+
+func (r *Render) renderUnion(un *api1.UnionType) *TsUnion {
+	u := TsUnion{
+		Comments:   un.Comments,
+		Deprecated: deprecated(un.SemComments),
+		Name:       un.Name,
+	}
+	for j := range un.Types {
+		u.Members = append(u.Members, *r.renderType(&un.Types[j]))
+	}
+	return &u
+}
+
+func (r *Render) renderType(t *api1.TypeRef) *TsType {
+	if t == nil {
+		return nil
+	}
+	typ := TsType{Nullable: t.Nullable}
+	if t.Name != "" {
+		switch t.Name {
+		case "int", "float":
+			typ.Name = "number"
+		case "string", "boolean", "any":
+			typ.Name = t.Name
+		case "object":
+			typ = *objectType()
+			typ.Nullable = t.Nullable
+		default:
+			typ.Name = r.qualify(t.Group, t.Name)
+			for j := range t.TypeArgs {
+				typ.TypeArgs = append(typ.TypeArgs, *r.renderType(&t.TypeArgs[j]))
+			}
+		}
+	} else if t.KeyType != nil {
+		// json object keys are strings, numeric keys are also allowed in TS
+		typ.KeyType = &TsType{Name: "string"}
+		if t.KeyType.Name == "int" || t.KeyType.Name == "float" {
+			typ.KeyType.Name = "number"
+		}
+		typ.ItemType = r.renderType(t.ItemType)
+	} else {
+		typ.ItemType = r.renderType(t.ItemType)
+	}
+	return &typ
+}
+
+func objectType() *TsType {
+	return &TsType{KeyType: &TsType{Name: "string"}, ItemType: &TsType{Name: "any"}}
+}
+
+func (r *Render) renderLiteral(l *api1.Literal, t *api1.TypeRef) string {
+	switch l.Kind {
+	case api1.LiteralString:
+		return quote(l.Text)
+	case api1.LiteralNull:
+		return "null"
+	case api1.LiteralIdent:
+		// enum option
+		return r.qualify(t.Group, t.Name) + "." + l.Text
+	case api1.LiteralArray:
+		var items []string
+		for i := range l.Items {
+			items = append(items, r.renderLiteral(&l.Items[i], t.ItemType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return l.Text
+}
+
+// renderClient renders a client class sending requests of the routes in
+// iface, returns nil if iface has no route.
+func (r *Render) renderClient(iface *api1.Iface) *TsClient {
+	c := TsClient{
+		Comments: iface.Comments,
+		Name:     utils.PascalCase(iface.Name) + "Client",
+	}
+	for _, fun := range iface.Funs {
+		if fun.Route == nil {
+			continue
+		}
+		c.Methods = append(c.Methods, *r.renderMethod(&fun))
+	}
+	if len(c.Methods) == 0 {
+		return nil
+	}
+	return &c
+}
+
+func (r *Render) renderMethod(fun *api1.Fun) *TsMethod {
+	m := TsMethod{
+		Comments:   fun.Comments,
+		Deprecated: deprecated(fun.SemComments),
+		Name:       fun.Name,
+		RetType:    r.renderType(fun.Type),
+		Method:     fun.Route.Method,
+		Envelope:   fun.Route.Response == api1.ResponseEnvelope,
+		FormType:   fun.Route.FormType,
+	}
+	if fun.Stream != nil {
+		// streamed responses are never wrapped
+		m.StreamType = fun.Stream.ContentType
+		m.Envelope = false
+		if fun.Stream.Item != nil {
+			m.StreamItem = r.renderType(fun.Stream.Item)
+		}
+	}
+	names := make(map[string]string)
+	for _, param := range fun.Params {
+		name := param.Name
+		if reservedWords[name] {
+			name += "_"
+		}
+		names[param.Name] = name
+		p := TsParam{
+			Name: name,
+			Type: r.renderType(param.Type),
+		}
+		if param.Default != nil {
+			p.Default = r.renderLiteral(param.Default, param.Type)
+		}
+		m.Params = append(m.Params, p)
+
+		switch fun.Route.ParamsIn[param.Name] {
+		case api1.PositionBody:
+			m.Body = name
+		case api1.PositionQuery:
+			m.QueryParams = append(m.QueryParams, TsQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionHeader:
+			m.Headers = append(m.Headers, TsQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionCookie:
+			m.Cookies = append(m.Cookies, TsQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		case api1.PositionForm:
+			m.Form = append(m.Form, TsQueryParam{Name: fun.Route.WireName(param.Name), Param: name})
+		}
+	}
+	m.Path = renderPath(fun.Route.Path, names)
+	return &m
+}
+
+// renderPath renders route path (in colon style) as a string, or a template
+// string if it has path params, e.g. "`/users/${encodeURIComponent(String(id))}`"
+func renderPath(path string, names map[string]string) string {
+	parts := strings.Split(path, "/")
+	var hasParam bool
+	for i, part := range parts {
+		if len(part) > 1 && (part[0] == ':' || part[0] == '*') {
+			name, ok := names[part[1:]]
+			if !ok {
+				continue
+			}
+			hasParam = true
+			if part[0] == '*' {
+				// wildcard param may contain slashes
+				parts[i] = sprintf("${encodeURI(String(%s))}", name)
+			} else {
+				parts[i] = sprintf("${encodeURIComponent(String(%s))}", name)
+			}
+		} else {
+			parts[i] = strings.NewReplacer("\\", "\\\\", "`", "\\`", "$", "\\$").Replace(part)
+		}
+	}
+	if !hasParam {
+		return quote(path)
+	}
+	return "`" + strings.Join(parts, "/") + "`"
+}
+
+func (r *Render) renderClientFile(dir string) TsFile {
+	code := `export interface RequestOptions {
+  query?: { [key: string]: unknown };
+  headers?: { [key: string]: unknown };
+  // sent by the Cookie header, which is forbidden (ignored) in browsers
+  cookies?: { [key: string]: unknown };
+  body?: unknown;
+  // form body, files (Blob) are sent only if multipart, objects are sent as json
+  form?: { [key: string]: unknown };
+  // form is sent as multipart/form-data instead of url-encoded
+  multipart?: boolean;
+  // response is wrapped in { code, data, message }
+  envelope?: boolean;
+}
+
+export class ApiError extends Error {
+  constructor(public status: number, public body: string) {
+    super(` + "`Request failed with status ${status}`" + `);
+    this.name = "ApiError";
+  }
+}
+
+export class ApiClient {
+  constructor(public baseUrl: string = "", public init: RequestInit = {}) {}
+
+  async request<T>(method: string, path: string, options: RequestOptions = {}): Promise<T> {
+    const res = await this.send(method, path, options);
+    const text = await res.text();
+    const data = text ? JSON.parse(text) : undefined;
+    return (options.envelope && data ? data.data : data) as T;
+  }
+
+  // events calls onEvent with json data of each server-sent event until the
+  // stream ends, the error event is thrown as ApiError
+  async events<T>(method: string, path: string, options: RequestOptions, onEvent: (event: T) => void): Promise<void> {
+    const res = await this.send(method, path, options);
+    if (!res.body) {
+      return;
+    }
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = "";
+    let event = "";
+    let data: string[] = [];
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffer += value;
+      const lines = buffer.split(/\r?\n/);
+      buffer = lines.pop() || "";
+      for (const line of lines) {
+        if (line) {
+          const i = line.indexOf(":");
+          const field = i < 0 ? line : line.slice(0, i);
+          const v = i < 0 ? "" : line.slice(i + 1).replace(/^ /, "");
+          if (field === "event") {
+            event = v;
+          } else if (field === "data") {
+            data.push(v);
+          }
+          continue;
+        }
+        // a blank line dispatches the event
+        const payload = data.join("\n");
+        const name = event;
+        event = "";
+        data = [];
+        if (!payload) {
+          continue;
+        }
+        if (name === "error") {
+          throw new ApiError(res.status, payload);
+        }
+        onEvent(JSON.parse(payload) as T);
+      }
+    }
+  }
+
+  // send sends request, and returns the response if the status is 2xx
+  async send(method: string, path: string, options: RequestOptions = {}): Promise<Response> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(options.query || {})) {
+      if (value === undefined || value === null) {
+        continue;
+      }
+      for (const v of Array.isArray(value) ? value : [value]) {
+        params.append(key, String(v));
+      }
+    }
+    let url = this.baseUrl + path;
+    const query = params.toString();
+    if (query) {
+      url += "?" + query;
+    }
+
+    const init: RequestInit = { ...this.init, method };
+    const headers = new Headers(this.init.headers);
+    for (const [key, value] of Object.entries(options.headers || {})) {
+      if (value !== undefined && value !== null) {
+        headers.set(key, String(value));
+      }
+    }
+    const cookies: string[] = [];
+    for (const [key, value] of Object.entries(options.cookies || {})) {
+      if (value !== undefined && value !== null) {
+        cookies.push(` + "`${key}=${encodeURIComponent(String(value))}`" + `);
+      }
+    }
+    if (cookies.length > 0) {
+      headers.set("Cookie", cookies.join("; "));
+    }
+    if (options.form !== undefined) {
+      // content type is set by fetch
+      init.body = encodeForm(options.form, options.multipart);
+    } else if (options.body !== undefined) {
+      headers.set("Content-Type", "application/json");
+      init.body = JSON.stringify(options.body);
+    }
+    init.headers = headers;
+    const res = await fetch(url, init);
+    if (!res.ok) {
+      throw new ApiError(res.status, await res.text());
+    }
+    return res;
+  }
+}
+
+function encodeForm(form: { [key: string]: unknown }, multipart?: boolean): FormData | URLSearchParams {
+  const data = multipart ? new FormData() : new URLSearchParams();
+  for (const [key, value] of Object.entries(form)) {
+    if (value === undefined || value === null) {
+      continue;
+    }
+    for (const v of Array.isArray(value) ? value : [value]) {
+      if (data instanceof FormData && v instanceof Blob) {
+        data.append(key, v);
+      } else {
+        data.append(key, typeof v === "object" ? JSON.stringify(v) : String(v));
+      }
+    }
+  }
+  return data;
+}
+`
+	return TsFile{
+		Name: sprintf("%s/%s.ts", dir, clientFile),
+		CodeGens: []CodeGen{
+			&RawCode{
+				code: code,
+			},
+		},
+	}
+}
+
+func deprecated(semComments map[string]interface{}) bool {
+	_, ok := semComments["deprecated"]
+	return ok
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,282 @@
+package typescript
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jinzhenj/api1/pkg/api1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	parser := api1.Parser{}
+
+	t1 := `
+	  group user
+
+		# @openapi.type integer
+		scalar Timestamp
+
+		enum Role {
+			Admin = "admin"
+			# normal user
+			Normal = "normal"
+		}
+
+		# user info
+		struct User {
+			# @ts.modifier readonly
+			id: int
+			name: string = "anonymous"
+			# @omitempty
+			nickname: string?
+			role: Role
+			tags: [string?]
+			props: {string: any}
+			createdAt: Timestamp
+			# @ignore
+			password: string
+		}
+
+		struct Page<T> {
+			items: [T]
+			total: int
+		}
+
+		struct Cat {
+			meow: boolean
+		}
+
+		struct Dog {
+			bark: boolean
+		}
+
+		union Pet = Cat | Dog
+
+		# @ts.type Blob
+		scalar File
+
+		interface UserApi {
+			# @route GET /users
+			listUsers(page: int = 1, role: Role? = Admin, delete: boolean?): Page<User>
+
+			# @route GET /users/:id
+			# @deprecated
+			getUser(id: int): User?
+
+			# @route PUT /users/:id
+			# @response envelope
+			updateUser(id: int, user: User)
+
+			# @route POST /users/:id/avatar
+			# @accept multipart/form-data
+			uploadAvatar(id: int, file: File, title: string?)
+
+			# @route POST /login
+			# @accept application/x-www-form-urlencoded
+			login(name: string, tags: [string])
+
+			# @route DELETE /users/:id
+			deleteUser(
+				id: int,
+				# @in header X-Request-Id
+				requestId: string,
+				# @in cookie
+				session: string
+			)
+
+			# @route GET /users/:id/events
+			watchUser(id: int): stream<User>
+
+			# @route GET /users/:id/export
+			# @produces text/csv
+			exportUser(id: int, format: string): stream
+
+			# not a route
+			ping()
+		}
+	`
+
+	schema, err := parser.Parse(t1)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, "web/api/user.ts", files[0].Name)
+	assert.Equal(t, "web/api/zz_client.ts", files[1].Name)
+
+	exp := `// Code generated by api1; DO NOT EDIT.
+
+import { ApiClient } from "./zz_client";
+
+export type Timestamp = number;
+
+export type File = Blob;
+
+export enum Role {
+  Admin = "admin",
+  /**
+   * normal user
+   */
+  Normal = "normal",
+}
+
+/**
+ * user info
+ */
+export interface User {
+  readonly id: number;
+  name?: string;
+  nickname?: string | null;
+  role: Role;
+  tags: (string | null)[];
+  props: { [key: string]: any };
+  createdAt: Timestamp;
+}
+
+export interface Page<T> {
+  items: T[];
+  total: number;
+}
+
+export interface Cat {
+  meow: boolean;
+}
+
+export interface Dog {
+  bark: boolean;
+}
+
+export type Pet = Cat | Dog;
+
+export class UserApiClient {
+  constructor(private client: ApiClient) {}
+
+  listUsers(page: number = 1, role: Role | null = Role.Admin, delete_: boolean | null): Promise<Page<User>> {
+    return this.client.request<Page<User>>("GET", "/users", { query: { page, role, delete: delete_ } });
+  }
+
+  /**
+   * @deprecated
+   */
+  getUser(id: number): Promise<User | null> {
+    return this.client.request<User | null>("GET", ` + "`/users/${encodeURIComponent(String(id))}`" + `);
+  }
+
+  updateUser(id: number, user: User): Promise<void> {
+    return this.client.request<void>("PUT", ` + "`/users/${encodeURIComponent(String(id))}`" + `, { body: user, envelope: true });
+  }
+
+  uploadAvatar(id: number, file: File, title: string | null): Promise<void> {
+    return this.client.request<void>("POST", ` + "`/users/${encodeURIComponent(String(id))}/avatar`" + `, { form: { file, title }, multipart: true });
+  }
+
+  login(name: string, tags: string[]): Promise<void> {
+    return this.client.request<void>("POST", "/login", { form: { name, tags } });
+  }
+
+  deleteUser(id: number, requestId: string, session: string): Promise<void> {
+    return this.client.request<void>("DELETE", ` + "`/users/${encodeURIComponent(String(id))}`" + `, { headers: { "X-Request-Id": requestId }, cookies: { session } });
+  }
+
+  watchUser(id: number, onEvent: (event: User) => void): Promise<void> {
+    return this.client.events<User>("GET", ` + "`/users/${encodeURIComponent(String(id))}/events`" + `, {}, onEvent);
+  }
+
+  exportUser(id: number, format: string): Promise<Response> {
+    return this.client.send("GET", ` + "`/users/${encodeURIComponent(String(id))}/export`" + `, { query: { format } });
+  }
+}
+`
+	assert.Equal(t, exp, files[0].Code())
+}
+
+func TestRenderImports(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		return file
+	}
+	write("common.api", `group common
+
+enum Status {
+	Active
+}
+`)
+	file := write("user.api", `group user
+
+import "common.api"
+
+struct User {
+	status: common.Status = Active
+}
+
+interface UserApi {
+	# @route GET /users
+	listUsers(status: common.Status = Active): [User]
+}
+`)
+
+	parser := api1.Parser{}
+	schema, err := parser.ParseFiles(file)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	r := Render{}
+	files, err := r.Render(schema)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	var code string
+	for _, f := range files {
+		if f.Name == "web/api/user.ts" {
+			code = f.Code()
+		}
+	}
+	assert.Contains(t, code, "import { ApiClient } from \"./zz_client\";\n"+
+		"import * as common from \"./common\";\n")
+	assert.Contains(t, code, "  status?: common.Status;\n")
+	assert.Contains(t, code, "listUsers(status: common.Status = common.Status.Active): Promise<User[]> {\n")
+}
+
+func TestRenderFile(t *testing.T) {
+	parser := api1.Parser{}
+	schema, err := parser.Parse(`
+group user
+
+# @openapi.type string
+# @openapi.format binary
+scalar File
+
+# @form
+struct Avatar {
+	file: File
+	title: string?
+}
+
+interface UserApi {
+	# @route POST /users/:id/avatar
+	# @accept multipart/form-data
+	uploadAvatar(id: int, file: File)
+
+	# @route PUT /users/:id/avatar
+	# @accept multipart/form-data
+	putAvatar(id: int, avatar: Avatar)
+}
+`)
+	assert.NoError(t, err)
+	r := Render{}
+	files, err := r.Render(schema)
+	assert.NoError(t, err)
+	code := files[0].Code()
+	assert.Contains(t, code, "export type File = Blob;\n")
+	// the whole form is spread, as interfaces are not assignable to index signatures
+	assert.Contains(t, code, "{ form: { ...avatar }, multipart: true });\n")
+}
@@ -0,0 +1,127 @@
+package typescript
+
+type CodeGen interface {
+	Code() string
+}
+
+// TsTypeAlias is a type alias, e.g. `export type Time = string;`
+type TsTypeAlias struct {
+	Comments []string
+	Name     string
+	Type     *TsType
+}
+
+type TsEnumOption struct {
+	Comments []string
+	Name     string
+	// literal value, e.g. `"admin"` or `1`
+	Value string
+}
+
+type TsEnum struct {
+	Comments   []string
+	Deprecated bool
+	Name       string
+	Options    []TsEnumOption
+}
+
+type TsType struct {
+	// named type, or builtin type, e.g. `number`
+	Name     string
+	TypeArgs []TsType
+	KeyType  *TsType
+	ItemType *TsType
+	Nullable bool
+}
+
+type TsField struct {
+	Comments   []string
+	Deprecated bool
+	// e.g. `readonly`
+	Modifier string
+	Name     string
+	Type     *TsType
+	Optional bool
+}
+
+type TsInterface struct {
+	Comments   []string
+	Deprecated bool
+	Name       string
+	TypeParams []string
+	Extends    []TsType
+	Fields     []TsField
+}
+
+// TsUnion is a union of member types, e.g. `export type Pet = Cat | Dog;`
+type TsUnion struct {
+	Comments   []string
+	Deprecated bool
+	Name       string
+	Members    []TsType
+}
+
+type TsParam struct {
+	Name string
+	Type *TsType
+	// default value expression, empty if not specified
+	Default string
+}
+
+// TsMethod is a client method sending request of a route
+type TsMethod struct {
+	Comments   []string
+	Deprecated bool
+	Name       string
+	Params     []TsParam
+	RetType    *TsType
+	Method     string
+	// path expression, e.g. "`/users/${encodeURIComponent(String(id))}`"
+	Path        string
+	QueryParams []TsQueryParam
+	Headers     []TsQueryParam
+	Cookies     []TsQueryParam
+	Form        []TsQueryParam
+	// content type of the form body, empty if the body is json
+	FormType string
+	// body param name, empty if no body
+	Body string
+	// response is wrapped in `{ code, data, message }`
+	Envelope bool
+	// content type of the streamed response, empty if not streamed
+	StreamType string
+	// item type of server-sent events, nil if bytes are streamed
+	StreamItem *TsType
+}
+
+// TsQueryParam maps a query (or header, cookie) param name to the client
+// method param
+type TsQueryParam struct {
+	Name  string
+	Param string
+}
+
+// TsClient is a client class of an interface
+type TsClient struct {
+	Comments []string
+	Name     string
+	Methods  []TsMethod
+}
+
+type TsImport struct {
+	// namespace name, e.g. `common` in `import * as common from "./common";`
+	Name string
+	// imported names, e.g. `ApiClient` in `import { ApiClient } from "./client";`
+	Names []string
+	Path  string
+}
+
+type TsFile struct {
+	Name     string
+	Imports  []TsImport
+	CodeGens []CodeGen
+}
+
+type RawCode struct {
+	code string
+}
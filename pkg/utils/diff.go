@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+	// texts with more line pairs (after trimming the common prefix & suffix)
+	// are diffed as a whole replacement
+	maxDiffCells = 1 << 24
+)
+
+type diffLine struct {
+	// ' ', '-' or '+'
+	kind byte
+	text string
+	// line numbers (from 1) in a & b
+	a, b int
+}
+
+// UnifiedDiff returns the unified diff from text a to b, which are named
+// nameA & nameB in the header, with 3 lines of context. It returns "" if the
+// lines of a & b are equal, i.e. the missing newline at the end is ignored.
+func UnifiedDiff(nameA string, nameB string, a string, b string) string {
+	lines := diffLines(splitLines(a), splitLines(b))
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		// a hunk starts diffContext lines before the next change, and ends
+		// when more than 2*diffContext lines are unchanged after a change
+		first := start
+		for first < len(lines) && lines[first].kind == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		from := first - diffContext
+		if from < start {
+			from = start
+		}
+		to, last := first, first
+		for to < len(lines) && to-last-1 <= 2*diffContext {
+			if lines[to].kind != ' ' {
+				last = to
+			}
+			to++
+		}
+		to = last + 1 + diffContext
+		if to > len(lines) {
+			to = len(lines)
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&sb, lines[from:to])
+		start = to
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, lines []diffLine) {
+	aStart, bStart := lines[0].a, lines[0].b
+	var aLen, bLen int
+	for _, l := range lines {
+		if l.kind != '+' {
+			aLen++
+		}
+		if l.kind != '-' {
+			bLen++
+		}
+	}
+	// empty ranges start at the line before, as diff(1) does
+	if aLen == 0 {
+		aStart--
+	}
+	if bLen == 0 {
+		bStart--
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, l := range lines {
+		sb.WriteByte(l.kind)
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+}
+
+func hunkRange(start int, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the lines of a & b in order, which are kept, removed
+// from a or added by b, with the longest common subsequence kept
+func diffLines(a []string, b []string) []diffLine {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var lines []diffLine
+	i, j := 0, 0
+	keep := func(n int) {
+		for ; n > 0; n-- {
+			lines = append(lines, diffLine{kind: ' ', text: a[i], a: i + 1, b: j + 1})
+			i, j = i+1, j+1
+		}
+	}
+	remove := func() {
+		lines = append(lines, diffLine{kind: '-', text: a[i], a: i + 1, b: j + 1})
+		i++
+	}
+	add := func() {
+		lines = append(lines, diffLine{kind: '+', text: b[j], a: i + 1, b: j + 1})
+		j++
+	}
+
+	keep(prefix)
+	if len(ma)*len(mb) > maxDiffCells {
+		for range ma {
+			remove()
+		}
+		for range mb {
+			add()
+		}
+	} else {
+		// lcs[x][y] is the length of the lcs of ma[x:] & mb[y:]
+		w := len(mb) + 1
+		lcs := make([]int32, (len(ma)+1)*w)
+		for x := len(ma) - 1; x >= 0; x-- {
+			for y := len(mb) - 1; y >= 0; y-- {
+				if ma[x] == mb[y] {
+					lcs[x*w+y] = lcs[(x+1)*w+y+1] + 1
+				} else if lcs[(x+1)*w+y] >= lcs[x*w+y+1] {
+					lcs[x*w+y] = lcs[(x+1)*w+y]
+				} else {
+					lcs[x*w+y] = lcs[x*w+y+1]
+				}
+			}
+		}
+		x, y := 0, 0
+		for x < len(ma) || y < len(mb) {
+			switch {
+			case x < len(ma) && y < len(mb) && ma[x] == mb[y]:
+				keep(1)
+				x, y = x+1, y+1
+			case y == len(mb) || (x < len(ma) && lcs[(x+1)*w+y] >= lcs[x*w+y+1]):
+				remove()
+				x++
+			default:
+				add()
+				y++
+			}
+		}
+	}
+	keep(suffix)
+	return lines
+}
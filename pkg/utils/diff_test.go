@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	assert.Equal(t, "", UnifiedDiff("a", "b", "x\ny\n", "x\ny"))
+
+	assert.Equal(t, "--- a\n+++ b\n"+
+		"@@ -0,0 +1,2 @@\n"+
+		"+x\n"+
+		"+y\n", UnifiedDiff("a", "b", "", "x\ny\n"))
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n14\n15\n16\n"
+	assert.Equal(t, "--- a\n+++ b\n"+
+		"@@ -2,7 +2,7 @@\n"+
+		" 2\n"+
+		" 3\n"+
+		" 4\n"+
+		"-5\n"+
+		"+five\n"+
+		" 6\n"+
+		" 7\n"+
+		" 8\n"+
+		"@@ -10,6 +10,6 @@\n"+
+		" 10\n"+
+		" 11\n"+
+		" 12\n"+
+		"-13\n"+
+		" 14\n"+
+		" 15\n"+
+		"+16\n", UnifiedDiff("a", "b", a, b))
+
+	// changes within 6 lines are in the same hunk
+	assert.Equal(t, "--- a\n+++ b\n"+
+		"@@ -1,8 +1,6 @@\n"+
+		"-1\n"+
+		" 2\n"+
+		" 3\n"+
+		" 4\n"+
+		" 5\n"+
+		" 6\n"+
+		" 7\n"+
+		"-8\n", UnifiedDiff("a", "b", "1\n2\n3\n4\n5\n6\n7\n8\n", "2\n3\n4\n5\n6\n7\n"))
+}
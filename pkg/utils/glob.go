@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlob reports whether the path name matches pattern, which is of the
+// syntax of path.Match, and `**` matches any (including no) directories,
+// e.g. `api/**/*.api`
+func MatchGlob(pattern string, name string) (bool, error) {
+	return matchGlobParts(strings.Split(path.Clean(filepath.ToSlash(pattern)), "/"),
+		strings.Split(path.Clean(filepath.ToSlash(name)), "/"))
+}
+
+func matchGlobParts(patterns []string, parts []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if ok, err := matchGlobParts(patterns[1:], parts[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(parts) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(patterns[0], parts[0]); !ok || err != nil {
+			return false, err
+		}
+		patterns, parts = patterns[1:], parts[1:]
+	}
+	return len(parts) == 0, nil
+}
+
+// Glob returns the names of files matching pattern of MatchGlob, or nil if
+// there is no matching file. Without `**` it is filepath.Glob, which also
+// matches directories.
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	// walks from the dir of parts before the first one with meta chars
+	parts := strings.Split(pattern, "/")
+	i := 0
+	for i < len(parts) && !strings.ContainsAny(parts[i], `*?[\`) {
+		i++
+	}
+	root := strings.Join(parts[:i], "/")
+	if root == "" && i > 0 {
+		root = "/"
+	} else if root == "" {
+		root = "."
+	}
+
+	var files []string
+	err := filepath.Walk(filepath.FromSlash(root), func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		ok, err := MatchGlob(pattern, file)
+		if ok {
+			files = append(files, file)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
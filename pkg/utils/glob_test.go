@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.api", "user.api", true},
+		{"*.api", "api/user.api", false},
+		{"api/*.api", "api/user.api", true},
+		{"api/**/*.api", "api/user.api", true},
+		{"api/**/*.api", "api/v1/admin/user.api", true},
+		{"api/**/*.api", "web/user.api", false},
+		{"**/internal/**", "api/internal/user.api", true},
+		{"**", "user.api", true},
+		{"./api/*.api", "api/user.api", true},
+	} {
+		ok, err := MatchGlob(c.pattern, c.name)
+		assert.NoError(t, err)
+		assert.Equal(t, c.match, ok, "%s %s", c.pattern, c.name)
+	}
+	_, err := MatchGlob("api/[", "api/user.api")
+	assert.Error(t, err)
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a.api", "api/b.api", "api/v1/c.api", "api/v1/c.txt"} {
+		file = filepath.Join(dir, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	}
+	files, err := Glob(filepath.Join(dir, "**/*.api"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.api"),
+		filepath.Join(dir, "api/b.api"),
+		filepath.Join(dir, "api/v1/c.api"),
+	}, files)
+
+	files, err = Glob(filepath.Join(dir, "api/**/*.api"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "api/b.api"), filepath.Join(dir, "api/v1/c.api")}, files)
+
+	files, err = Glob(filepath.Join(dir, "none/**/*.api"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -66,3 +67,18 @@ func StringPtr(s string) *string {
 func Int64Ptr(i int64) *int64 {
 	return &i
 }
+
+var reGoModule = Compile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+// GoModulePath returns the module path declared in go.mod of dir.
+func GoModulePath(dir string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	m := reGoModule.FindSubmatch(content)
+	if m == nil {
+		return "", fmt.Errorf("module is not declared in %s", filepath.Join(dir, "go.mod"))
+	}
+	return string(m[1]), nil
+}